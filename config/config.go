@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -37,6 +38,130 @@ var (
 	Email               string
 	EmailPassword       string
 	DeepseekAPIKey      string
+
+	// LLM 服务商
+	LLMProviders       []LLMProviderConfig // 已注册的服务商
+	LLMDefaultProvider string              // 未指定服务商时使用的服务商
+	LLMRequestTimeout  time.Duration       // 单次请求超时
+	LLMMaxOutputTokens int                 // 单次请求允许的最大输出 token
+	LLMMaxToolRounds   int                 // 单次请求最多进行的工具调用轮数
+	LLMToolTimeout     time.Duration       // 单次工具调用超时
+	LLMStreamBufferTTL time.Duration       // 流式事件在Redis中的缓存时间, 用于断线续传
+	LLMResumeGrace     time.Duration       // 客户端断开后等待续传的时间, 超时无人续传则取消生成
+	LLMIdleTimeout     time.Duration       // 服务商连续多久没有返回数据时中止请求
+
+	// LLM 并发控制(0 表示不限制)
+	LLMMaxConcurrent        int           // 全局同时进行的请求数
+	LLMMaxConcurrentPerUser int           // 每个用户同时进行的请求数
+	LLMMaxQueue             int           // 排队等待的最大请求数
+	LLMMaxQueuePerUser      int           // 每个用户排队等待的最大请求数
+	LLMQueueTimeout         time.Duration // 排队超时
+
+	// LLM 响应缓存(请求中 cache 为 true 且 temperature 为 0 时生效)
+	LLMCacheTTL      time.Duration // 缓存时间, 0 表示关闭缓存
+	LLMCacheMaxBytes int           // 单条缓存的最大字节数, 超出时不缓存
+
+	// LLM 内容审核
+	LLMModerationRulesFile          string            // 审核规则文件, 每行 分类|关键词 或 分类|re:正则
+	LLMModerationActions            map[string]string // 各分类的处理方式 block/redact/warn
+	LLMModerationDefaultAction      string            // 未单独配置的分类的处理方式
+	LLMModerationClassifierProvider string            // 分类器使用的服务商, 为空时不使用分类器
+	LLMModerationClassifierModel    string            // 分类器使用的模型, 为空时使用服务商的默认模型
+	LLMModerationTimeout            time.Duration     // 分类器超时
+	LLMModerationHoldback           int               // 流式输出时暂缓发送的字符数, 用于匹配跨数据块的内容
+
+	// LLM 用量配额(按用户统计, 0 表示不限制)
+	LLMDailyTokenQuota   int // 每日 token 配额
+	LLMMonthlyTokenQuota int // 每月 token 配额
+	LLMMaxAPIKeys        int // 每个用户最多创建的API密钥数量
+
+	// 未登录调用者的 LLM 配额(按客户端地址统计, 0 表示不限制)
+	LLMAnonymousDailyTokenQuota  int // 每个地址每日 token 配额
+	LLMAnonymousGlobalDailyQuota int // 所有未登录调用者每日 token 总配额
+
+	// 静态资源检索增强(RAG)
+	RAGSourceDir         string // 建立索引的目录
+	RAGIndexPath         string // 索引文件
+	RAGChunkSize         int    // 片段长度(字符)
+	RAGChunkOverlap      int    // 相邻片段重叠的字符数
+	RAGTopK              int    // 每次检索返回的片段数
+	RAGEmbeddingProvider string // 向量化使用的服务商, 为空时使用本地哈希向量
+	RAGEmbeddingModel    string // 向量化模型
+
+	// LLM 多轮对话
+	LLMContextMessages int // 每次请求最多携带的历史消息条数
+	LLMContextChars    int // 每次请求携带的历史消息最大字符数
+
+	// 可信反向代理(IP或CIDR), 只信任这些地址转发的 X-Forwarded-For, 为空时使用连接地址
+	TrustedProxies []string
+
+	// 邮件发送策略
+	EmailAllowDomains     []string // 允许发送的邮箱域名白名单(为空表示不限制)
+	EmailDenyDomains      []string // 禁止发送的邮箱域名黑名单
+	EmailDisposableFile   string   // 一次性邮箱域名列表文件(每行一个域名),追加到内置列表
+	EmailCheckMX          bool     // 是否通过DNS检查邮箱域名的MX记录
+	EmailIPDailyLimit     int      // 单个IP每日最多发送邮件数
+	EmailGlobalDailyLimit int      // 全站每日最多发送邮件数
+
+	PublicBaseURL          string // 对外访问的服务地址,用于生成邮件中的链接
+	EmailUnsubscribeSecret string // 退订链接签名密钥,未配置时使用 SECRET_KEY
+
+	// DKIM 签名, 域名、选择器和私钥路径均配置后才启用
+	DKIMDomain    string
+	DKIMSelector  string
+	DKIMKeyPath   string
+	DKIMAlgorithm string // rsa-sha256 或 ed25519-sha256, 为空时根据私钥类型自动选择
+
+	// 图形验证码策略
+	CaptchaAllowedTypes    []string // 允许客户端选择的验证码类型
+	CaptchaDefaultType     string   // 未指定类型时使用的验证码类型
+	CaptchaMaxWidth        int      // 图片最大宽度
+	CaptchaMaxHeight       int      // 图片最大高度
+	CaptchaMinLength       int      // 验证码最小长度
+	CaptchaMaxLength       int      // 验证码最大长度
+	CaptchaSliderTolerance int      // 滑块验证码允许的偏移误差(像素)
+
+	// 工作量证明(PoW)人机验证
+	PowBaseDifficulty int           // 基础难度(前导零比特数)
+	PowMaxDifficulty  int           // 最大难度
+	PowDifficultyStep int           // 同一IP在统计窗口内每多少次请求提升1比特难度
+	PowWindow         time.Duration // IP请求量统计窗口
+	PowChallengeTTL   time.Duration // 挑战有效期
+
+	// 风险评估
+	RiskHumanCheckThreshold int           // 风险分达到该值时要求人机验证
+	RiskEmailCodeThreshold  int           // 风险分达到该值时注册要求邮箱验证码
+	RiskVelocityLimit       int           // 同一IP在速率窗口内的正常请求次数上限
+	RiskVelocityWindow      time.Duration // 请求速率统计窗口
+	RiskFailureWindow       time.Duration // 失败次数统计窗口
+	RiskReputationTTL       time.Duration // IP不良信誉记录保留时长
+
+	// 加密消息
+	EncryptionDefaultMaxViews int           // 未指定时的最大查看次数(1为阅后即焚)
+	EncryptionMaxViews        int           // 允许设置的最大查看次数
+	EncryptionDefaultTTL      time.Duration // 未指定时的有效期
+	EncryptionMinTTL          time.Duration // 允许设置的最短有效期
+	EncryptionMaxTTL          time.Duration // 允许设置的最长有效期
+	EncryptionCleanupInterval time.Duration // 过期消息清理间隔
+	EncryptionCleanupBatch    int           // 每批删除的过期消息数量
+	EncryptionMaxAttempts     int           // 访问口令最多允许错误的次数, 超过后销毁消息
+	EncryptionStore           string        // 消息存储: mysql / redis / memory
+	EncryptionMaxCipherText   int           // 密文最大长度(字符)
+
+	// 加密消息附件
+	AttachmentDir           string        // 附件存储目录(不能位于公开静态目录下)
+	AttachmentMaxSize       int64         // 单个附件最大字节数
+	AttachmentMaxChunkSize  int64         // 单个分片最大字节数
+	AttachmentMinChunkSize  int64         // 单个分片最小字节数(只有一个分片时不限制), 限制分片总数
+	AttachmentStorageQuota  int64         // 所有未清理附件的总字节数上限
+	AttachmentMaxPerMessage int           // 每条消息最多附件数
+	AttachmentDownloadTTL   time.Duration // 取出消息后附件下载链接的有效期
+
+	UserMaxPublicKeys int // 每个用户最多发布的公钥数量
+
+	// 加密消息回执通知
+	NotifyWebhookSecret  string        // Webhook 请求签名密钥, 为空时不签名
+	NotifyWebhookTimeout time.Duration // Webhook 请求超时
 )
 
 func init() {
@@ -64,6 +189,165 @@ func init() {
 	Email = getEnv("EMAIL")
 	EmailPassword = getEnv("EMAIL_PASSWORD")
 	DeepseekAPIKey = getEnv("DEEPSEEK_API_KEY")
+	LLMProviders = loadLLMProviders()
+	LLMDefaultProvider = strings.ToLower(getEnv("LLM_DEFAULT_PROVIDER"))
+	if LLMDefaultProvider == "" && len(LLMProviders) > 0 {
+		LLMDefaultProvider = LLMProviders[0].Name
+	}
+	LLMRequestTimeout = time.Duration(getEnvAsIntDefault("LLM_REQUEST_TIMEOUT", 300)) * time.Second
+	LLMMaxOutputTokens = getEnvAsIntDefault("LLM_MAX_OUTPUT_TOKENS", 8192)
+	LLMMaxToolRounds = getEnvAsIntDefault("LLM_MAX_TOOL_ROUNDS", 5)
+	LLMToolTimeout = time.Duration(getEnvAsIntDefault("LLM_TOOL_TIMEOUT", 15)) * time.Second
+	LLMStreamBufferTTL = time.Duration(getEnvAsIntDefault("LLM_STREAM_BUFFER_TTL", 300)) * time.Second
+	LLMResumeGrace = time.Duration(getEnvAsIntDefault("LLM_RESUME_GRACE", 30)) * time.Second
+	LLMIdleTimeout = time.Duration(getEnvAsIntDefault("LLM_IDLE_TIMEOUT", 60)) * time.Second
+	LLMMaxConcurrent = getEnvAsIntDefault("LLM_MAX_CONCURRENT", 20)
+	LLMMaxConcurrentPerUser = getEnvAsIntDefault("LLM_MAX_CONCURRENT_PER_USER", 2)
+	LLMMaxQueue = getEnvAsIntDefault("LLM_MAX_QUEUE", 100)
+	LLMMaxQueuePerUser = getEnvAsIntDefault("LLM_MAX_QUEUE_PER_USER", 5)
+	LLMQueueTimeout = time.Duration(getEnvAsIntDefault("LLM_QUEUE_TIMEOUT", 60)) * time.Second
+	LLMCacheTTL = time.Duration(getEnvAsIntDefault("LLM_CACHE_TTL", 86400)) * time.Second
+	LLMCacheMaxBytes = getEnvAsIntDefault("LLM_CACHE_MAX_BYTES", 256*1024)
+	LLMDailyTokenQuota = getEnvAsIntDefault("LLM_DAILY_TOKEN_QUOTA", 100000)
+	LLMMonthlyTokenQuota = getEnvAsIntDefault("LLM_MONTHLY_TOKEN_QUOTA", 2000000)
+	LLMMaxAPIKeys = getEnvAsIntDefault("LLM_MAX_API_KEYS", 5)
+	LLMAnonymousDailyTokenQuota = getEnvAsIntDefault("LLM_ANONYMOUS_DAILY_TOKEN_QUOTA", 5000)
+	LLMAnonymousGlobalDailyQuota = getEnvAsIntDefault("LLM_ANONYMOUS_GLOBAL_DAILY_QUOTA", 200000)
+	RAGSourceDir = getEnv("RAG_SOURCE_DIR")
+	if RAGSourceDir == "" {
+		RAGSourceDir = "./public/static"
+	}
+	RAGIndexPath = getEnv("RAG_INDEX_PATH")
+	if RAGIndexPath == "" {
+		RAGIndexPath = "./data/rag/index.gob"
+	}
+	RAGChunkSize = getEnvAsIntDefault("RAG_CHUNK_SIZE", 600)
+	RAGChunkOverlap = getEnvAsIntDefault("RAG_CHUNK_OVERLAP", 100)
+	RAGTopK = getEnvAsIntDefault("RAG_TOP_K", 4)
+	RAGEmbeddingProvider = strings.ToLower(getEnv("RAG_EMBEDDING_PROVIDER"))
+	RAGEmbeddingModel = getEnv("RAG_EMBEDDING_MODEL")
+	LLMModerationRulesFile = getEnv("LLM_MODERATION_RULES_FILE")
+	LLMModerationActions = getEnvAsMap("LLM_MODERATION_ACTIONS")
+	LLMModerationDefaultAction = strings.ToLower(getEnv("LLM_MODERATION_DEFAULT_ACTION"))
+	if LLMModerationDefaultAction == "" {
+		LLMModerationDefaultAction = "block"
+	}
+	LLMModerationClassifierProvider = strings.ToLower(getEnv("LLM_MODERATION_CLASSIFIER_PROVIDER"))
+	LLMModerationClassifierModel = getEnv("LLM_MODERATION_CLASSIFIER_MODEL")
+	LLMModerationTimeout = time.Duration(getEnvAsIntDefault("LLM_MODERATION_TIMEOUT", 10)) * time.Second
+	LLMModerationHoldback = getEnvAsIntDefault("LLM_MODERATION_HOLDBACK", 32)
+	LLMContextMessages = getEnvAsIntDefault("LLM_CONTEXT_MESSAGES", 20)
+	LLMContextChars = getEnvAsIntDefault("LLM_CONTEXT_CHARS", 12000)
+	TrustedProxies = getEnvAsList("TRUSTED_PROXIES")
+	EmailAllowDomains = getEnvAsList("EMAIL_ALLOW_DOMAINS")
+	EmailDenyDomains = getEnvAsList("EMAIL_DENY_DOMAINS")
+	EmailDisposableFile = getEnv("EMAIL_DISPOSABLE_FILE")
+	EmailCheckMX = getEnvAsBoolDefault("EMAIL_CHECK_MX", true)
+	EmailIPDailyLimit = getEnvAsIntDefault("EMAIL_IP_DAILY_LIMIT", 20)
+	EmailGlobalDailyLimit = getEnvAsIntDefault("EMAIL_GLOBAL_DAILY_LIMIT", 500)
+	PublicBaseURL = strings.TrimRight(getEnv("PUBLIC_BASE_URL"), "/")
+	EmailUnsubscribeSecret = getEnv("EMAIL_UNSUBSCRIBE_SECRET")
+	DKIMDomain = getEnv("DKIM_DOMAIN")
+	DKIMSelector = getEnv("DKIM_SELECTOR")
+	DKIMKeyPath = getEnv("DKIM_KEY_PATH")
+	DKIMAlgorithm = strings.ToLower(getEnv("DKIM_ALGORITHM"))
+	CaptchaAllowedTypes = getEnvAsList("CAPTCHA_ALLOWED_TYPES")
+	if len(CaptchaAllowedTypes) == 0 {
+		CaptchaAllowedTypes = []string{"digit", "string", "math", "chinese", "audio", "slider"}
+	}
+	CaptchaDefaultType = strings.ToLower(getEnv("CAPTCHA_DEFAULT_TYPE"))
+	if CaptchaDefaultType == "" {
+		CaptchaDefaultType = "digit"
+	}
+	CaptchaMaxWidth = getEnvAsIntDefault("CAPTCHA_MAX_WIDTH", 400)
+	CaptchaMaxHeight = getEnvAsIntDefault("CAPTCHA_MAX_HEIGHT", 160)
+	CaptchaMinLength = getEnvAsIntDefault("CAPTCHA_MIN_LENGTH", 4)
+	CaptchaMaxLength = getEnvAsIntDefault("CAPTCHA_MAX_LENGTH", 8)
+	CaptchaSliderTolerance = getEnvAsIntDefault("CAPTCHA_SLIDER_TOLERANCE", 4)
+	PowBaseDifficulty = getEnvAsIntDefault("POW_BASE_DIFFICULTY", 18)
+	PowMaxDifficulty = getEnvAsIntDefault("POW_MAX_DIFFICULTY", 26)
+	PowDifficultyStep = getEnvAsIntDefault("POW_DIFFICULTY_STEP", 5)
+	PowWindow = time.Duration(getEnvAsIntDefault("POW_WINDOW", 600)) * time.Second
+	PowChallengeTTL = time.Duration(getEnvAsIntDefault("POW_CHALLENGE_TTL", 300)) * time.Second
+	RiskHumanCheckThreshold = getEnvAsIntDefault("RISK_HUMAN_CHECK_THRESHOLD", 30)
+	RiskEmailCodeThreshold = getEnvAsIntDefault("RISK_EMAIL_CODE_THRESHOLD", 0)
+	RiskVelocityLimit = getEnvAsIntDefault("RISK_VELOCITY_LIMIT", 10)
+	RiskVelocityWindow = time.Duration(getEnvAsIntDefault("RISK_VELOCITY_WINDOW", 60)) * time.Second
+	RiskFailureWindow = time.Duration(getEnvAsIntDefault("RISK_FAILURE_WINDOW", 3600)) * time.Second
+	RiskReputationTTL = time.Duration(getEnvAsIntDefault("RISK_REPUTATION_TTL", 86400)) * time.Second
+	EncryptionDefaultMaxViews = getEnvAsIntDefault("ENCRYPTION_DEFAULT_MAX_VIEWS", 1)
+	EncryptionMaxViews = getEnvAsIntDefault("ENCRYPTION_MAX_VIEWS", 100)
+	EncryptionDefaultTTL = time.Duration(getEnvAsIntDefault("ENCRYPTION_DEFAULT_TTL", 86400)) * time.Second
+	EncryptionMinTTL = time.Duration(getEnvAsIntDefault("ENCRYPTION_MIN_TTL", 300)) * time.Second
+	EncryptionMaxTTL = time.Duration(getEnvAsIntDefault("ENCRYPTION_MAX_TTL", 30*86400)) * time.Second
+	EncryptionCleanupInterval = time.Duration(getEnvAsIntDefault("ENCRYPTION_CLEANUP_INTERVAL", 600)) * time.Second
+	EncryptionCleanupBatch = getEnvAsIntDefault("ENCRYPTION_CLEANUP_BATCH", 500)
+	EncryptionMaxAttempts = getEnvAsIntDefault("ENCRYPTION_MAX_ATTEMPTS", 5)
+	EncryptionStore = strings.ToLower(getEnv("ENCRYPTION_STORE"))
+	if EncryptionStore == "" {
+		EncryptionStore = "mysql"
+	}
+	EncryptionMaxCipherText = getEnvAsIntDefault("ENCRYPTION_MAX_CIPHERTEXT", 65535)
+	AttachmentDir = getEnv("ATTACHMENT_DIR")
+	if AttachmentDir == "" {
+		AttachmentDir = "./data/attachments"
+	}
+	AttachmentMaxSize = int64(getEnvAsIntDefault("ATTACHMENT_MAX_SIZE", 100*1024*1024))
+	AttachmentMaxChunkSize = int64(getEnvAsIntDefault("ATTACHMENT_MAX_CHUNK_SIZE", 5*1024*1024))
+	AttachmentMinChunkSize = int64(getEnvAsIntDefault("ATTACHMENT_MIN_CHUNK_SIZE", 256*1024))
+	AttachmentStorageQuota = int64(getEnvAsIntDefault("ATTACHMENT_STORAGE_QUOTA", 10*1024*1024*1024))
+	AttachmentMaxPerMessage = getEnvAsIntDefault("ATTACHMENT_MAX_PER_MESSAGE", 5)
+	AttachmentDownloadTTL = time.Duration(getEnvAsIntDefault("ATTACHMENT_DOWNLOAD_TTL", 600)) * time.Second
+	UserMaxPublicKeys = getEnvAsIntDefault("USER_MAX_PUBLIC_KEYS", 5)
+	NotifyWebhookSecret = getEnv("NOTIFY_WEBHOOK_SECRET")
+	NotifyWebhookTimeout = time.Duration(getEnvAsIntDefault("NOTIFY_WEBHOOK_TIMEOUT", 5)) * time.Second
+}
+
+// LLMProviderConfig 一个LLM服务商的配置
+type LLMProviderConfig struct {
+	Name    string   // 服务商名称, 请求中通过该名称选择
+	Type    string   // deepseek / openai / ollama
+	BaseURL string   // 接口地址
+	APIKey  string   // API 密钥(ollama 不需要)
+	Models  []string // 允许使用的模型, 第一个为默认模型
+}
+
+/*
+读取 LLM_PROVIDERS 中列出的服务商, 每个服务商的配置为:
+LLM_<名称>_TYPE、LLM_<名称>_BASE_URL、LLM_<名称>_API_KEY、LLM_<名称>_MODELS
+未列出 deepseek 但配置了 DEEPSEEK_API_KEY 时自动注册 deepseek
+*/
+func loadLLMProviders() []LLMProviderConfig {
+	var providers []LLMProviderConfig
+	hasDeepseek := false
+	for _, name := range getEnvAsList("LLM_PROVIDERS") {
+		prefix := "LLM_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		provider := LLMProviderConfig{
+			Name:    name,
+			Type:    strings.ToLower(getEnv(prefix + "TYPE")),
+			BaseURL: strings.TrimRight(getEnv(prefix+"BASE_URL"), "/"),
+			APIKey:  getEnv(prefix + "API_KEY"),
+		}
+		if provider.Type == "" {
+			provider.Type = name
+		}
+		for _, model := range strings.Split(getEnv(prefix+"MODELS"), ",") {
+			if model = strings.TrimSpace(model); model != "" {
+				provider.Models = append(provider.Models, model)
+			}
+		}
+		if provider.Type == "deepseek" {
+			hasDeepseek = true
+			if provider.APIKey == "" {
+				provider.APIKey = DeepseekAPIKey
+			}
+		}
+		providers = append(providers, provider)
+	}
+	if !hasDeepseek && DeepseekAPIKey != "" {
+		providers = append(providers, LLMProviderConfig{Name: "deepseek", Type: "deepseek", APIKey: DeepseekAPIKey})
+	}
+	return providers
 }
 
 func getEnv(key string) string {
@@ -81,3 +365,45 @@ func getEnvAsInt(key string) int {
 	}
 	return 0
 }
+
+func getEnvAsIntDefault(key string, defaultValue int) int {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		if intVal, err := strconv.Atoi(value); err == nil {
+			return intVal
+		}
+	}
+	return defaultValue
+}
+
+func getEnvAsBoolDefault(key string, defaultValue bool) bool {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		if boolVal, err := strconv.ParseBool(value); err == nil {
+			return boolVal
+		}
+	}
+	return defaultValue
+}
+
+// getEnvAsMap 读取 key1=value1,key2=value2 格式的环境变量,键和值统一转为小写
+func getEnvAsMap(key string) map[string]string {
+	result := map[string]string{}
+	for _, item := range getEnvAsList(key) {
+		name, value, ok := strings.Cut(item, "=")
+		if name, value = strings.TrimSpace(name), strings.TrimSpace(value); ok && name != "" && value != "" {
+			result[name] = value
+		}
+	}
+	return result
+}
+
+// getEnvAsList 读取逗号分隔的列表,去除空白项并统一转为小写
+func getEnvAsList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(getEnv(key), ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
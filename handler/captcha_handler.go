@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"gin/model"
 	"gin/service"
 	"net/http"
 	"time"
@@ -10,22 +11,29 @@ import (
 )
 
 type CaptchaRequest struct {
-	UUID string `json:"uuid" binding:"required"`
+	UUID     string `json:"uuid" binding:"required"`
+	Type     string `json:"type"`     // 验证码类型 digit/string(alphanumeric)/math/chinese/audio/slider, 默认由服务端配置
+	Width    int    `json:"width"`    // 图片宽度
+	Height   int    `json:"height"`   // 图片高度
+	Length   int    `json:"length"`   // 验证码长度
+	Language string `json:"language"` // 语音验证码语言 en/zh/ja/ru
 }
 
 type VerifyCaptchaRequest struct {
 	UUID string `json:"uuid" binding:"required"`
-	Code string `json:"code" binding:"required,len=6"`
+	Code string `json:"code" binding:"required,max=8192"` // 滑块验证码为包含位置和轨迹的JSON字符串
 }
 
 // GetCaptchaHandler 获取图形验证码
 // @Summary 获取图形验证码
-// @Description 生成图形验证码，返回base64编码的图片
+// @Description 生成验证码，返回base64编码的图片(语音验证码为wav音频)，可选类型和尺寸受服务端策略限制。
+// @Description 滑块验证码额外返回拼图块 piece 及其纵坐标 pieceY，提交时 code 为 {"x":横坐标,"track":[{"x","y","t"}]} 的JSON字符串
 // @Tags 验证码
 // @Accept json
 // @Produce json
-// @Param request body CaptchaRequest true "UUID（可选，不传则自动生成）"
+// @Param request body CaptchaRequest true "UUID（可选，不传则自动生成）及验证码类型、尺寸"
 // @Success 200 {object} map[string]interface{} "验证码生成成功"
+// @Failure 400 {object} map[string]interface{} "验证码类型或尺寸不被允许"
 // @Failure 500 {object} map[string]interface{} "内部服务器错误"
 // @Router /api/captcha [post]
 func GetCaptchaHandler(c *gin.Context) {
@@ -34,7 +42,40 @@ func GetCaptchaHandler(c *gin.Context) {
 	if req.UUID == "" {
 		req.UUID = uuid.NewString()
 	}
-	id, b64img, err := service.GenerateCaptcha(req.UUID)
+	options, err := service.ResolveCaptchaOptions(model.CaptchaOptions{
+		Type:     req.Type,
+		Width:    req.Width,
+		Height:   req.Height,
+		Length:   req.Length,
+		Language: req.Language,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": 400, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	if options.Type == model.CaptchaTypeSlider {
+		slider, err := service.GenerateSliderCaptcha(req.UUID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "生成验证码失败", "code": 500, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"captcha":   slider.Background,
+			"piece":     slider.Piece,
+			"pieceY":    slider.PieceY,
+			"pieceSize": slider.PieceSize,
+			"width":     slider.Width,
+			"height":    slider.Height,
+			"type":      options.Type,
+			"code":      200,
+			"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+			"messages":  "获取captcha成功",
+			"UUID":      slider.ID,
+		})
+		return
+	}
+
+	id, b64img, err := service.GenerateCaptcha(req.UUID, options)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成验证码失败", "code": 500, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
 		return
@@ -42,6 +83,7 @@ func GetCaptchaHandler(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"captcha":   b64img,
+		"type":      options.Type,
 		"code":      200,
 		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
 		"messages":  "获取captcha成功",
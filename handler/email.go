@@ -10,7 +10,9 @@ import (
 )
 
 type EmailRequest struct {
-	Email string `json:"email" binding:"required,email"`
+	Email          string `json:"email" binding:"required,email"`
+	HumanCheckKey  string `json:"humanCheckKey" binding:"required"`  // 人机验证验证码对应的key
+	HumanCheckCode string `json:"humanCheckCode" binding:"required"` // 人机验证验证码
 }
 
 type VerifyRequest struct {
@@ -20,11 +22,11 @@ type VerifyRequest struct {
 
 // SendEmailHandler 发送邮箱验证码
 // @Summary 发送邮箱验证码
-// @Description 向指定邮箱发送6位数字验证码
+// @Description 向指定邮箱发送6位数字验证码，需要先通过图形验证码，并受邮箱域名策略和每日发送配额限制
 // @Tags 邮箱验证
 // @Accept json
 // @Produce json
-// @Param request body EmailRequest true "邮箱地址"
+// @Param request body EmailRequest true "邮箱地址和图形验证码"
 // @Success 200 {object} map[string]interface{} "验证码发送成功"
 // @Failure 400 {object} map[string]interface{} "请求参数错误"
 // @Failure 429 {object} map[string]interface{} "发送太频繁"
@@ -37,7 +39,12 @@ func SendEmailHandler(c *gin.Context) {
 		return
 	}
 
-	if err := service.SendEmailCode(req.Email); err != nil {
+	if !service.VerifyHumanCheck(req.HumanCheckKey, req.HumanCheckCode) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "图形验证码无效或已过期", "code": 400, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+
+	if err := service.SendEmailCode(req.Email, c.ClientIP()); err != nil {
 		fmt.Println("发送邮件错误:", err)
 		if _, ok := err.(*service.TooFrequentError); ok {
 			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error(), "code": 429, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
@@ -79,3 +86,41 @@ func VerifyCodeHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "验证码无效或已过期", "code": 400, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
 	}
 }
+
+// ReloadDisposableDomainsHandler 重新加载一次性邮箱域名列表
+// @Summary 重新加载一次性邮箱域名列表
+// @Description 从 EMAIL_DISPOSABLE_FILE 重新读取一次性邮箱域名，与内置列表合并（仅内网可访问）
+// @Tags 邮箱验证
+// @Produce json
+// @Success 200 {object} map[string]interface{} "加载成功"
+// @Failure 500 {object} map[string]interface{} "加载失败"
+// @Router /api/admin/email/disposable-domains/reload [post]
+func ReloadDisposableDomainsHandler(c *gin.Context) {
+	count, err := service.LoadDisposableDomains()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "code": 500, "count": count, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "一次性邮箱域名列表已重新加载",
+		"code":      200,
+		"count":     count,
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
+
+// EmailDNSCheckHandler 检查发信域名的DNS记录
+// @Summary 检查发信域名的 DKIM/SPF/DMARC 记录
+// @Description 通过DNS服务查询发信域名的 DKIM、SPF、DMARC 记录，并将 DKIM 公钥与配置的私钥比对（仅内网可访问）
+// @Tags 邮箱验证
+// @Produce json
+// @Success 200 {object} model.EmailDNSReport "检查结果"
+// @Router /api/admin/email/dns-check [get]
+func EmailDNSCheckHandler(c *gin.Context) {
+	report := service.CheckEmailDNSRecords()
+	c.JSON(http.StatusOK, gin.H{
+		"code":      200,
+		"data":      report,
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
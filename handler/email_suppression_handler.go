@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"fmt"
+	"gin/model"
+	"gin/service"
+	"html"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UnsubscribeHandler 邮件退订
+// @Summary 邮件退订
+// @Description 通过邮件中的签名链接退订非必要邮件。GET 供用户点击链接，只返回确认页面，避免邮件扫描器预取链接时误退订；POST 写入退订，供确认页面提交和邮件客户端一键退订(RFC 8058)
+// @Tags 邮箱验证
+// @Accept json
+// @Produce json
+// @Param token query string true "退订token"
+// @Success 200 {object} map[string]interface{} "退订成功"
+// @Failure 400 {object} map[string]interface{} "退订链接无效"
+// @Router /api/unsubscribe [get]
+// @Router /api/unsubscribe [post]
+func UnsubscribeHandler(c *gin.Context) {
+	var req model.UnsubscribeRequest
+	// 一键退订时 token 在查询参数中, 请求体为 List-Unsubscribe=One-Click
+	if err := c.ShouldBindQuery(&req); err != nil {
+		if err := c.ShouldBind(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "缺少退订token", "code": 400, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+			return
+		}
+	}
+
+	if c.Request.Method == http.MethodGet {
+		email, err := service.ParseUnsubscribeToken(req.Token)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": 400, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+			return
+		}
+		// 表单不设置 action, 提交到当前链接(带 token)
+		page := `<!DOCTYPE html><html><head><meta charset="utf-8"><title>确认退订</title></head>` +
+			`<body style="font-family: sans-serif;text-align: center;padding-top: 80px;">` +
+			`<h2>确认退订</h2><p>` + html.EscapeString(email) + ` 将不再收到此类邮件通知。</p>` +
+			`<form method="post"><input type="hidden" name="confirm" value="1"><button type="submit">确认退订</button></form></body></html>`
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(page))
+		return
+	}
+
+	email, err := service.Unsubscribe(req.Token)
+	if err != nil {
+		fmt.Println("退订失败:", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": 400, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+
+	// 确认页面提交时返回页面, 一键退订返回 JSON
+	if c.PostForm("confirm") != "" {
+		page := `<!DOCTYPE html><html><head><meta charset="utf-8"><title>退订成功</title></head>` +
+			`<body style="font-family: sans-serif;text-align: center;padding-top: 80px;">` +
+			`<h2>退订成功</h2><p>` + html.EscapeString(email) + ` 将不再收到此类邮件通知。</p></body></html>`
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(page))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "退订成功",
+		"code":      200,
+		"email":     email,
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
+
+// ImportSuppressionsHandler 批量导入邮件屏蔽名单
+// @Summary 导入邮件屏蔽名单
+// @Description 批量将邮箱加入屏蔽名单（仅内网可访问），reason 可选 bounce/complaint/unsubscribe/manual
+// @Tags 邮箱验证
+// @Accept json
+// @Produce json
+// @Param request body model.SuppressionImportRequest true "屏蔽名单"
+// @Success 200 {object} map[string]interface{} "导入成功"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 500 {object} map[string]interface{} "内部服务器错误"
+// @Router /api/admin/email-suppressions [post]
+func ImportSuppressionsHandler(c *gin.Context) {
+	var req model.SuppressionImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": 400, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	if req.Source == "" {
+		req.Source = "管理员导入"
+	}
+
+	count, err := service.SuppressEmails(req.Emails, req.Reason, req.Source)
+	if err != nil {
+		fmt.Println("导入邮件屏蔽名单错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "导入邮件屏蔽名单失败", "code": 500, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "导入成功",
+		"code":      200,
+		"count":     count,
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
+
+// RemoveSuppressionsHandler 批量移除邮件屏蔽名单
+// @Summary 移除邮件屏蔽名单
+// @Description 批量将邮箱移出屏蔽名单（仅内网可访问）
+// @Tags 邮箱验证
+// @Accept json
+// @Produce json
+// @Param request body model.SuppressionRemoveRequest true "邮箱列表"
+// @Success 200 {object} map[string]interface{} "移除成功"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 500 {object} map[string]interface{} "内部服务器错误"
+// @Router /api/admin/email-suppressions [delete]
+func RemoveSuppressionsHandler(c *gin.Context) {
+	var req model.SuppressionRemoveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": 400, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+
+	count, err := service.UnsuppressEmails(req.Emails)
+	if err != nil {
+		fmt.Println("移除邮件屏蔽名单错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "移除邮件屏蔽名单失败", "code": 500, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "移除成功",
+		"code":      200,
+		"count":     count,
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
+
+// ListSuppressionsHandler 查询邮件屏蔽名单
+// @Summary 查询邮件屏蔽名单
+// @Description 分页查询邮件屏蔽名单（仅内网可访问）
+// @Tags 邮箱验证
+// @Produce json
+// @Param page query int false "页码，默认1"
+// @Param pageSize query int false "每页数量，默认20，最大200"
+// @Success 200 {object} map[string]interface{} "屏蔽名单"
+// @Failure 500 {object} map[string]interface{} "内部服务器错误"
+// @Router /api/admin/email-suppressions [get]
+func ListSuppressionsHandler(c *gin.Context) {
+	page, pageSize := parsePagination(c)
+	records, total, err := service.ListSuppressions(page, pageSize)
+	if err != nil {
+		fmt.Println("查询邮件屏蔽名单错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询邮件屏蔽名单失败", "code": 500, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":      200,
+		"data":      records,
+		"total":     total,
+		"page":      page,
+		"pageSize":  pageSize,
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
+
+// parsePagination 解析分页参数 page / pageSize
+func parsePagination(c *gin.Context) (int, int) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if err != nil || pageSize < 1 {
+		pageSize = 20
+	}
+	if pageSize > 200 {
+		pageSize = 200
+	}
+	return page, pageSize
+}
@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"gin/config"
+	"gin/model"
+	"gin/service"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondAttachmentError 按错误类型返回附件接口的状态码
+func respondAttachmentError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	message := "附件操作失败"
+	switch {
+	case errors.Is(err, service.ErrInvalidAttachment), errors.Is(err, service.ErrAttachmentIncomplete):
+		status, message = http.StatusBadRequest, err.Error()
+	case errors.Is(err, service.ErrAttachmentForbidden):
+		status, message = http.StatusForbidden, err.Error()
+	case errors.Is(err, service.ErrAttachmentNotFound), errors.Is(err, service.ErrEncryptionMessageNotFound),
+		errors.Is(err, service.ErrAttachmentUnavailable):
+		status, message = http.StatusNotFound, err.Error()
+	case errors.Is(err, service.ErrEncryptionMessageGone):
+		status, message = http.StatusGone, err.Error()
+	case errors.Is(err, service.ErrAttachmentQuota):
+		status, message = http.StatusInsufficientStorage, err.Error()
+	default:
+		fmt.Println("附件操作错误:", err)
+	}
+	c.JSON(status, gin.H{"error": message, "code": status, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+}
+
+// InitAttachmentHandler 创建附件上传任务
+// @Summary 创建附件上传任务
+// @Description 为尚未被查看的加密消息创建附件，需要存入消息时返回的 uploadToken。附件内容由客户端加密后按 chunkSize 分片上传，除只有一个分片外 chunkSize 不能小于 ATTACHMENT_MIN_CHUNK_SIZE
+// @Tags 加密消息
+// @Accept json
+// @Produce json
+// @Param request body model.AttachmentInitRequest true "附件信息"
+// @Success 200 {object} model.EncryptionAttachment "附件信息"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 403 {object} map[string]interface{} "上传凭证无效"
+// @Failure 410 {object} map[string]interface{} "消息已被查看或已过期"
+// @Failure 507 {object} map[string]interface{} "附件存储空间不足"
+// @Router /api/encryption-attachments [post]
+func InitAttachmentHandler(c *gin.Context) {
+	var req model.AttachmentInitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": 400, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	attachment, err := service.InitAttachment(req)
+	if err != nil {
+		respondAttachmentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data":      attachment,
+		"code":      200,
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
+
+// UploadAttachmentChunkHandler 上传附件分片
+// @Summary 上传附件分片
+// @Description 请求体为分片的原始字节，上传凭证放在 X-Upload-Token 请求头中。同一分片可重复上传
+// @Tags 加密消息
+// @Accept octet-stream
+// @Produce json
+// @Param id path string true "附件ID"
+// @Param index path int true "分片序号(从0开始)"
+// @Param X-Upload-Token header string true "上传凭证"
+// @Success 200 {object} map[string]interface{} "上传成功"
+// @Failure 400 {object} map[string]interface{} "分片错误"
+// @Failure 403 {object} map[string]interface{} "上传凭证无效"
+// @Router /api/encryption-attachments/{id}/chunks/{index} [put]
+func UploadAttachmentChunkHandler(c *gin.Context) {
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "分片序号错误", "code": 400, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, config.AttachmentMaxChunkSize+1)
+	if err := service.SaveAttachmentChunk(c.Param("id"), c.GetHeader("X-Upload-Token"), index, body); err != nil {
+		respondAttachmentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "分片上传成功",
+		"index":     index,
+		"code":      200,
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
+
+// AttachmentUploadStatusHandler 查询附件上传进度
+// @Summary 查询附件上传进度
+// @Description 返回已上传的分片序号，用于断点续传
+// @Tags 加密消息
+// @Produce json
+// @Param id path string true "附件ID"
+// @Param X-Upload-Token header string true "上传凭证"
+// @Success 200 {object} model.AttachmentUploadStatus "上传进度"
+// @Failure 403 {object} map[string]interface{} "上传凭证无效"
+// @Router /api/encryption-attachments/{id}/status [get]
+func AttachmentUploadStatusHandler(c *gin.Context) {
+	status, err := service.GetAttachmentUploadStatus(c.Param("id"), c.GetHeader("X-Upload-Token"))
+	if err != nil {
+		respondAttachmentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data":      status,
+		"code":      200,
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
+
+// CompleteAttachmentHandler 完成附件上传
+// @Summary 完成附件上传
+// @Description 所有分片上传后调用，服务端合并分片
+// @Tags 加密消息
+// @Produce json
+// @Param id path string true "附件ID"
+// @Param X-Upload-Token header string true "上传凭证"
+// @Success 200 {object} map[string]interface{} "上传完成"
+// @Failure 400 {object} map[string]interface{} "分片未全部上传"
+// @Failure 403 {object} map[string]interface{} "上传凭证无效"
+// @Router /api/encryption-attachments/{id}/complete [post]
+func CompleteAttachmentHandler(c *gin.Context) {
+	if err := service.CompleteAttachment(c.Param("id"), c.GetHeader("X-Upload-Token")); err != nil {
+		respondAttachmentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "附件上传完成",
+		"code":      200,
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
+
+// DownloadAttachmentHandler 下载附件
+// @Summary 下载附件
+// @Description 使用取出消息时返回的 downloadToken 下载加密附件，支持 Range 断点续传
+// @Tags 加密消息
+// @Produce octet-stream
+// @Param token path string true "下载凭证"
+// @Success 200 {file} file "加密附件"
+// @Failure 404 {object} map[string]interface{} "下载链接无效或已过期"
+// @Router /api/encryption-attachments/download/{token} [get]
+func DownloadAttachmentHandler(c *gin.Context) {
+	file, attachment, err := service.OpenAttachmentDownload(c.Param("token"))
+	if err != nil {
+		respondAttachmentError(c, err)
+		return
+	}
+	defer file.Close()
+
+	c.Header("Content-Type", "application/octet-stream")
+	c.Header("Content-Disposition", "attachment; filename=\""+attachment.AttachmentID+".bin\"")
+	c.Header("Cache-Control", "no-store")
+	http.ServeContent(c.Writer, c.Request, attachment.AttachmentID+".bin", attachment.CreatedAt, file)
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"gin/model"
 	"gin/service"
@@ -12,7 +13,10 @@ import (
 
 // EncryptMessageHandler 处理加密消息请求
 // @Summary 存入消息
-// @Description 前端传递加密信息,后端存储就行
+// @Description 前端传递加密信息,后端校验格式后存储并生成消息 uuid。可指定最大查看次数 maxViews(1为阅后即焚)、有效期 expiresIn(秒)，
+// @Description 以及由访问口令在客户端派生的 accessKey(服务端只保存其 Argon2id 哈希)。返回的 uploadToken 用于上传附件。
+// @Description 指定 recipientUsername 和 recipientKeyId 时消息发送到该用户的收件箱。
+// @Description 可选 notifyEmail / notifyWebhook 接收查看、过期未读等回执(只包含时间、大致位置和事件原因)，notifyEmail 需要登录且只能是当前账号的邮箱
 // @Tags 加密消息
 // @Accept json
 // @Produce json
@@ -27,28 +31,44 @@ func EncryptMessageHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": 400, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
 		return
 	}
-	if err := service.EncryptMessage(req); err != nil {
+	result, err := service.EncryptMessage(req, c.GetString("username"))
+	if err != nil {
 		fmt.Println("存储加密消息错误:", err)
+		if errors.Is(err, service.ErrUserNotFound) || errors.Is(err, service.ErrPublicKeyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "收件人或收件人公钥不存在", "code": 404, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+			return
+		}
+		if errors.Is(err, service.ErrInvalidEncryptionOptions) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": 400, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "存储加密消息失败", "code": 500, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
-		"message":   "消息存储成功",
-		"code":      200,
-		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+		"message":     "消息存储成功",
+		"uuid":        result.UUID,
+		"uploadToken": result.UploadToken,
+		"expiresAt":   result.ExpiresAt,
+		"code":        200,
+		"timestamp":   time.Now().Format("2006-01-02 15:04:05"),
 	})
 }
 
 // DecryptMessageHandler 处理解密消息请求
 // @Summary 取出消息
-// @Description 后端直接抛出uuid所对应的加密消息
+// @Description 返回uuid所对应的加密消息并计一次查看，达到最大查看次数或过期后消息被销毁。
+// @Description 设置了访问口令的消息需要提交 accessKey，错误次数过多时消息被销毁。发给指定收件人的消息需要收件人携带token
 // @Tags 加密消息
 // @Accept json
 // @Produce json
 // @Param request body model.DecryptionMessage true "解密请求"
-// @Success 200 {object} model.EncryptionMessage "解密消息"
+// @Success 200 {object} model.DecryptionResponse "解密消息"
 // @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 401 {object} map[string]interface{} "需要访问口令或口令错误"
+// @Failure 403 {object} map[string]interface{} "只有收件人可以查看"
 // @Failure 404 {object} map[string]interface{} "消息未找到"
+// @Failure 410 {object} map[string]interface{} "消息已被查看或已过期"
 // @Failure 500 {object} map[string]interface{} "内部服务器错误"
 // @Router /api/GetEncryptionMessage [post]
 func DecryptMessageHandler(c *gin.Context) {
@@ -57,14 +77,59 @@ func DecryptMessageHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": 400, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
 		return
 	}
-	message, err := service.DecryptMessage(req)
+	message, err := service.DecryptMessage(req, c.GetString("username"), c.ClientIP())
 	if err != nil {
 		fmt.Println("获取解密消息错误:", err)
+		if errors.Is(err, service.ErrEncryptionMessageDenied) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": 403, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+			return
+		}
+		var accessKeyErr *service.AccessKeyError
+		if errors.Is(err, service.ErrEncryptionAccessKeyNeeded) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error(), "requireAccessKey": true, "code": 401, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+			return
+		}
+		if errors.As(err, &accessKeyErr) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error(), "requireAccessKey": true, "remainingAttempts": accessKeyErr.Remaining, "code": 401, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+			return
+		}
+		if errors.Is(err, service.ErrEncryptionMessageNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error(), "code": 404, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+			return
+		}
+		if errors.Is(err, service.ErrEncryptionMessageGone) {
+			c.JSON(http.StatusGone, gin.H{"error": err.Error(), "code": 410, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取解密消息失败", "code": 500, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
-		"message":   message,
+		"message":        message,
+		"remainingViews": message.RemainingViews,
+		"code":           200,
+		"timestamp":      time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
+
+// CleanupEncryptionMessagesHandler 立即清理过期加密消息
+// @Summary 清理过期加密消息
+// @Description 管理接口，立即分批删除已过期的加密消息并返回删除数量
+// @Tags 加密消息
+// @Produce json
+// @Success 200 {object} map[string]interface{} "清理成功"
+// @Failure 500 {object} map[string]interface{} "内部服务器错误"
+// @Router /api/admin/encryption-messages/cleanup [post]
+func CleanupEncryptionMessagesHandler(c *gin.Context) {
+	deleted, err := service.CleanupExpiredEncryptionMessages()
+	if err != nil {
+		fmt.Println("清理过期加密消息错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "清理过期加密消息失败", "deleted": deleted, "code": 500, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "清理完成",
+		"deleted":   deleted,
 		"code":      200,
 		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
 	})
@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"gin/model"
+	"gin/service"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PublishKeyHandler 发布公钥
+// @Summary 发布公钥
+// @Description 当前登录用户发布一个 RSA-OAEP(SPKI DER base64) 或 X25519(32字节原始公钥 base64) 公钥，其他人可用它加密发给该用户的消息
+// @Tags 公钥目录
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body model.PublishKeyRequest true "公钥"
+// @Success 200 {object} model.UserPublicKey "发布成功"
+// @Failure 400 {object} map[string]interface{} "公钥格式错误"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Router /api/keys [post]
+func PublishKeyHandler(c *gin.Context) {
+	var req model.PublishKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": 400, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	key, err := service.PublishUserKey(c.GetString("username"), req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidPublicKey) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": 400, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+			return
+		}
+		fmt.Println("发布公钥错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "发布公钥失败", "code": 500, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data":      key,
+		"code":      200,
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
+
+// ListMyKeysHandler 查询自己发布的公钥
+// @Summary 查询自己发布的公钥
+// @Tags 公钥目录
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} model.UserPublicKey "公钥列表"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Router /api/keys [get]
+func ListMyKeysHandler(c *gin.Context) {
+	keys, err := service.ListUserKeys(c.GetString("username"))
+	if err != nil {
+		fmt.Println("查询公钥错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询公钥失败", "code": 500, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data":      keys,
+		"code":      200,
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
+
+// DeleteKeyHandler 删除自己的公钥
+// @Summary 删除公钥
+// @Tags 公钥目录
+// @Produce json
+// @Security BearerAuth
+// @Param keyId path string true "公钥ID"
+// @Success 200 {object} map[string]interface{} "删除成功"
+// @Failure 404 {object} map[string]interface{} "公钥不存在"
+// @Router /api/keys/{keyId} [delete]
+func DeleteKeyHandler(c *gin.Context) {
+	if err := service.DeleteUserKey(c.GetString("username"), c.Param("keyId")); err != nil {
+		if errors.Is(err, service.ErrPublicKeyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error(), "code": 404, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+			return
+		}
+		fmt.Println("删除公钥错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除公钥失败", "code": 500, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "公钥已删除",
+		"code":      200,
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
+
+// LookupUserKeysHandler 查询用户公开的公钥
+// @Summary 查询用户公钥
+// @Description 公钥目录：发送方查询收件人的公钥，用其加密AES密钥后通过 recipientUsername/recipientKeyId 发送消息
+// @Tags 公钥目录
+// @Produce json
+// @Param username path string true "用户名"
+// @Success 200 {array} model.UserPublicKey "公钥列表"
+// @Failure 404 {object} map[string]interface{} "用户不存在"
+// @Router /api/users/{username}/keys [get]
+func LookupUserKeysHandler(c *gin.Context) {
+	keys, err := service.ListUserKeys(c.Param("username"))
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error(), "code": 404, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+			return
+		}
+		fmt.Println("查询用户公钥错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询用户公钥失败", "code": 500, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"username":  c.Param("username"),
+		"data":      keys,
+		"code":      200,
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
+
+// InboxHandler 收件箱
+// @Summary 收件箱
+// @Description 列出发给当前登录用户且尚未销毁的加密消息，取出内容仍使用 /api/GetEncryptionMessage(需携带token)
+// @Tags 公钥目录
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "页码"
+// @Param pageSize query int false "每页数量"
+// @Success 200 {array} model.InboxMessage "消息列表"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Router /api/inbox [get]
+func InboxHandler(c *gin.Context) {
+	page, pageSize := parsePagination(c)
+	messages, total, err := service.ListInbox(c.GetString("username"), page, pageSize)
+	if err != nil {
+		fmt.Println("查询收件箱错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询收件箱失败", "code": 500, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":      200,
+		"data":      messages,
+		"total":     total,
+		"page":      page,
+		"pageSize":  pageSize,
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"fmt"
+	"gin/service"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PurgeLLMCacheHandler 清除LLM响应缓存
+// @Summary 清除LLM响应缓存
+// @Description 管理接口，不带参数时清除全部缓存；指定 provider 时只清除该服务商的缓存，同时指定 model 时只清除该模型的缓存
+// @Tags LLM API
+// @Produce json
+// @Param provider query string false "服务商"
+// @Param model query string false "模型, 需要同时指定服务商"
+// @Success 200 {object} map[string]interface{} "清除成功"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 500 {object} map[string]interface{} "内部服务器错误"
+// @Router /api/admin/llm-cache [delete]
+func PurgeLLMCacheHandler(c *gin.Context) {
+	provider, modelName := c.Query("provider"), c.Query("model")
+	if modelName != "" && provider == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "指定模型时需要同时指定服务商", "code": 400, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	deleted, err := service.PurgeLLMCache(provider, modelName)
+	if err != nil {
+		fmt.Println("清除LLM缓存错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "清除LLM缓存失败", "deleted": deleted, "code": 500, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "缓存已清除",
+		"deleted":   deleted,
+		"code":      200,
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
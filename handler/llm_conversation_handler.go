@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gin/model"
+	"gin/service"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondConversationError 按错误类型返回对话接口的状态码
+func respondConversationError(c *gin.Context, err error) {
+	if respondQuotaExceeded(c, err) || respondLLMBusy(c, err) {
+		return
+	}
+	status := http.StatusInternalServerError
+	message := "对话操作失败"
+	switch {
+	case errors.Is(err, service.ErrInvalidConversation), errors.Is(err, service.ErrLLMProviderNotFound),
+		errors.Is(err, service.ErrLLMModelNotAllowed), errors.Is(err, service.ErrLLMInputBlocked):
+		status, message = http.StatusBadRequest, err.Error()
+	case errors.Is(err, service.ErrConversationNotFound), errors.Is(err, service.ErrUserNotFound):
+		status, message = http.StatusNotFound, err.Error()
+	default:
+		fmt.Println("对话操作错误:", err)
+	}
+	c.JSON(status, gin.H{"error": message, "code": status, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+}
+
+// CreateConversationHandler 创建对话
+// @Summary 创建对话
+// @Description 为当前登录用户创建一个LLM对话，标题为空时取第一条消息的开头作为标题
+// @Tags LLM API
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body model.ConversationRequest false "对话标题"
+// @Success 200 {object} model.LLMConversation "对话"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Router /api/conversations [post]
+func CreateConversationHandler(c *gin.Context) {
+	var req model.ConversationRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": 400, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+			return
+		}
+	}
+	conversation, err := service.CreateConversation(c.GetString("username"), req.Title)
+	if err != nil {
+		respondConversationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data":      conversation,
+		"code":      200,
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
+
+// ListConversationsHandler 查询对话列表
+// @Summary 查询对话列表
+// @Tags LLM API
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "页码"
+// @Param pageSize query int false "每页数量"
+// @Success 200 {array} model.LLMConversation "对话列表"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Router /api/conversations [get]
+func ListConversationsHandler(c *gin.Context) {
+	page, pageSize := parsePagination(c)
+	conversations, total, err := service.ListConversations(c.GetString("username"), page, pageSize)
+	if err != nil {
+		respondConversationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":      200,
+		"data":      conversations,
+		"total":     total,
+		"page":      page,
+		"pageSize":  pageSize,
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
+
+// RenameConversationHandler 重命名对话
+// @Summary 重命名对话
+// @Tags LLM API
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "对话ID"
+// @Param request body model.ConversationRequest true "新标题"
+// @Success 200 {object} model.LLMConversation "对话"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 404 {object} map[string]interface{} "对话不存在"
+// @Router /api/conversations/{id} [put]
+func RenameConversationHandler(c *gin.Context) {
+	var req model.ConversationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": 400, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	conversation, err := service.RenameConversation(c.GetString("username"), c.Param("id"), req.Title)
+	if err != nil {
+		respondConversationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data":      conversation,
+		"code":      200,
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
+
+// DeleteConversationHandler 删除对话
+// @Summary 删除对话
+// @Description 删除对话及其全部消息
+// @Tags LLM API
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "对话ID"
+// @Success 200 {object} map[string]interface{} "删除成功"
+// @Failure 404 {object} map[string]interface{} "对话不存在"
+// @Router /api/conversations/{id} [delete]
+func DeleteConversationHandler(c *gin.Context) {
+	if err := service.DeleteConversation(c.GetString("username"), c.Param("id")); err != nil {
+		respondConversationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "对话已删除",
+		"code":      200,
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
+
+// ListConversationMessagesHandler 查询对话消息
+// @Summary 查询对话消息
+// @Tags LLM API
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "对话ID"
+// @Success 200 {array} model.LLMConversationMessage "消息列表"
+// @Failure 404 {object} map[string]interface{} "对话不存在"
+// @Router /api/conversations/{id}/messages [get]
+func ListConversationMessagesHandler(c *gin.Context) {
+	messages, err := service.ListConversationMessages(c.GetString("username"), c.Param("id"))
+	if err != nil {
+		respondConversationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data":      messages,
+		"code":      200,
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
+
+// SendConversationMessageHandler 在对话中发送消息
+// @Summary 在对话中发送消息并获取流式响应
+// @Description 可通过 provider / model 选择服务商和模型。请求会带上该对话的历史消息(按 LLM_CONTEXT_MESSAGES / LLM_CONTEXT_CHARS 裁剪)，回复在流结束后保存到对话中(客户端断开后继续生成)。事件格式与 /api/llm-message 相同，断线后可通过 GET /api/llm-stream 续传。消息和回复经过内容审核，保存的是审核后的内容
+// @Tags LLM API
+// @Accept json
+// @Produce text/event-stream
+// @Security BearerAuth
+// @Param id path string true "对话ID"
+// @Param request body model.ConversationMessageRequest true "用户消息"
+// @Success 200 {string} string "流式响应数据"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 404 {object} map[string]interface{} "对话不存在"
+// @Failure 429 {object} map[string]interface{} "token配额已用完"
+// @Failure 503 {object} map[string]interface{} "排队已满"
+// @Router /api/conversations/{id}/messages [post]
+func SendConversationMessageHandler(c *gin.Context) {
+	var req model.ConversationMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": 400, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	// 排队时回调在后台执行, 不能再访问 gin.Context
+	username, conversationID := c.GetString("username"), c.Param("id")
+	streamID, records, err := service.StartLLMStream(c.Request.Context(), username, func(ctx context.Context) (<-chan model.LLMStreamEvent, <-chan error, error) {
+		return service.StreamConversationReply(ctx, username, conversationID, req)
+	})
+	if err != nil {
+		respondConversationError(c, err)
+		return
+	}
+	writeSSEStream(c, streamID, records)
+}
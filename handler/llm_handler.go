@@ -1,10 +1,13 @@
 package handler
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"gin/model"
 	"gin/service"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -12,31 +15,154 @@ import (
 
 // 流式传输处理器 - 支持 Server-Sent Events (SSE)
 // @Summary 发送消息到LLM并获取流式响应
-// @Description 发送用户消息到LLM并通过流式传输获取响应
+// @Description 发送用户消息到LLM并通过流式传输获取响应，可通过 provider / model 选择已注册的服务商和模型，personaId 指定角色(系统提示词及温度、模型等参数，角色锁定的参数不能覆盖)。enableTools 为 true 时模型可以调用后端工具，调用和结果分别以 event: tool_call / event: tool_result 推送。enableRetrieval 为 true 时从静态资源(书籍、页面)中检索参考内容，回复前先以 event: citations 推送引用列表，回复中以 [n] 标注来源。
+// @Description 事件类型为 delta(回复片段) / reasoning(推理片段) / usage / error / done 等，data 均为 JSON，id 为 流ID:序号。断线后带 Last-Event-ID 请求头重新请求可以续传(忽略请求体)，断开超过 LLM_RESUME_GRACE 秒无人续传时取消生成。
+// @Description 超出全局或单用户并发限制时排队，排队期间推送 event: queue {"position": n}。cache 为 true 且 temperature 为 0、未启用工具时使用响应缓存，命中时先推送 event: cached 再重放缓存的回复，不消耗配额。启用内容审核时，消息和回复命中 warn / redact 分类会推送 event: moderation，消息命中 block 分类返回400，回复命中 block 分类时以 error 事件中止。可使用登录token或API密钥，用量计入每日/每月token配额；未提供凭证时按客户端地址计入较低的匿名每日配额(LLM_ANONYMOUS_DAILY_TOKEN_QUOTA)，所有匿名调用合计不超过 LLM_ANONYMOUS_GLOBAL_DAILY_QUOTA
 // @Tags LLM API
 // @Accept json
 // @Produce text/event-stream
-// @Param request body model.Message true "用户消息"
+// @Security BearerAuth
+// @Param request body model.LLMMessageRequest true "用户消息"
 // @Success 200 {string} string "流式响应数据"
 // @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 401 {object} map[string]interface{} "token或API密钥无效"
+// @Failure 404 {object} map[string]interface{} "角色不存在"
+// @Failure 429 {object} map[string]interface{} "token配额已用完"
 // @Failure 500 {object} map[string]interface{} "内部服务器错误"
-// @Router /api/llm-message/deepseek-stream [post]
+// @Failure 503 {object} map[string]interface{} "排队已满或检索索引未建立"
+// @Router /api/llm-message [post]
 func SendMessageToLLMStreamHandler(c *gin.Context) {
-	var req model.Message
+	streamLLMMessage(c, "")
+}
+
+// DeepSeek 流式传输处理器, 未指定服务商时使用 deepseek
+// @Summary 发送消息到DeepSeek并获取流式响应
+// @Description /api/llm-message 的别名，provider 默认为 deepseek
+// @Tags LLM API
+// @Accept json
+// @Produce text/event-stream
+// @Security BearerAuth
+// @Param request body model.LLMMessageRequest true "用户消息"
+// @Success 200 {string} string "流式响应数据"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 401 {object} map[string]interface{} "token或API密钥无效"
+// @Failure 404 {object} map[string]interface{} "角色不存在"
+// @Failure 429 {object} map[string]interface{} "token配额已用完"
+// @Failure 500 {object} map[string]interface{} "内部服务器错误"
+// @Failure 503 {object} map[string]interface{} "排队已满或检索索引未建立"
+// @Router /api/llm-message/deepseek [post]
+func SendMessageToDeepseekStreamHandler(c *gin.Context) {
+	streamLLMMessage(c, "deepseek")
+}
+
+func streamLLMMessage(c *gin.Context, defaultProvider string) {
+	// 带 Last-Event-ID 重连时续传之前的生成, 不再发起新请求
+	if lastEventID := c.GetHeader("Last-Event-ID"); lastEventID != "" {
+		resumeLLMStream(c, lastEventID)
+		return
+	}
+	var req model.LLMMessageRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": 400, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
 		return
 	}
+	if req.Provider == "" {
+		req.Provider = defaultProvider
+	}
 
-	// 设置 SSE 响应头
-	c.Header("Content-Type", "text/event-stream")
-	c.Header("Cache-Control", "no-cache")
-	c.Header("Connection", "keep-alive")
-	c.Header("Access-Control-Allow-Origin", "*")
+	// 排队时回调在后台执行, 不能再访问 gin.Context
+	caller := llmCaller(c)
+	streamID, records, err := service.StartLLMStream(c.Request.Context(), c.GetString("username"), func(ctx context.Context) (<-chan model.LLMStreamEvent, <-chan error, error) {
+		return service.SendMessageToLLMStream(ctx, caller, req)
+	})
+	if err != nil {
+		respondLLMError(c, err)
+		return
+	}
+	writeSSEStream(c, streamID, records)
+}
+
+// llmCaller 获取 LLMIdentityMiddleware 识别出的调用者
+func llmCaller(c *gin.Context) model.LLMCaller {
+	caller, _ := c.Get("llmCaller")
+	result, _ := caller.(model.LLMCaller)
+	return result
+}
+
+// respondQuotaExceeded 配额用完时返回429
+func respondQuotaExceeded(c *gin.Context, err error) bool {
+	var quotaErr *service.QuotaExceededError
+	if !errors.As(err, &quotaErr) {
+		return false
+	}
+	c.Header("Retry-After", strconv.Itoa(int(time.Until(quotaErr.ResetAt).Seconds())+1))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":     quotaErr.Error(),
+		"code":      429,
+		"period":    quotaErr.Period,
+		"limit":     quotaErr.Limit,
+		"used":      quotaErr.Used,
+		"resetAt":   quotaErr.ResetAt.Format("2006-01-02 15:04:05"),
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+	})
+	return true
+}
+
+// 排队已满时建议客户端重试的间隔(秒)
+const llmBusyRetryAfter = 5
+
+// respondLLMBusy 排队已满或排队超时返回503
+func respondLLMBusy(c *gin.Context, err error) bool {
+	if !errors.Is(err, service.ErrLLMQueueFull) && !errors.Is(err, service.ErrLLMQueueTimeout) {
+		return false
+	}
+	c.Header("Retry-After", strconv.Itoa(llmBusyRetryAfter))
+	c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error(), "code": 503, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+	return true
+}
+
+// respondLLMError 服务商或模型选择错误、消息未通过内容审核返回400, 配额用完返回429, 服务繁忙或检索索引不可用返回503, 其它返回500
+func respondLLMError(c *gin.Context, err error) {
+	if respondQuotaExceeded(c, err) || respondLLMBusy(c, err) {
+		return
+	}
+	if errors.Is(err, service.ErrLLMPersonaNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error(), "code": 404, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	if errors.Is(err, service.ErrLLMProviderNotFound) || errors.Is(err, service.ErrLLMModelNotAllowed) ||
+		errors.Is(err, service.ErrInvalidLLMParams) || errors.Is(err, service.ErrLLMInputBlocked) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": 400, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	if errors.Is(err, service.ErrRAGIndexEmpty) || errors.Is(err, service.ErrRAGIndexOutdated) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error(), "code": 503, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	fmt.Println("LLM请求错误:", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "LLM请求失败", "code": 500, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+}
 
-	// 获取数据流通道
-	dataChan, errChan := service.SendMessageToLLMStream(req.Content)
+// ListLLMProvidersHandler 查询已注册的服务商
+// @Summary 查询LLM服务商和模型
+// @Tags LLM API
+// @Produce json
+// @Success 200 {array} model.LLMProviderInfo "服务商列表"
+// @Router /api/llm-providers [get]
+func ListLLMProvidersHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data":      service.ListLLMProviders(),
+		"code":      200,
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
+
+// SSE 心跳间隔
+const sseHeartbeatInterval = 15 * time.Second
 
+// writeSSEStream 以 Server-Sent Events 格式转发事件
+// 每个事件带 id(流ID:序号)、event 和一行 JSON 的 data, 客户端断线后可以用 Last-Event-ID 续传
+func writeSSEStream(c *gin.Context, streamID string, records <-chan model.LLMStreamRecord) {
 	// 获取响应写入器
 	w := c.Writer
 	flusher, ok := w.(http.Flusher)
@@ -45,38 +171,77 @@ func SendMessageToLLMStreamHandler(c *gin.Context) {
 		return
 	}
 
+	// 设置 SSE 响应头
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("Access-Control-Allow-Origin", "*")
+	c.Header("X-Stream-ID", streamID)
+
 	// 标记流式传输已开始
 	c.Status(http.StatusOK)
 
-	// 循环处理数据和错误
+	// 排队或工具调用期间没有事件时定期发送注释行, 避免代理因连接空闲断开
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
 	for {
 		select {
-		case data, ok := <-dataChan:
+		case record, ok := <-records:
 			if !ok {
-				// 数据通道已关闭
-				fmt.Fprint(w, "data: [DONE]\n\n")
-				flusher.Flush()
 				return
 			}
+			fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", service.LLMEventID(streamID, record.Seq), record.Event, record.Data)
+			flusher.Flush()
+			heartbeat.Reset(sseHeartbeatInterval)
 
-			// 发送数据块
-			if data != "" {
-				fmt.Fprintf(w, "data: %s\n\n", data)
-				flusher.Flush()
-			}
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
 
-		case err := <-errChan:
-			if err != nil {
-				// 发送错误信息
-				fmt.Fprintf(w, "data: {\"error\": \"%s\"}\n\n", err.Error())
-				flusher.Flush()
-			}
+		case <-c.Request.Context().Done():
+			// 客户端断开连接, 生成在后台继续, 重连后可以续传
+			fmt.Println("客户端断开连接 - 流ID:", streamID)
 			return
+		}
+	}
+}
 
-		case <-c.Request.Context().Done():
-			// 客户端断开连接
-			fmt.Println("客户端断开连接")
+// resumeLLMStream 按 Last-Event-ID 续传之前的生成
+func resumeLLMStream(c *gin.Context, lastEventID string) {
+	streamID, records, err := service.ResumeLLMStream(c.Request.Context(), c.GetString("username"), lastEventID)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidLastEventID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": 400, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+			return
+		}
+		if errors.Is(err, service.ErrLLMStreamNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error(), "code": 404, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
 			return
 		}
+		fmt.Println("续传LLM流错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "续传失败", "code": 500, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	writeSSEStream(c, streamID, records)
+}
+
+// ResumeLLMStreamHandler 断线续传
+// @Summary 续传LLM流式响应
+// @Description 按 Last-Event-ID 请求头(或 lastEventId 参数)推送该事件之后的事件，生成尚未结束时继续推送直到 done / error 事件。事件在Redis中缓存 LLM_STREAM_BUFFER_TTL 秒，只有发起生成的用户(未登录时为同一客户端地址)可以续传
+// @Tags LLM API
+// @Produce text/event-stream
+// @Security BearerAuth
+// @Param Last-Event-ID header string false "最后收到的事件ID(流ID:序号)"
+// @Param lastEventId query string false "最后收到的事件ID, 适用于无法设置请求头的客户端"
+// @Success 200 {string} string "流式响应数据"
+// @Failure 400 {object} map[string]interface{} "事件ID格式错误"
+// @Failure 401 {object} map[string]interface{} "token或API密钥无效"
+// @Failure 404 {object} map[string]interface{} "流不存在或已过期"
+// @Router /api/llm-stream [get]
+func ResumeLLMStreamHandler(c *gin.Context) {
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("lastEventId")
 	}
+	resumeLLMStream(c, lastEventID)
 }
@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"gin/model"
+	"gin/service"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListModerationIncidentsHandler 查询内容审核记录
+// @Summary 查询内容审核记录
+// @Description 管理接口，按时间倒序分页返回内容审核命中记录，可按复核状态、阶段(input/output)和分类过滤
+// @Tags LLM API
+// @Produce json
+// @Param page query int false "页码"
+// @Param pageSize query int false "每页数量"
+// @Param reviewed query bool false "是否已复核"
+// @Param stage query string false "阶段 input/output"
+// @Param category query string false "分类"
+// @Success 200 {array} model.LLMModerationIncident "审核记录"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 500 {object} map[string]interface{} "内部服务器错误"
+// @Router /api/admin/llm-moderation/incidents [get]
+func ListModerationIncidentsHandler(c *gin.Context) {
+	page, pageSize := parsePagination(c)
+	var reviewed *bool
+	if value := c.Query("reviewed"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "reviewed 参数格式错误", "code": 400, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+			return
+		}
+		reviewed = &parsed
+	}
+	stage := c.Query("stage")
+	if stage != "" && stage != model.ModerationStageInput && stage != model.ModerationStageOutput {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "stage 只能为 input 或 output", "code": 400, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	incidents, total, err := service.ListModerationIncidents(page, pageSize, reviewed, stage, c.Query("category"))
+	if err != nil {
+		fmt.Println("查询审核记录错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询审核记录失败", "code": 500, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":      200,
+		"data":      incidents,
+		"total":     total,
+		"page":      page,
+		"pageSize":  pageSize,
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
+
+// ReviewModerationIncidentHandler 复核内容审核记录
+// @Summary 复核内容审核记录
+// @Description 管理接口，将审核记录标记为已复核并保存备注
+// @Tags LLM API
+// @Accept json
+// @Produce json
+// @Param id path int true "记录ID"
+// @Param request body model.ReviewModerationIncidentRequest false "复核备注"
+// @Success 200 {object} model.LLMModerationIncident "审核记录"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 404 {object} map[string]interface{} "记录不存在"
+// @Router /api/admin/llm-moderation/incidents/{id}/review [put]
+func ReviewModerationIncidentHandler(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "记录ID格式错误", "code": 400, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	var req model.ReviewModerationIncidentRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": 400, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+			return
+		}
+	}
+	incident, err := service.ReviewModerationIncident(id, req.Note)
+	if errors.Is(err, service.ErrModerationIncidentNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error(), "code": 404, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	if err != nil {
+		fmt.Println("复核审核记录错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "复核审核记录失败", "code": 500, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data":      incident,
+		"code":      200,
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
+
+// ReloadModerationRulesHandler 重新加载内容审核规则
+// @Summary 重新加载内容审核规则
+// @Description 管理接口，从 LLM_MODERATION_RULES_FILE 重新读取关键词和正则规则，加载失败时保留原有规则
+// @Tags LLM API
+// @Produce json
+// @Success 200 {object} map[string]interface{} "加载成功"
+// @Failure 500 {object} map[string]interface{} "加载失败"
+// @Router /api/admin/llm-moderation/reload [post]
+func ReloadModerationRulesHandler(c *gin.Context) {
+	count, err := service.LoadLLMModerationRules()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "code": 500, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "内容审核规则已重新加载",
+		"code":      200,
+		"count":     count,
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
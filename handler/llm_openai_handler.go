@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gin/model"
+	"gin/service"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondOpenAIError 以 OpenAI 的错误格式返回, 便于 OpenAI SDK 解析
+func respondOpenAIError(c *gin.Context, err error) {
+	status, errType, code := http.StatusInternalServerError, "server_error", ""
+	message := "LLM请求失败"
+	var quotaErr *service.QuotaExceededError
+	switch {
+	case errors.As(err, &quotaErr):
+		c.Header("Retry-After", strconv.Itoa(int(time.Until(quotaErr.ResetAt).Seconds())+1))
+		status, errType, code, message = http.StatusTooManyRequests, "insufficient_quota", "insufficient_quota", err.Error()
+	case errors.Is(err, service.ErrLLMQueueFull) || errors.Is(err, service.ErrLLMQueueTimeout):
+		c.Header("Retry-After", strconv.Itoa(llmBusyRetryAfter))
+		status, errType, code, message = http.StatusServiceUnavailable, "server_error", "server_busy", err.Error()
+	case errors.Is(err, service.ErrLLMProviderNotFound) || errors.Is(err, service.ErrLLMModelNotAllowed):
+		status, errType, code, message = http.StatusNotFound, "invalid_request_error", "model_not_found", err.Error()
+	case errors.Is(err, service.ErrInvalidLLMParams):
+		status, errType, message = http.StatusBadRequest, "invalid_request_error", err.Error()
+	case errors.Is(err, service.ErrLLMInputBlocked):
+		status, errType, code, message = http.StatusBadRequest, "invalid_request_error", "content_policy_violation", err.Error()
+	default:
+		fmt.Println("OpenAI兼容接口错误:", err)
+	}
+	c.JSON(status, gin.H{"error": openAIErrorBody(message, errType, code)})
+}
+
+func openAIErrorBody(message, errType, code string) gin.H {
+	body := gin.H{"message": message, "type": errType, "code": nil}
+	if code != "" {
+		body["code"] = code
+	}
+	return body
+}
+
+// OpenAIChatCompletionsHandler OpenAI 兼容的对话接口
+// @Summary OpenAI 兼容的对话接口
+// @Description 请求和响应格式与 OpenAI Chat Completions 相同，可直接使用 OpenAI SDK(base_url 指向本服务的 /v1，api_key 使用本站API密钥)。
+// @Description model 为 /v1/models 返回的 服务商/模型，也可以只写模型名。支持 stream 和 stream_options.include_usage，推理模型的推理过程在 reasoning_content 中返回。
+// @Description 用量计入调用者的每日/每月token配额，超出并发限制时排队等待，暂不支持客户端定义的工具(tools)。扩展字段 cache 为 true 且 temperature 为 0 时使用响应缓存。消息未通过内容审核返回400(code 为 content_policy_violation)，回复被审核中止时 finish_reason 为 content_filter。错误以 OpenAI 格式 {"error": {...}} 返回
+// @Tags LLM API
+// @Accept json
+// @Produce json,text/event-stream
+// @Security BearerAuth
+// @Param request body model.OpenAIChatRequest true "对话请求"
+// @Success 200 {object} model.OpenAIChatCompletion "对话结果(stream 为 true 时为 chat.completion.chunk 数据流)"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 401 {object} map[string]interface{} "未登录且未提供API密钥"
+// @Failure 404 {object} map[string]interface{} "模型不存在"
+// @Failure 429 {object} map[string]interface{} "token配额已用完"
+// @Failure 500 {object} map[string]interface{} "内部服务器错误"
+// @Failure 503 {object} map[string]interface{} "排队已满或排队超时"
+// @Router /v1/chat/completions [post]
+func OpenAIChatCompletionsHandler(c *gin.Context) {
+	var req model.OpenAIChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": openAIErrorBody(err.Error(), "invalid_request_error", "")})
+		return
+	}
+
+	if !req.Stream {
+		completion, err := service.OpenAIChatCompletion(c.Request.Context(), llmCaller(c), req)
+		if err != nil {
+			respondOpenAIError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, completion)
+		return
+	}
+
+	chunks, errChan, err := service.OpenAIChatStream(c.Request.Context(), llmCaller(c), req)
+	if err != nil {
+		respondOpenAIError(c, err)
+		return
+	}
+	w := c.Writer
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": openAIErrorBody("不支持流式传输", "server_error", "")})
+		return
+	}
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Status(http.StatusOK)
+
+	for {
+		select {
+		case chunk, ok := <-chunks:
+			if !ok {
+				// 数据通道关闭时错误通道也已关闭, 先检查是否有错误
+				if err := <-errChan; err != nil {
+					fmt.Println("OpenAI兼容接口流式错误:", err)
+					data, _ := json.Marshal(gin.H{"error": openAIErrorBody(err.Error(), "server_error", "")})
+					fmt.Fprintf(w, "data: %s\n\n", data)
+					flusher.Flush()
+					return
+				}
+				fmt.Fprint(w, "data: [DONE]\n\n")
+				flusher.Flush()
+				return
+			}
+			data, _ := json.Marshal(chunk)
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			flusher.Flush()
+
+		case <-c.Request.Context().Done():
+			fmt.Println("客户端断开连接")
+			return
+		}
+	}
+}
+
+// OpenAIModelsHandler OpenAI 兼容的模型列表
+// @Summary OpenAI 兼容的模型列表
+// @Description 返回已注册服务商的全部模型，id 为 服务商/模型
+// @Tags LLM API
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "模型列表 {object: list, data: [...]}"
+// @Failure 401 {object} map[string]interface{} "未登录且未提供API密钥"
+// @Router /v1/models [get]
+func OpenAIModelsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"object": "list",
+		"data":   service.ListOpenAIModels(),
+	})
+}
@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"gin/model"
+	"gin/service"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondPersonaError 按错误类型返回角色接口的状态码
+func respondPersonaError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	message := "角色操作失败"
+	switch {
+	case errors.Is(err, service.ErrInvalidLLMPersona):
+		status, message = http.StatusBadRequest, err.Error()
+	case errors.Is(err, service.ErrLLMPersonaNotFound):
+		status, message = http.StatusNotFound, err.Error()
+	default:
+		fmt.Println("角色操作错误:", err)
+	}
+	c.JSON(status, gin.H{"error": message, "code": status, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+}
+
+// ListLLMPersonasHandler 查询可用角色
+// @Summary 查询LLM角色
+// @Description 列出已启用的角色，调用 /api/llm-message 时通过 personaId 使用
+// @Tags LLM API
+// @Produce json
+// @Success 200 {array} model.LLMPersonaInfo "角色列表"
+// @Router /api/llm-personas [get]
+func ListLLMPersonasHandler(c *gin.Context) {
+	personas, err := service.ListEnabledLLMPersonas()
+	if err != nil {
+		respondPersonaError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data":      personas,
+		"code":      200,
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
+
+// AdminListLLMPersonasHandler 查询全部角色(管理员)
+// @Summary 查询全部LLM角色
+// @Tags LLM API
+// @Produce json
+// @Success 200 {array} model.LLMPersona "角色列表"
+// @Router /api/admin/llm-personas [get]
+func AdminListLLMPersonasHandler(c *gin.Context) {
+	personas, err := service.ListLLMPersonas()
+	if err != nil {
+		respondPersonaError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data":      personas,
+		"code":      200,
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
+
+// CreateLLMPersonaHandler 创建角色(管理员)
+// @Summary 创建LLM角色
+// @Tags LLM API
+// @Accept json
+// @Produce json
+// @Param request body model.LLMPersona true "角色"
+// @Success 200 {object} model.LLMPersona "角色"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Router /api/admin/llm-personas [post]
+func CreateLLMPersonaHandler(c *gin.Context) {
+	var req model.LLMPersona
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": 400, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	persona, err := service.CreateLLMPersona(req)
+	if err != nil {
+		respondPersonaError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data":      persona,
+		"code":      200,
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
+
+// UpdateLLMPersonaHandler 更新角色(管理员)
+// @Summary 更新LLM角色
+// @Tags LLM API
+// @Accept json
+// @Produce json
+// @Param id path int true "角色ID"
+// @Param request body model.LLMPersona true "角色"
+// @Success 200 {object} model.LLMPersona "角色"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 404 {object} map[string]interface{} "角色不存在"
+// @Router /api/admin/llm-personas/{id} [put]
+func UpdateLLMPersonaHandler(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "角色ID格式错误", "code": 400, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	var req model.LLMPersona
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": 400, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	persona, err := service.UpdateLLMPersona(id, req)
+	if err != nil {
+		respondPersonaError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data":      persona,
+		"code":      200,
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
+
+// DeleteLLMPersonaHandler 删除角色(管理员)
+// @Summary 删除LLM角色
+// @Tags LLM API
+// @Produce json
+// @Param id path int true "角色ID"
+// @Success 200 {object} map[string]interface{} "删除成功"
+// @Failure 404 {object} map[string]interface{} "角色不存在"
+// @Router /api/admin/llm-personas/{id} [delete]
+func DeleteLLMPersonaHandler(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "角色ID格式错误", "code": 400, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	if err := service.DeleteLLMPersona(id); err != nil {
+		respondPersonaError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "角色已删除",
+		"code":      200,
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"gin/model"
+	"gin/service"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateLLMAPIKeyHandler 创建API密钥
+// @Summary 创建LLM API密钥
+// @Description 创建用于调用LLM接口的API密钥，明文密钥只在创建时返回一次。通过API密钥的调用计入所属用户的token配额
+// @Tags LLM API
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body model.CreateLLMAPIKeyRequest false "备注"
+// @Success 200 {object} map[string]interface{} "创建成功"
+// @Failure 400 {object} map[string]interface{} "数量已达上限"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Router /api/llm-keys [post]
+func CreateLLMAPIKeyHandler(c *gin.Context) {
+	var req model.CreateLLMAPIKeyRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": 400, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+			return
+		}
+	}
+	key, secret, err := service.CreateLLMAPIKey(c.GetString("username"), req.Name)
+	if err != nil {
+		if errors.Is(err, service.ErrLLMAPIKeyLimit) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": 400, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+			return
+		}
+		fmt.Println("创建API密钥错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建API密钥失败", "code": 500, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data":      key,
+		"apiKey":    secret,
+		"code":      200,
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
+
+// ListLLMAPIKeysHandler 查询自己的API密钥
+// @Summary 查询LLM API密钥
+// @Tags LLM API
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} model.LLMAPIKey "密钥列表"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Router /api/llm-keys [get]
+func ListLLMAPIKeysHandler(c *gin.Context) {
+	keys, err := service.ListLLMAPIKeys(c.GetString("username"))
+	if err != nil {
+		fmt.Println("查询API密钥错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询API密钥失败", "code": 500, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data":      keys,
+		"code":      200,
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
+
+// DeleteLLMAPIKeyHandler 删除API密钥
+// @Summary 删除LLM API密钥
+// @Tags LLM API
+// @Produce json
+// @Security BearerAuth
+// @Param keyId path string true "密钥ID"
+// @Success 200 {object} map[string]interface{} "删除成功"
+// @Failure 404 {object} map[string]interface{} "密钥不存在"
+// @Router /api/llm-keys/{keyId} [delete]
+func DeleteLLMAPIKeyHandler(c *gin.Context) {
+	if err := service.DeleteLLMAPIKey(c.GetString("username"), c.Param("keyId")); err != nil {
+		if errors.Is(err, service.ErrLLMAPIKeyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error(), "code": 404, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+			return
+		}
+		fmt.Println("删除API密钥错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除API密钥失败", "code": 500, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "API密钥已删除",
+		"code":      200,
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
+
+// LLMUsageReportHandler 用量报表
+// @Summary 查询LLM用量
+// @Description 按服务商和模型汇总 token 用量，并返回当前的每日/每月配额使用情况。默认统计本月
+// @Tags LLM API
+// @Produce json
+// @Security BearerAuth
+// @Param from query string false "开始日期 YYYY-MM-DD"
+// @Param to query string false "结束日期 YYYY-MM-DD(包含)"
+// @Success 200 {object} model.LLMUsageReport "用量报表"
+// @Failure 400 {object} map[string]interface{} "日期格式错误"
+// @Failure 401 {object} map[string]interface{} "未登录且未提供API密钥"
+// @Router /api/llm-usage [get]
+func LLMUsageReportHandler(c *gin.Context) {
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+	to := from.AddDate(0, 1, 0)
+	if value := c.Query("from"); value != "" {
+		t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from 日期格式错误", "code": 400, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+			return
+		}
+		from = t
+	}
+	if value := c.Query("to"); value != "" {
+		t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to 日期格式错误", "code": 400, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+			return
+		}
+		to = t.AddDate(0, 0, 1)
+	}
+	if !to.After(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "结束日期不能早于开始日期", "code": 400, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+
+	report, err := service.GetLLMUsageReport(llmCaller(c), from, to)
+	if err != nil {
+		fmt.Println("查询LLM用量错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询LLM用量失败", "code": 500, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data":      report,
+		"code":      200,
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"fmt"
+	"gin/model"
+	"gin/service"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetPowChallengeHandler 获取工作量证明挑战
+// @Summary 获取工作量证明挑战
+// @Description 无感人机验证：返回随机 nonce 和难度，客户端找到 suffix 使 SHA-256(nonce+suffix) 前 difficulty 位为0，
+// @Description 之后将 challengeId 作为 humanCheckKey、suffix 作为 humanCheckCode 提交。同一IP请求越频繁难度越高
+// @Tags 验证码
+// @Produce json
+// @Success 200 {object} model.PowChallenge "挑战"
+// @Failure 500 {object} map[string]interface{} "内部服务器错误"
+// @Router /api/pow/challenge [post]
+func GetPowChallengeHandler(c *gin.Context) {
+	challenge, err := service.IssuePowChallenge(c.ClientIP())
+	if err != nil {
+		fmt.Println("签发PoW挑战失败:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成挑战失败", "code": 500, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":      200,
+		"data":      challenge,
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
+
+// VerifyPowHandler 校验工作量证明
+// @Summary 校验工作量证明
+// @Description 校验挑战的解，挑战校验后即失效
+// @Tags 验证码
+// @Accept json
+// @Produce json
+// @Param request body model.PowVerifyRequest true "挑战ID和后缀"
+// @Success 200 {object} map[string]interface{} "验证成功"
+// @Failure 400 {object} map[string]interface{} "验证失败"
+// @Router /api/pow/verify [post]
+func VerifyPowHandler(c *gin.Context) {
+	var req model.PowVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": 400, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	if service.VerifyPowSolution(req.ChallengeID, req.Suffix) {
+		c.JSON(http.StatusOK, gin.H{"message": "验证成功", "code": 200, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+	} else {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "挑战无效、已过期或解不正确", "code": 400, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+	}
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"gin/service"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RebuildRAGIndexHandler 重建检索索引
+// @Summary 重建检索索引
+// @Description 管理接口，提取静态资源目录中 EPUB、HTML 和文本文件的内容，切分后通过服务商向量化接口(未配置时使用本地哈希向量)建立索引并保存到本地
+// @Tags LLM API
+// @Produce json
+// @Success 200 {object} model.RAGIndexStatus "重建完成"
+// @Failure 409 {object} map[string]interface{} "索引正在重建"
+// @Failure 500 {object} map[string]interface{} "内部服务器错误"
+// @Router /api/admin/rag/reindex [post]
+func RebuildRAGIndexHandler(c *gin.Context) {
+	status, err := service.RebuildRAGIndex(c.Request.Context())
+	if err != nil {
+		if errors.Is(err, service.ErrRAGIndexBuilding) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": 409, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+			return
+		}
+		fmt.Println("重建检索索引错误:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "重建检索索引失败: " + err.Error(), "code": 500, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "索引重建完成",
+		"data":      status,
+		"code":      200,
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
+
+// RAGIndexStatusHandler 查询检索索引状态
+// @Summary 查询检索索引状态
+// @Tags LLM API
+// @Produce json
+// @Success 200 {object} model.RAGIndexStatus "索引状态"
+// @Router /api/admin/rag/status [get]
+func RAGIndexStatusHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data":      service.GetRAGIndexStatus(),
+		"code":      200,
+		"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
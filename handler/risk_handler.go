@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"errors"
+	"gin/model"
+	"gin/service"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// clientMeta 提取客户端信息用于风险评估
+// ClientIP 只信任 TRUSTED_PROXIES 中的代理转发的地址, 否则为连接地址
+func clientMeta(c *gin.Context) model.ClientMeta {
+	return model.ClientMeta{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+}
+
+// respondRiskRequirement 风险评估要求额外验证时返回对应提示, 返回 true 表示已处理
+func respondRiskRequirement(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, service.ErrHumanCheckRequired):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             err.Error(),
+			"code":              400,
+			"message":           "请完成人机验证",
+			"requireHumanCheck": true,
+			"timestamp":         time.Now().Format("2006-01-02 15:04:05"),
+		})
+		return true
+	case errors.Is(err, service.ErrEmailCodeRequired):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":            err.Error(),
+			"code":             400,
+			"message":          "请获取邮箱验证码",
+			"requireEmailCode": true,
+			"timestamp":        time.Now().Format("2006-01-02 15:04:05"),
+		})
+		return true
+	}
+	return false
+}
+
+// RiskCheckHandler 风险预检
+// @Summary 风险预检
+// @Description 根据IP信誉、失败历史、User-Agent和请求速率评估风险，告知前端本次注册/重置密码/登录是否需要人机验证和邮箱验证码
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param request body model.RiskCheckRequest true "业务场景和账号"
+// @Success 200 {object} model.RiskAssessment "评估结果"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Router /api/risk/check [post]
+func RiskCheckHandler(c *gin.Context) {
+	var req model.RiskCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": 400, "timestamp": time.Now().Format("2006-01-02 15:04:05")})
+		return
+	}
+
+	assessment := service.AssessRisk(req.Action, req.Identity, clientMeta(c))
+	// 风险分和命中原因不对外暴露, 避免被用来调试绕过
+	c.JSON(http.StatusOK, gin.H{
+		"code":              200,
+		"level":             assessment.Level,
+		"requireHumanCheck": assessment.RequireHumanCheck,
+		"requireEmailCode":  assessment.RequireEmailCode,
+		"timestamp":         time.Now().Format("2006-01-02 15:04:05"),
+	})
+}
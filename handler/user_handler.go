@@ -13,7 +13,7 @@ import (
 
 // RegisterHandler 用户注册处理器
 // @Summary      用户注册
-// @Description  使用SRP协议进行安全的用户注册，需要验证邮箱验证码和图形验证码
+// @Description  使用SRP协议进行安全的用户注册，风险较高时需要验证邮箱验证码和图形验证码
 // @Tags         用户管理
 // @Accept       json
 // @Produce      json
@@ -47,9 +47,6 @@ func RegisterHandler(c *gin.Context) {
 		"[邮箱]":       req.Email,
 		"[Salt]":     req.Salt,
 		"[Verifier]": req.Verifier,
-		"[邮箱验证码]":    req.EmailVerificationCode,
-		"[图形验证码Key]": req.HumanCheckKey,
-		"[图形验证码]":    req.HumanCheckCode,
 	}
 
 	var missingParams []string
@@ -69,31 +66,13 @@ func RegisterHandler(c *gin.Context) {
 		return
 	}
 
-	// 检查验证码是否为空
-	if req.EmailVerificationCode == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":     "邮箱验证码不能为空",
-			"code":      400,
-			"message":   "请获取邮箱验证码",
-			"timestamp": time.Now().Format("2006-01-02 15:04:05"),
-		})
-		return
-	}
-
-	if req.HumanCheckKey == "" || req.HumanCheckCode == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":     "图形验证码不能为空",
-			"code":      400,
-			"message":   "请获取图形验证码",
-			"timestamp": time.Now().Format("2006-01-02 15:04:05"),
-		})
-		return
-	}
-
 	// 调用注册服务
-	err := service.RegisterService(req)
+	err := service.RegisterService(req, clientMeta(c))
 	if err != nil {
 		fmt.Println("注册失败:", err)
+		if respondRiskRequirement(c, err) {
+			return
+		}
 
 		// 根据错误类型返回不同的状态码
 		if err.Error() == "用户名或邮箱已存在" {
@@ -148,7 +127,7 @@ func RegisterHandler(c *gin.Context) {
 
 // LoginHandler 用户登录处理器
 // @Summary      用户登录第一步
-// @Description  使用SRP协议进行安全的用户登录，返回服务器公钥和盐值
+// @Description  使用SRP协议进行安全的用户登录，返回服务器公钥和盐值，风险较高时需要先完成人机验证
 // @Tags         用户管理
 // @Accept       json
 // @Produce      json
@@ -178,9 +157,12 @@ func LoginHandler(c *gin.Context) {
 	fmt.Println("登录请求 - 用户名:", req.Username)
 
 	// 调用登录服务
-	response, err := service.LoginService(req)
+	response, err := service.LoginService(req, clientMeta(c))
 	if err != nil {
 		fmt.Println("登录失败:", err)
+		if respondRiskRequirement(c, err) {
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error":     err.Error(),
 			"code":      401,
@@ -232,7 +214,7 @@ func LoginStep2Handler(c *gin.Context) {
 	fmt.Println(" 登录第二步请求 - 用户名:", req.Username)
 
 	// 调用登录第二步服务
-	response, err := service.LoginStep2Service(req)
+	response, err := service.LoginStep2Service(req, clientMeta(c))
 	if err != nil {
 		fmt.Println(" 登录第二步失败:", err)
 		c.JSON(http.StatusUnauthorized, gin.H{
@@ -256,7 +238,7 @@ func LoginStep2Handler(c *gin.Context) {
 
 // ChangePasswordHandler 用户修改密码处理器
 // @Summary      用户修改密码
-// @Description  使用SRP协议进行安全的用户密码修改，需要验证邮箱验证码和图形验证码
+// @Description  使用SRP协议进行安全的用户密码修改，需要验证邮箱验证码，风险较高时还需要图形验证码
 // @Tags         用户管理
 // @Accept       json
 // @Produce      json
@@ -285,9 +267,6 @@ func ChangePasswordHandler(c *gin.Context) {
 		"[邮箱]":       req.Email,
 		"[Salt]":     req.Salt,
 		"[Verifier]": req.Verifier,
-		"[邮箱验证码]":    req.EmailVerificationCode,
-		"[图形验证码Key]": req.HumanCheckKey,
-		"[图形验证码]":    req.HumanCheckCode,
 	}
 	var missingParams []string
 	for name, value := range params {
@@ -305,9 +284,12 @@ func ChangePasswordHandler(c *gin.Context) {
 		return
 	}
 	// 调用修改密码服务
-	err := service.ResetPasswordService(req)
+	err := service.ResetPasswordService(req, clientMeta(c))
 	if err != nil {
 		fmt.Println("修改密码失败:", err)
+		if respondRiskRequirement(c, err) {
+			return
+		}
 		// 根据错误类型返回不同的状态码
 		if err.Error() == "邮箱不存在" {
 			c.JSON(http.StatusBadRequest, gin.H{
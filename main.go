@@ -60,15 +60,40 @@ func RateLimitMiddleware(rps rate.Limit, burst int) gin.HandlerFunc {
 func main() {
 	public := gin.Default()
 	private := gin.Default()
+	// 限流、风险评估等按客户端IP计数, 只信任配置的反向代理转发的IP, 防止伪造 X-Forwarded-For
+	if err := public.SetTrustedProxies(config.TrustedProxies); err != nil {
+		fmt.Printf("错误: 可信代理配置无效: %v\n", err)
+		return
+	}
 	db.InitRedis()
 	db.InitMysql()
 	// 自动迁移数据库结构
-	db.DB.AutoMigrate(&model.User{})
+	db.DB.AutoMigrate(&model.User{}, &model.EmailSuppression{}, &model.EncryptionMessage{}, &model.EncryptionAttachment{}, &model.UserPublicKey{}, &model.LLMConversation{}, &model.LLMConversationMessage{}, &model.LLMAPIKey{}, &model.LLMUsageRecord{}, &model.LLMPersona{}, &model.LLMModerationIncident{})
 	if err := utils.InitRSAKeys(); err != nil {
 		fmt.Printf("错误: %v\n", err)
 		return
 	}
 
+	if err := service.InitEncryptionStore(); err != nil {
+		fmt.Printf("错误: %v\n", err)
+		return
+	}
+
+	if err := service.InitLLMProviders(); err != nil {
+		fmt.Printf("错误: %v\n", err)
+		return
+	}
+
+	// 加载检索索引, 失败时检索功能不可用
+	if err := service.InitRAGIndex(); err != nil {
+		fmt.Printf("警告: %v\n", err)
+	}
+
+	if err := service.InitAttachmentStorage(); err != nil {
+		fmt.Printf("错误: %v\n", err)
+		return
+	}
+
 	// 初始化定时任务
 	if err := service.InitScheduledTasks(); err != nil {
 		fmt.Printf("警告: 定时任务初始化失败: %v\n", err)
@@ -77,8 +102,8 @@ func main() {
 	public.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"}, // 建议生产配置具体域名
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-Upload-Token", "Range", "X-API-Key", "Last-Event-ID"},
+		ExposeHeaders:    []string{"Content-Length", "Content-Range", "Accept-Ranges", "Retry-After", "X-Stream-ID"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
@@ -115,8 +140,13 @@ func main() {
 	public.GET("/api/static-files", handler.StaticFilesHandler)        // 获取静态资源文件列表路由
 	public.POST("/api/send-email", handler.SendEmailHandler)           // 发送邮箱验证码路由
 	public.POST("/api/verify-code", handler.VerifyCodeHandler)         // 验证邮箱验证码路由
+	public.GET("/api/unsubscribe", handler.UnsubscribeHandler)         // 邮件退订确认页面(点击链接)
+	public.POST("/api/unsubscribe", handler.UnsubscribeHandler)        // 邮件退订路由(一键退订)
 	public.POST("/api/captcha", handler.GetCaptchaHandler)             // 获取图形验证码路由
 	public.POST("/api/verify-captcha", handler.VerifyCaptchaHandler)   // 验证图形验证码路由
+	public.POST("/api/pow/challenge", handler.GetPowChallengeHandler)  // 获取工作量证明挑战路由
+	public.POST("/api/pow/verify", handler.VerifyPowHandler)           // 验证工作量证明路由
+	public.POST("/api/risk/check", handler.RiskCheckHandler)           // 风险预检路由
 	public.GET("/api/bili-follow-anime", handler.BilibiliAnimeHandler) // 获取B站追番列表路由
 	public.GET("/api/bili-follow-movie", handler.BilibiliMovieHandler) // 获取B站追剧列表路由
 	// public.GET("/api/server-status", handler.GetServerStatusHandler)       // 获取服务器运行状态路由
@@ -128,21 +158,63 @@ func main() {
 	public.POST("/api/login/step2", handler.LoginStep2Handler)        // 用户登录路由（第二步）
 	public.POST("/api/reset-password", handler.ChangePasswordHandler) // 用户重置密码路由
 	public.POST("/api/reset-email", handler.ResetEmailHandler)
-	public.POST("/api/llm-message/deepseek", handler.SendMessageToLLMStreamHandler) // 流式传输 DeepSeek 消息
-	public.POST("/api/SendEncryptionMessage", handler.EncryptMessageHandler)        // 加密消息传输接口
-	public.POST("/api/GetEncryptionMessage", handler.DecryptMessageHandler)         // 解密消息传输接口
-	
+	public.POST("/api/llm-message", middleware.OptionalLLMIdentityMiddleware(), handler.SendMessageToLLMStreamHandler)               // 流式传输 LLM 消息
+	public.POST("/api/llm-message/deepseek", middleware.OptionalLLMIdentityMiddleware(), handler.SendMessageToDeepseekStreamHandler) // 流式传输 DeepSeek 消息
+	public.GET("/api/llm-providers", handler.ListLLMProvidersHandler)                                                                // 查询LLM服务商和模型
+	public.GET("/api/llm-personas", handler.ListLLMPersonasHandler)                                                                  // 查询LLM角色
+	public.POST("/v1/chat/completions", middleware.LLMIdentityMiddleware(), handler.OpenAIChatCompletionsHandler)                    // OpenAI 兼容的对话接口
+	public.GET("/v1/models", middleware.LLMIdentityMiddleware(), handler.OpenAIModelsHandler)                                        // OpenAI 兼容的模型列表
+	public.GET("/api/llm-stream", middleware.OptionalLLMIdentityMiddleware(), handler.ResumeLLMStreamHandler)                        // 续传LLM流式响应
+	public.GET("/api/llm-usage", middleware.LLMIdentityMiddleware(), handler.LLMUsageReportHandler)                                  // 查询LLM用量
+	public.POST("/api/SendEncryptionMessage", middleware.OptionalJWTAuthMiddleware(), handler.EncryptMessageHandler)                 // 加密消息传输接口
+	public.POST("/api/GetEncryptionMessage", middleware.OptionalJWTAuthMiddleware(), handler.DecryptMessageHandler)                  // 解密消息传输接口
+	public.GET("/api/users/:username/keys", handler.LookupUserKeysHandler)                                                           // 公钥目录: 查询用户公钥
+	public.POST("/api/encryption-attachments", handler.InitAttachmentHandler)                                                        // 创建加密附件上传任务
+	public.PUT("/api/encryption-attachments/:id/chunks/:index", handler.UploadAttachmentChunkHandler)                                // 上传加密附件分片
+	public.GET("/api/encryption-attachments/:id/status", handler.AttachmentUploadStatusHandler)                                      // 查询加密附件上传进度
+	public.POST("/api/encryption-attachments/:id/complete", handler.CompleteAttachmentHandler)                                       // 完成加密附件上传
+	public.GET("/api/encryption-attachments/download/:token", handler.DownloadAttachmentHandler)                                     // 下载加密附件
+
 	// Swagger 文档路由
 	public.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	auth.Use(middleware.JWTAuthMiddleware())
+	auth.POST("/api/keys", handler.PublishKeyHandler)                                    // 发布公钥
+	auth.GET("/api/keys", handler.ListMyKeysHandler)                                     // 查询自己的公钥
+	auth.DELETE("/api/keys/:keyId", handler.DeleteKeyHandler)                            // 删除公钥
+	auth.GET("/api/inbox", handler.InboxHandler)                                         // 收件箱
+	auth.POST("/api/conversations", handler.CreateConversationHandler)                   // 创建LLM对话
+	auth.GET("/api/conversations", handler.ListConversationsHandler)                     // 查询LLM对话列表
+	auth.PUT("/api/conversations/:id", handler.RenameConversationHandler)                // 重命名LLM对话
+	auth.DELETE("/api/conversations/:id", handler.DeleteConversationHandler)             // 删除LLM对话
+	auth.GET("/api/conversations/:id/messages", handler.ListConversationMessagesHandler) // 查询LLM对话消息
+	auth.POST("/api/conversations/:id/messages", handler.SendConversationMessageHandler) // 在LLM对话中发送消息(流式)
+	auth.POST("/api/llm-keys", handler.CreateLLMAPIKeyHandler)                           // 创建LLM API密钥
+	auth.GET("/api/llm-keys", handler.ListLLMAPIKeysHandler)                             // 查询LLM API密钥
+	auth.DELETE("/api/llm-keys/:keyId", handler.DeleteLLMAPIKeyHandler)                  // 删除LLM API密钥
 	// {
 	// 	auth.POST("/api/proxy",handler.ProxyDownloadHandler) // 代理下载路由
 	// }
 
 	private.POST("/api/proxy", handler.ProxyDownloadHandler)
-	private.GET("/api/dns/query", handler.QueryDNSHandler)                           // DNS查询接口 (GET)
-	private.POST("/api/dns/query", handler.QueryDNSPostHandler)                      // DNS查询接口 (POST)
+	private.GET("/api/dns/query", handler.QueryDNSHandler)                                                 // DNS查询接口 (GET)
+	private.POST("/api/dns/query", handler.QueryDNSPostHandler)                                            // DNS查询接口 (POST)
+	private.POST("/api/admin/email/disposable-domains/reload", handler.ReloadDisposableDomainsHandler)     // 重新加载一次性邮箱域名列表
+	private.GET("/api/admin/email/dns-check", handler.EmailDNSCheckHandler)                                // 检查发信域名 DKIM/SPF/DMARC 记录
+	private.GET("/api/admin/email-suppressions", handler.ListSuppressionsHandler)                          // 查询邮件屏蔽名单
+	private.POST("/api/admin/email-suppressions", handler.ImportSuppressionsHandler)                       // 导入邮件屏蔽名单
+	private.DELETE("/api/admin/email-suppressions", handler.RemoveSuppressionsHandler)                     // 移除邮件屏蔽名单
+	private.POST("/api/admin/encryption-messages/cleanup", handler.CleanupEncryptionMessagesHandler)       // 立即清理过期加密消息
+	private.GET("/api/admin/llm-personas", handler.AdminListLLMPersonasHandler)                            // 查询全部LLM角色
+	private.POST("/api/admin/llm-personas", handler.CreateLLMPersonaHandler)                               // 创建LLM角色
+	private.PUT("/api/admin/llm-personas/:id", handler.UpdateLLMPersonaHandler)                            // 更新LLM角色
+	private.DELETE("/api/admin/llm-personas/:id", handler.DeleteLLMPersonaHandler)                         // 删除LLM角色
+	private.DELETE("/api/admin/llm-cache", handler.PurgeLLMCacheHandler)                                   // 清除LLM响应缓存
+	private.GET("/api/admin/llm-moderation/incidents", handler.ListModerationIncidentsHandler)             // 查询内容审核记录
+	private.PUT("/api/admin/llm-moderation/incidents/:id/review", handler.ReviewModerationIncidentHandler) // 复核内容审核记录
+	private.POST("/api/admin/llm-moderation/reload", handler.ReloadModerationRulesHandler)                 // 重新加载内容审核规则
+	private.POST("/api/admin/rag/reindex", handler.RebuildRAGIndexHandler)                                 // 重建检索索引
+	private.GET("/api/admin/rag/status", handler.RAGIndexStatusHandler)                                    // 查询检索索引状态
 	private.GET("/private/test", func(c *gin.Context) {
 		c.JSON(200, gin.H{
 			"message": "Private API is running!",
@@ -47,4 +47,17 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 
-}
\ No newline at end of file
+}
+
+// OptionalJWTAuthMiddleware 可选的JWT校验
+// 未携带token时直接放行, 携带了token则必须有效, 校验通过后同样设置 username
+func OptionalJWTAuthMiddleware() gin.HandlerFunc {
+	required := JWTAuthMiddleware()
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+		required(c)
+	}
+}
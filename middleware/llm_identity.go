@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"gin/service"
+	"gin/utils"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LLMIdentityMiddleware 识别LLM接口的调用者
+// 支持登录token(Authorization: Bearer <JWT>)和API密钥(Authorization: Bearer sk-... 或 X-API-Key)
+// 校验通过后设置 llmCaller 和 username
+func LLMIdentityMiddleware() gin.HandlerFunc {
+	return llmIdentityMiddleware(false)
+}
+
+// OptionalLLMIdentityMiddleware 与 LLMIdentityMiddleware 相同, 但未提供凭证时按客户端地址识别为未登录调用者(使用匿名配额)
+// 提供了无效凭证时仍然返回401
+func OptionalLLMIdentityMiddleware() gin.HandlerFunc {
+	return llmIdentityMiddleware(true)
+}
+
+func llmIdentityMiddleware(allowAnonymous bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		credential := c.GetHeader("X-API-Key")
+		if credential == "" {
+			parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2)
+			if len(parts) == 2 && parts[0] == "Bearer" {
+				credential = strings.TrimSpace(parts[1])
+			}
+		}
+		if credential == "" && allowAnonymous {
+			caller := service.AnonymousLLMCaller(c.ClientIP())
+			c.Set("llmCaller", caller)
+			c.Set("username", caller.Username)
+			c.Next()
+			return
+		}
+		if credential == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"code":      401,
+				"error":     "请登录或提供API密钥",
+				"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+			})
+			return
+		}
+
+		if strings.HasPrefix(credential, service.LLMAPIKeyPrefix) {
+			caller, err := service.AuthenticateLLMAPIKey(credential)
+			if err != nil {
+				if !errors.Is(err, service.ErrLLMAPIKeyInvalid) {
+					fmt.Println("校验API密钥错误:", err)
+				}
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+					"code":      401,
+					"error":     service.ErrLLMAPIKeyInvalid.Error(),
+					"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+				})
+				return
+			}
+			c.Set("llmCaller", caller)
+			c.Set("username", caller.Username)
+			c.Next()
+			return
+		}
+
+		claims, err := utils.ParseToken(credential)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"code":      401,
+				"error":     "无效的 Token",
+				"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+			})
+			return
+		}
+		caller, err := service.LLMCallerFromUsername(claims.Username)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"code":      401,
+				"error":     "用户不存在",
+				"timestamp": time.Now().Format("2006-01-02 15:04:05"),
+			})
+			return
+		}
+		c.Set("llmCaller", caller)
+		c.Set("username", caller.Username)
+		c.Next()
+	}
+}
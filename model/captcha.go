@@ -0,0 +1,44 @@
+package model
+
+// 图形验证码类型
+const (
+	CaptchaTypeDigit   = "digit"   // 数字
+	CaptchaTypeString  = "string"  // 字母数字混合, 校验时忽略大小写
+	CaptchaTypeMath    = "math"    // 算术题, 校验计算结果
+	CaptchaTypeChinese = "chinese" // 中文
+	CaptchaTypeAudio   = "audio"   // 语音数字
+	CaptchaTypeSlider  = "slider"  // 滑块拼图
+)
+
+// CaptchaOptions 经过服务端策略校验后的验证码生成参数
+type CaptchaOptions struct {
+	Type     string `json:"type"`     // 验证码类型
+	Width    int    `json:"width"`    // 图片宽度
+	Height   int    `json:"height"`   // 图片高度
+	Length   int    `json:"length"`   // 验证码长度(算术题无效)
+	Language string `json:"language"` // 语音验证码语言 en/zh/ja/ru
+}
+
+// SliderCaptcha 滑块拼图验证码
+type SliderCaptcha struct {
+	ID         string `json:"uuid"`      // 验证码ID, 作为 humanCheckKey 使用
+	Background string `json:"captcha"`   // 带缺口的背景图(base64 PNG)
+	Piece      string `json:"piece"`     // 拼图块(base64 PNG)
+	PieceY     int    `json:"pieceY"`    // 拼图块在背景图中的纵坐标
+	Width      int    `json:"width"`     // 背景图宽度
+	Height     int    `json:"height"`    // 背景图高度
+	PieceSize  int    `json:"pieceSize"` // 拼图块宽高
+}
+
+// SliderAnswer 滑块验证码答案, 以JSON字符串形式放在 humanCheckCode 中提交
+type SliderAnswer struct {
+	X     float64            `json:"x"`     // 拼图块最终的横坐标
+	Track []SliderTrackPoint `json:"track"` // 拖动轨迹
+}
+
+// SliderTrackPoint 拖动轨迹中的一个采样点
+type SliderTrackPoint struct {
+	X float64 `json:"x"` // 横向位移
+	Y float64 `json:"y"` // 纵向位移
+	T int64   `json:"t"` // 相对拖动开始的毫秒数
+}
@@ -0,0 +1,21 @@
+package model
+
+// EmailDNSReport 发信域名DNS记录检查结果
+type EmailDNSReport struct {
+	Domain      string         `json:"domain"`      // 发信域名
+	DKIMEnabled bool           `json:"dkimEnabled"` // 是否启用了DKIM签名
+	DKIM        DNSRecordCheck `json:"dkim"`        // DKIM记录检查
+	SPF         DNSRecordCheck `json:"spf"`         // SPF记录检查
+	DMARC       DNSRecordCheck `json:"dmarc"`       // DMARC记录检查
+	Passed      bool           `json:"passed"`      // 全部检查是否通过
+}
+
+// DNSRecordCheck 单条DNS记录的检查结果
+type DNSRecordCheck struct {
+	Name     string   `json:"name"`               // 查询的记录名
+	Record   string   `json:"record"`             // 查到的记录内容
+	Expected string   `json:"expected,omitempty"` // 期望的记录内容(仅DKIM)
+	Found    bool     `json:"found"`              // 是否找到记录
+	Passed   bool     `json:"passed"`             // 是否通过检查
+	Issues   []string `json:"issues"`             // 发现的问题
+}
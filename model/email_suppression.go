@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// 邮件屏蔽原因
+const (
+	SuppressionReasonBounce      = "bounce"      // 退信
+	SuppressionReasonComplaint   = "complaint"   // 投诉
+	SuppressionReasonUnsubscribe = "unsubscribe" // 用户退订
+	SuppressionReasonManual      = "manual"      // 管理员手动屏蔽
+)
+
+// EmailSuppression 邮件屏蔽名单 - 对应 emailsuppression 表
+type EmailSuppression struct {
+	ID        uint      `gorm:"column:id;primaryKey" json:"id"`
+	Email     string    `gorm:"column:email;type:varchar(255);uniqueIndex" json:"email"` // 被屏蔽的邮箱(小写)
+	Reason    string    `gorm:"column:reason;type:varchar(32)" json:"reason"`            // 屏蔽原因
+	Source    string    `gorm:"column:source;type:varchar(255)" json:"source"`           // 来源说明,如退订链接、导入批次
+	CreatedAt time.Time `gorm:"column:createdAt" json:"createdAt"`                       // 创建时间
+	UpdatedAt time.Time `gorm:"column:updatedAt" json:"updatedAt"`                       // 更新时间
+}
+
+// TableName 指定表名
+func (EmailSuppression) TableName() string {
+	return "emailsuppression"
+}
+
+// SuppressionImportRequest 批量导入屏蔽名单请求
+type SuppressionImportRequest struct {
+	Emails []string `json:"emails" binding:"required,min=1,max=5000,dive,email"` // 邮箱列表
+	Reason string   `json:"reason" binding:"omitempty,oneof=bounce complaint unsubscribe manual"`
+	Source string   `json:"source"`
+}
+
+// SuppressionRemoveRequest 批量移除屏蔽名单请求
+type SuppressionRemoveRequest struct {
+	Emails []string `json:"emails" binding:"required,min=1,max=5000,dive,email"` // 邮箱列表
+}
+
+// UnsubscribeRequest 退订请求
+type UnsubscribeRequest struct {
+	Token string `json:"token" form:"token" binding:"required"` // 退订链接中的签名token
+}
@@ -0,0 +1,57 @@
+package model
+
+import "time"
+
+// 附件上传状态
+const (
+	AttachmentStatusUploading = "uploading"
+	AttachmentStatusComplete  = "complete"
+)
+
+// EncryptionAttachment 加密消息附件, 内容由客户端加密后分片上传
+type EncryptionAttachment struct {
+	ID            uint64     `json:"-" gorm:"column:id;primaryKey;autoIncrement"`
+	AttachmentID  string     `json:"attachmentId" gorm:"column:attachmentId;type:varchar(64);uniqueIndex"` // 附件唯一ID
+	MessageUUID   string     `json:"messageUuid" gorm:"column:messageUuid;type:varchar(255);index"`        // 所属消息UUID
+	EncryptedName string     `json:"encryptedName" gorm:"column:encryptedName;type:text"`                  // 客户端加密后的文件名
+	Iv            string     `json:"iv" gorm:"column:iv;type:varchar(255)"`                                // IV值
+	Size          int64      `json:"size" gorm:"column:size"`                                              // 加密后文件总大小
+	ChunkSize     int64      `json:"chunkSize" gorm:"column:chunkSize"`                                    // 分片大小(最后一片可以更小)
+	TotalChunks   int        `json:"totalChunks" gorm:"column:totalChunks"`                                // 分片总数
+	Status        string     `json:"status" gorm:"column:status;type:varchar(16)"`                         // 上传状态
+	ExpiresAt     time.Time  `json:"-" gorm:"column:expiresAt;index"`                                      // 跟随消息的过期时间
+	DeleteAfter   *time.Time `json:"-" gorm:"column:deleteAfter;index"`                                    // 消息被销毁后附件的删除时间
+	CreatedAt     time.Time  `json:"-" gorm:"column:createdAt;autoCreateTime"`                             // 创建时间
+}
+
+// TableName 指定表名
+func (EncryptionAttachment) TableName() string {
+	return "encryptionattachment"
+}
+
+// AttachmentInitRequest 创建附件上传请求
+type AttachmentInitRequest struct {
+	MessageUUID   string `json:"messageUuid" binding:"required"`
+	UploadToken   string `json:"uploadToken" binding:"required"` // 存入消息时返回的上传凭证
+	EncryptedName string `json:"encryptedName" binding:"max=1024"`
+	Iv            string `json:"iv" binding:"max=255"`
+	Size          int64  `json:"size" binding:"required,gt=0"`
+	ChunkSize     int64  `json:"chunkSize" binding:"required,gt=0"` // 分片大小, 多于一个分片时不能小于配置的最小分片
+}
+
+// AttachmentUploadStatus 附件上传进度, 用于断点续传
+type AttachmentUploadStatus struct {
+	AttachmentID   string `json:"attachmentId"`
+	Status         string `json:"status"`
+	TotalChunks    int    `json:"totalChunks"`
+	UploadedChunks []int  `json:"uploadedChunks"`
+}
+
+// AttachmentDownload 取出消息时返回的附件信息
+type AttachmentDownload struct {
+	AttachmentID  string `json:"attachmentId"`
+	EncryptedName string `json:"encryptedName"`
+	Iv            string `json:"iv"`
+	Size          int64  `json:"size"`
+	DownloadToken string `json:"downloadToken"` // 下载凭证, 有效期内可多次使用(支持断点续传)
+}
@@ -1,10 +1,29 @@
 package model
 
+import "time"
+
 type EncryptionMessage struct {
-	UUID            string `json:"uuid" gorm:"column:uuid"`              // 消息唯一ID
-	EncryptedAESKey string `json:"encryptedAESKey" gorm:"column:encryptedAESKey"` // 加密的AES密钥
-	Iv              string `json:"iv" gorm:"column:iv"`                             // IV值
-	CipherText      string `json:"cipherText" gorm:"column:cipherText"`            // 密文
+	ID                uint64     `json:"-" gorm:"column:id;primaryKey;autoIncrement"`
+	UUID              string     `json:"uuid" gorm:"column:uuid;type:varchar(255);uniqueIndex"`                  // 消息唯一ID
+	EncryptedAESKey   string     `json:"encryptedAESKey" gorm:"column:encryptedAESKey;type:text"`                // 加密的AES密钥
+	Iv                string     `json:"iv" gorm:"column:iv;type:varchar(255)"`                                  // IV值
+	CipherText        string     `json:"cipherText" gorm:"column:cipherText;type:text"`                          // 密文
+	MaxViews          int        `json:"maxViews" gorm:"column:maxViews;not null;default:1"`                     // 最大查看次数, 1为阅后即焚
+	Views             int        `json:"-" gorm:"column:views;not null;default:0"`                               // 已查看次数
+	CreatedAt         time.Time  `json:"-" gorm:"column:createdAt;autoCreateTime"`                               // 创建时间
+	ExpiresAt         *time.Time `json:"-" gorm:"column:expiresAt;index"`                                        // 过期时间
+	AccessSalt        string     `json:"-" gorm:"column:accessSalt;type:varchar(64)"`                            // 访问口令校验盐值
+	AccessHash        string     `json:"-" gorm:"column:accessHash;type:varchar(128)"`                           // 访问口令校验值 Argon2id(accessKey, salt)
+	UploadTokenHash   string     `json:"-" gorm:"column:uploadTokenHash;type:varchar(64)"`                       // 附件上传凭证的SHA-256哈希
+	FailedAttempts    int        `json:"-" gorm:"column:failedAttempts;not null;default:0"`                      // 访问口令错误次数
+	RecipientUserID   string     `json:"-" gorm:"column:recipientUserId;type:varchar(64);index"`                 // 收件人用户ID, 为空表示通过链接分享
+	RecipientKeyID    string     `json:"recipientKeyId" gorm:"column:recipientKeyId;type:varchar(64)"`           // 加密AES密钥所用的收件人公钥ID
+	SenderUsername    string     `json:"-" gorm:"column:senderUsername;type:varchar(255)"`                       // 发送人用户名(已登录时记录)
+	NotifyEmail       string     `json:"notifyEmail,omitempty" gorm:"column:notifyEmail;type:varchar(255)"`      // 回执通知邮箱
+	NotifyWebhook     string     `json:"notifyWebhook,omitempty" gorm:"column:notifyWebhook;type:varchar(1024)"` // 回执通知Webhook地址
+	RecipientUsername string     `json:"recipientUsername,omitempty" gorm:"-"`                                   // 收件人用户名, 与 recipientKeyId 一起提交
+	AccessKey         string     `json:"accessKey,omitempty" gorm:"-"`                                           // 由访问口令在客户端派生的密钥, 服务端只保存其哈希
+	ExpiresIn         int        `json:"expiresIn" gorm:"-"`                                                     // 有效期(秒), 由发送方指定, 5分钟到30天
 }
 
 // TableName 指定表名
@@ -13,11 +32,38 @@ func (EncryptionMessage) TableName() string {
 }
 
 type DecryptionMessage struct {
-	UUID string `json:"uuid"` // 消息唯一ID
+	UUID      string `json:"uuid"`                // 消息唯一ID
+	AccessKey string `json:"accessKey,omitempty"` // 访问口令派生的密钥, 设置了口令的消息必填
 }
 
 type DecryptionResponse struct {
-	EncryptedAESKey string `json:"encryptedAESKey"` // 加密的AES密钥
-	Iv              string `json:"iv"`              // IV值
-	CipherText      string `json:"cipherText"`      // 密文
+	EncryptedAESKey string               `json:"encryptedAESKey"` // 加密的AES密钥
+	Iv              string               `json:"iv"`              // IV值
+	CipherText      string               `json:"cipherText"`      // 密文
+	RemainingViews  int                  `json:"remainingViews"`  // 剩余查看次数, 为0时消息已销毁
+	ExpiresAt       time.Time            `json:"expiresAt"`       // 过期时间
+	Attachments     []AttachmentDownload `json:"attachments"`     // 附件下载信息
+}
+
+// 消息回执事件
+const (
+	MessageEventRetrieved     = "retrieved"      // 消息被查看
+	MessageEventExpiredUnread = "expired_unread" // 消息过期且未被查看
+	MessageEventDestroyed     = "destroyed"      // 访问口令错误次数过多被销毁
+)
+
+// MessageNotification 发给发送方的回执, 只包含非敏感的元数据
+type MessageNotification struct {
+	Event          string    `json:"event"`              // 事件类型
+	MessageUUID    string    `json:"messageUuid"`        // 消息ID
+	OccurredAt     time.Time `json:"occurredAt"`         // 发生时间
+	Location       string    `json:"location,omitempty"` // 查看者的大致位置(国家/地区/城市)
+	RemainingViews int       `json:"remainingViews"`     // 剩余查看次数
+}
+
+// EncryptResult 存入消息的结果
+type EncryptResult struct {
+	UUID        string    `json:"uuid"`        // 服务端生成的消息ID
+	UploadToken string    `json:"uploadToken"` // 附件上传凭证
+	ExpiresAt   time.Time `json:"expiresAt"`   // 过期时间
 }
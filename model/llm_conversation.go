@@ -0,0 +1,52 @@
+package model
+
+import "time"
+
+// 对话消息角色
+const (
+	ChatRoleSystem    = "system"
+	ChatRoleUser      = "user"
+	ChatRoleAssistant = "assistant"
+	ChatRoleTool      = "tool"
+)
+
+// LLMConversation 用户与LLM的对话
+type LLMConversation struct {
+	ID             uint64    `json:"-" gorm:"column:id;primaryKey;autoIncrement"`
+	ConversationID string    `json:"conversationId" gorm:"column:conversationId;type:varchar(64);uniqueIndex"` // 对话ID
+	UserID         string    `json:"-" gorm:"column:userId;type:varchar(64);index"`                            // 所属用户ID, 对应 user.userId
+	Title          string    `json:"title" gorm:"column:title;type:varchar(100)"`                              // 标题
+	CreatedAt      time.Time `json:"createdAt" gorm:"column:createdAt"`                                        // 创建时间
+	UpdatedAt      time.Time `json:"updatedAt" gorm:"column:updatedAt;index"`                                  // 最后一次对话时间
+}
+
+// TableName 指定表名
+func (LLMConversation) TableName() string {
+	return "llmconversation"
+}
+
+// LLMConversationMessage 对话中的一条消息
+type LLMConversationMessage struct {
+	ID             uint64    `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	ConversationID string    `json:"-" gorm:"column:conversationId;type:varchar(64);index"` // 所属对话ID
+	Role           string    `json:"role" gorm:"column:role;type:varchar(16)"`              // user / assistant
+	Content        string    `json:"content" gorm:"column:content;type:mediumtext"`         // 消息内容
+	CreatedAt      time.Time `json:"createdAt" gorm:"column:createdAt"`                     // 创建时间
+}
+
+// TableName 指定表名
+func (LLMConversationMessage) TableName() string {
+	return "llmmessage"
+}
+
+// ConversationRequest 创建或重命名对话
+type ConversationRequest struct {
+	Title string `json:"title" binding:"max=100"`
+}
+
+// ConversationMessageRequest 在对话中发送消息
+type ConversationMessageRequest struct {
+	Content  string `json:"content" binding:"required"`
+	Provider string `json:"provider"` // 为空时使用默认服务商
+	Model    string `json:"model"`    // 为空时使用服务商的默认模型
+}
@@ -1,27 +1,175 @@
 package model
 
+import "time"
+
 type ChatRequest struct {
-	Messages []Message `json:"messages"`
-	Model    string    `json:"model"`
+	Messages    []Message `json:"messages"`
+	Model       string    `json:"model"`
+	Stream      bool      `json:"stream,omitempty"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	MaxTokens   int       `json:"max_tokens,omitempty"`
+	Tools       []LLMTool `json:"tools,omitempty"`
+	// 流式请求时要求在最后一个数据块中返回用量
+	StreamOptions *ChatStreamOptions `json:"stream_options,omitempty"`
+}
+
+type ChatStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 type Message struct {
-	Role    string `json:"role"`
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`   // assistant 消息中模型要求调用的工具
+	ToolCallID string     `json:"tool_call_id,omitempty"` // tool 消息对应的调用ID
+}
+
+// ToolCall 模型发起的一次工具调用
+type ToolCall struct {
+	ID       string           `json:"id"`
+	Type     string           `json:"type"`
+	Function ToolCallFunction `json:"function"`
+}
+
+type ToolCallFunction struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"` // JSON 字符串
+}
+
+// LLMTool 提供给模型的工具定义
+type LLMTool struct {
+	Type     string          `json:"type"`
+	Function LLMToolFunction `json:"function"`
+}
+
+type LLMToolFunction struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Parameters  map[string]any `json:"parameters"` // JSON Schema
+}
+
+// LLMChatParams 发给服务商的对话参数
+type LLMChatParams struct {
+	Model       string
+	Messages    []Message
+	Temperature *float64  // 为空时使用服务商默认值
+	MaxTokens   int       // 0 表示不限制
+	Tools       []LLMTool // 允许模型调用的工具
+	Cache       bool      // 允许使用响应缓存, 只在 temperature 为 0 且不带工具时生效
+}
+
+// SSE 事件类型, 每个事件的 data 都是一行 JSON
+const (
+	LLMEventDelta      = "delta"      // 回复文本片段
+	LLMEventReasoning  = "reasoning"  // 推理过程片段(DeepSeek reasoning_content 等)
+	LLMEventUsage      = "usage"      // 本次请求的 token 用量
+	LLMEventError      = "error"      // 生成失败, 之后不再有其他事件
+	LLMEventDone       = "done"       // 生成结束
+	LLMEventQueue      = "queue"      // 排队中, 推送当前排队位置
+	LLMEventCached     = "cached"     // 回复来自缓存, 之后重放缓存的事件
+	LLMEventModeration = "moderation" // 内容审核提示
+	LLMEventToolCall   = "tool_call"
+	LLMEventToolResult = "tool_result"
+	LLMEventCitations  = "citations"
+)
+
+// LLMStreamEvent 生成过程中产生的一个事件, Data 为 JSON
+type LLMStreamEvent struct {
+	Event string
+	Data  string
+}
+
+// LLMStreamRecord 带序号的事件, 缓存在 Redis 中用于断线续传, SSE 事件ID为 流ID:序号
+type LLMStreamRecord struct {
+	Seq   int    `json:"seq"`
+	Event string `json:"event"`
+	Data  string `json:"data"`
+}
+
+// LLMDeltaEvent 文本片段和推理片段事件
+type LLMDeltaEvent struct {
 	Content string `json:"content"`
 }
 
+// LLMErrorEvent 错误事件
+type LLMErrorEvent struct {
+	Error string `json:"error"`
+}
+
+// LLMQueueEvent 排队事件
+type LLMQueueEvent struct {
+	Position int `json:"position"` // 前面还有 position-1 个请求
+}
+
+// LLMCachedEvent 缓存命中事件
+type LLMCachedEvent struct {
+	CachedAt time.Time `json:"cachedAt"`
+}
+
+// LLMDoneEvent 结束事件
+type LLMDoneEvent struct {
+	StreamID string `json:"streamId"`
+}
+
+// LLMToolCallEvent 工具调用事件
+type LLMToolCallEvent struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// LLMToolResultEvent 工具结果事件
+type LLMToolResultEvent struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Result string `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// LLMMessageRequest 单轮对话请求, provider / model 为空时使用默认服务商和模型
+// 指定 personaId 时使用该角色的系统提示词和参数, 角色锁定的参数不能被覆盖
+type LLMMessageRequest struct {
+	Content     string   `json:"content" binding:"required"`
+	Provider    string   `json:"provider"`
+	Model       string   `json:"model"`
+	PersonaID   uint64   `json:"personaId"`
+	Temperature *float64 `json:"temperature" binding:"omitempty,min=0,max=2"`
+	MaxTokens   int      `json:"maxTokens" binding:"omitempty,min=1"`
+	EnableTools bool     `json:"enableTools"` // 允许模型调用后端工具(B站追番、DNS查询、IP信息、小红书解析)
+	// 从静态资源(书籍、页面)中检索相关内容作为参考资料, 回复中以 [n] 标注引用
+	EnableRetrieval bool `json:"enableRetrieval"`
+	// 使用响应缓存, 只在 temperature 为 0 且未启用工具时生效, 相同的服务商、模型和消息直接重放缓存的回复
+	Cache bool `json:"cache"`
+}
+
+// LLMProviderInfo 已注册的服务商
+type LLMProviderInfo struct {
+	Name    string   `json:"name"`
+	Models  []string `json:"models"`  // 第一个为默认模型
+	Default bool     `json:"default"` // 是否为默认服务商
+}
+
 // 响应结构体（根据 DeepSeek Chat API）
 type ChatResponse struct {
 	Choices []struct {
 		Message Message `json:"message"`
 	} `json:"choices"`
+	Usage *LLMUsage `json:"usage"`
 }
 
 // 流式响应结构体 - 用于处理 SSE (Server-Sent Events) 格式的数据
 type ChatStreamResponse struct {
 	Choices []struct {
 		Delta struct {
-			Content string `json:"content"`
+			Content          string `json:"content"`
+			ReasoningContent string `json:"reasoning_content"`
+			ToolCalls        []struct {
+				Index    int              `json:"index"`
+				ID       string           `json:"id"`
+				Type     string           `json:"type"`
+				Function ToolCallFunction `json:"function"`
+			} `json:"tool_calls"`
 		} `json:"delta"`
 	} `json:"choices"`
+	Usage *LLMUsage `json:"usage"`
 }
@@ -0,0 +1,58 @@
+package model
+
+import "time"
+
+// 内容审核的处理方式
+const (
+	ModerationActionBlock  = "block"  // 拒绝请求或中止回复
+	ModerationActionRedact = "redact" // 替换命中的内容
+	ModerationActionWarn   = "warn"   // 放行, 推送提示并记录
+)
+
+// 内容审核阶段
+const (
+	ModerationStageInput  = "input"  // 用户输入
+	ModerationStageOutput = "output" // 模型回复
+)
+
+// 命中来源
+const (
+	ModerationSourceRule       = "rule"       // 关键词/正则规则
+	ModerationSourceClassifier = "classifier" // LLM分类器
+)
+
+// LLMModerationIncident 内容审核命中记录, 供管理员复核
+type LLMModerationIncident struct {
+	ID         uint64     `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	UserID     string     `json:"userId" gorm:"column:userId;type:varchar(64);index"`    // 用户id
+	Username   string     `json:"username" gorm:"column:username;type:varchar(64)"`      // 用户名
+	Stage      string     `json:"stage" gorm:"column:stage;type:varchar(16)"`            // input/output
+	Category   string     `json:"category" gorm:"column:category;type:varchar(64)"`      // 分类
+	Action     string     `json:"action" gorm:"column:action;type:varchar(16)"`          // block/redact/warn
+	Source     string     `json:"source" gorm:"column:source;type:varchar(16)"`          // rule/classifier
+	Rule       string     `json:"rule" gorm:"column:rule;type:varchar(255)"`             // 命中的规则
+	Excerpt    string     `json:"excerpt" gorm:"column:excerpt;type:text"`               // 命中的内容片段
+	Provider   string     `json:"provider" gorm:"column:provider;type:varchar(32)"`      // 服务商
+	Model      string     `json:"model" gorm:"column:model;type:varchar(64)"`            // 模型
+	Reviewed   bool       `json:"reviewed" gorm:"column:reviewed;index"`                 // 是否已复核
+	ReviewNote string     `json:"reviewNote" gorm:"column:reviewNote;type:varchar(255)"` // 复核备注
+	ReviewedAt *time.Time `json:"reviewedAt" gorm:"column:reviewedAt"`                   // 复核时间
+	CreatedAt  time.Time  `json:"createdAt" gorm:"column:createdAt;index"`               // 创建时间
+}
+
+// TableName 指定表名
+func (LLMModerationIncident) TableName() string {
+	return "llmmoderationincident"
+}
+
+// ReviewModerationIncidentRequest 复核审核记录
+type ReviewModerationIncidentRequest struct {
+	Note string `json:"note" binding:"max=255"`
+}
+
+// LLMModerationEvent 审核提示事件, 输入被处理或回复被拦截时推送
+type LLMModerationEvent struct {
+	Stage    string `json:"stage"`
+	Category string `json:"category"`
+	Action   string `json:"action"`
+}
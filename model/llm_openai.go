@@ -0,0 +1,113 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// OpenAIChatRequest OpenAI Chat Completions 兼容接口的请求
+// model 为 服务商/模型(如 deepseek/deepseek-chat), 也可以只写模型名, 为空时使用默认服务商和模型
+type OpenAIChatRequest struct {
+	Model               string             `json:"model"`
+	Messages            []OpenAIMessage    `json:"messages" binding:"required,min=1,dive"`
+	Stream              bool               `json:"stream"`
+	StreamOptions       *ChatStreamOptions `json:"stream_options"`
+	Temperature         *float64           `json:"temperature" binding:"omitempty,min=0,max=2"`
+	MaxTokens           int                `json:"max_tokens" binding:"omitempty,min=1"`
+	MaxCompletionTokens int                `json:"max_completion_tokens" binding:"omitempty,min=1"`
+	N                   int                `json:"n" binding:"omitempty,max=1"` // 只支持生成一个回复
+	Tools               []LLMTool          `json:"tools"`                       // 暂不支持客户端定义的工具
+	Cache               bool               `json:"cache"`                       // 扩展字段, 使用响应缓存(temperature 为 0 时生效)
+}
+
+// OpenAIMessage OpenAI 格式的消息
+type OpenAIMessage struct {
+	Role       string               `json:"role" binding:"required"`
+	Content    OpenAIMessageContent `json:"content"`
+	ToolCalls  []ToolCall           `json:"tool_calls,omitempty"`
+	ToolCallID string               `json:"tool_call_id,omitempty"`
+}
+
+// OpenAIMessageContent 消息内容, 可以是字符串或内容片段数组(只支持 text 片段)
+type OpenAIMessageContent string
+
+func (c *OpenAIMessageContent) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*c = ""
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*c = OpenAIMessageContent(text)
+		return nil
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return errors.New("content 应为字符串或内容片段数组")
+	}
+	var builder strings.Builder
+	for _, part := range parts {
+		if part.Type != "text" {
+			return errors.New("只支持 text 类型的内容片段")
+		}
+		builder.WriteString(part.Text)
+	}
+	*c = OpenAIMessageContent(builder.String())
+	return nil
+}
+
+// OpenAIChatCompletion 非流式响应
+type OpenAIChatCompletion struct {
+	ID      string         `json:"id"`
+	Object  string         `json:"object"` // chat.completion
+	Created int64          `json:"created"`
+	Model   string         `json:"model"`
+	Choices []OpenAIChoice `json:"choices"`
+	Usage   *LLMUsage      `json:"usage,omitempty"`
+}
+
+type OpenAIChoice struct {
+	Index        int                 `json:"index"`
+	Message      OpenAIChoiceMessage `json:"message"`
+	FinishReason string              `json:"finish_reason"`
+}
+
+type OpenAIChoiceMessage struct {
+	Role             string `json:"role"`
+	Content          string `json:"content"`
+	ReasoningContent string `json:"reasoning_content,omitempty"`
+}
+
+// OpenAIChatChunk 流式响应的数据块
+type OpenAIChatChunk struct {
+	ID      string              `json:"id"`
+	Object  string              `json:"object"` // chat.completion.chunk
+	Created int64               `json:"created"`
+	Model   string              `json:"model"`
+	Choices []OpenAIChunkChoice `json:"choices"`
+	Usage   *LLMUsage           `json:"usage,omitempty"`
+}
+
+type OpenAIChunkChoice struct {
+	Index        int              `json:"index"`
+	Delta        OpenAIChunkDelta `json:"delta"`
+	FinishReason *string          `json:"finish_reason"`
+}
+
+type OpenAIChunkDelta struct {
+	Role             string `json:"role,omitempty"`
+	Content          string `json:"content,omitempty"`
+	ReasoningContent string `json:"reasoning_content,omitempty"`
+}
+
+// OpenAIModel /v1/models 中的一个模型
+type OpenAIModel struct {
+	ID      string `json:"id"` // 服务商/模型
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+}
@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+// LLMPersona 管理员维护的聊天角色, 请求时在消息前加入系统提示词并应用参数
+type LLMPersona struct {
+	ID              uint64    `json:"id" gorm:"column:id;primaryKey;autoIncrement"`
+	Name            string    `json:"name" gorm:"column:name;type:varchar(64)" binding:"required,max=64"`             // 名称
+	Description     string    `json:"description" gorm:"column:description;type:varchar(255)" binding:"max=255"`      // 简介
+	SystemPrompt    string    `json:"systemPrompt" gorm:"column:systemPrompt;type:text" binding:"required,max=20000"` // 系统提示词
+	Provider        string    `json:"provider" gorm:"column:provider;type:varchar(32)"`                               // 服务商, 为空时使用默认服务商
+	Model           string    `json:"model" gorm:"column:model;type:varchar(64)"`                                     // 模型, 为空时使用服务商的默认模型
+	Temperature     *float64  `json:"temperature" gorm:"column:temperature" binding:"omitempty,min=0,max=2"`          // 温度, 为空时使用服务商默认值
+	MaxTokens       int       `json:"maxTokens" gorm:"column:maxTokens" binding:"omitempty,min=0"`                    // 最大输出 token, 0 表示不限制
+	LockModel       bool      `json:"lockModel" gorm:"column:lockModel"`                                              // 调用方不能更换服务商和模型
+	LockTemperature bool      `json:"lockTemperature" gorm:"column:lockTemperature"`                                  // 调用方不能修改温度
+	LockMaxTokens   bool      `json:"lockMaxTokens" gorm:"column:lockMaxTokens"`                                      // 调用方不能修改最大输出 token
+	Enabled         bool      `json:"enabled" gorm:"column:enabled"`                                                  // 是否启用
+	CreatedAt       time.Time `json:"createdAt" gorm:"column:createdAt"`                                              // 创建时间
+	UpdatedAt       time.Time `json:"updatedAt" gorm:"column:updatedAt"`                                              // 更新时间
+}
+
+// TableName 指定表名
+func (LLMPersona) TableName() string {
+	return "llmpersona"
+}
+
+// LLMPersonaInfo 对外公开的角色信息, 不包含系统提示词
+type LLMPersonaInfo struct {
+	ID          uint64 `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Provider    string `json:"provider"`
+	Model       string `json:"model"`
+	LockModel   bool   `json:"lockModel"`
+}
@@ -0,0 +1,94 @@
+package model
+
+import "time"
+
+// LLMUsage 一次请求消耗的 token
+type LLMUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// LLMChunk 服务商流式返回的一个数据块, 最后一个数据块可能只携带 Usage 或 ToolCalls
+type LLMChunk struct {
+	Content   string
+	Reasoning string     // 推理过程片段, 不计入上下文
+	ToolCalls []ToolCall // 流结束时拼接完整的工具调用
+	Usage     *LLMUsage
+}
+
+// LLMCaller 调用LLM的身份, 通过API密钥调用时 APIKeyID 不为空
+// 未登录调用者 Anonymous 为 true, Username 和 UserID 为按客户端地址生成的标识
+type LLMCaller struct {
+	Username  string
+	UserID    string
+	APIKeyID  string
+	Anonymous bool
+}
+
+// LLMAPIKey 用户创建的LLM调用密钥, 只保存SHA-256哈希
+type LLMAPIKey struct {
+	ID         uint64     `json:"-" gorm:"column:id;primaryKey;autoIncrement"`
+	KeyID      string     `json:"keyId" gorm:"column:keyId;type:varchar(32);uniqueIndex"` // 密钥ID
+	UserID     string     `json:"-" gorm:"column:userId;type:varchar(64);index"`          // 所属用户ID, 对应 user.userId
+	Name       string     `json:"name" gorm:"column:name;type:varchar(64)"`               // 备注
+	Prefix     string     `json:"prefix" gorm:"column:prefix;type:varchar(16)"`           // 密钥前几位, 用于辨认
+	KeyHash    string     `json:"-" gorm:"column:keyHash;type:varchar(64);uniqueIndex"`   // 密钥SHA-256哈希
+	CreatedAt  time.Time  `json:"createdAt" gorm:"column:createdAt"`                      // 创建时间
+	LastUsedAt *time.Time `json:"lastUsedAt" gorm:"column:lastUsedAt"`                    // 最后使用时间
+}
+
+// TableName 指定表名
+func (LLMAPIKey) TableName() string {
+	return "llmapikey"
+}
+
+// CreateLLMAPIKeyRequest 创建API密钥请求
+type CreateLLMAPIKeyRequest struct {
+	Name string `json:"name" binding:"max=64"`
+}
+
+// LLMUsageRecord 一次LLM请求的用量记录
+type LLMUsageRecord struct {
+	ID               uint64    `json:"-" gorm:"column:id;primaryKey;autoIncrement"`
+	UserID           string    `json:"-" gorm:"column:userId;type:varchar(64);index:idx_llmusage_user_time"` // 用户ID
+	APIKeyID         string    `json:"apiKeyId" gorm:"column:apiKeyId;type:varchar(32)"`                     // 通过API密钥调用时的密钥ID
+	Provider         string    `json:"provider" gorm:"column:provider;type:varchar(32)"`                     // 服务商
+	Model            string    `json:"model" gorm:"column:model;type:varchar(64)"`                           // 模型
+	PromptTokens     int       `json:"promptTokens" gorm:"column:promptTokens"`                              // 输入 token
+	CompletionTokens int       `json:"completionTokens" gorm:"column:completionTokens"`                      // 输出 token
+	TotalTokens      int       `json:"totalTokens" gorm:"column:totalTokens"`                                // 合计 token
+	Estimated        bool      `json:"estimated" gorm:"column:estimated"`                                    // 服务商未返回用量时按字符数估算
+	CreatedAt        time.Time `json:"createdAt" gorm:"column:createdAt;index:idx_llmusage_user_time"`       // 请求时间
+}
+
+// TableName 指定表名
+func (LLMUsageRecord) TableName() string {
+	return "llmusage"
+}
+
+// LLMModelUsage 按模型汇总的用量
+type LLMModelUsage struct {
+	Provider         string `json:"provider"`
+	Model            string `json:"model"`
+	Requests         int64  `json:"requests"`
+	PromptTokens     int64  `json:"promptTokens"`
+	CompletionTokens int64  `json:"completionTokens"`
+	TotalTokens      int64  `json:"totalTokens"`
+}
+
+// LLMQuotaStatus 配额使用情况, Limit 为0表示不限制
+type LLMQuotaStatus struct {
+	Limit   int64     `json:"limit"`
+	Used    int64     `json:"used"`
+	ResetAt time.Time `json:"resetAt"`
+}
+
+// LLMUsageReport 用量报表
+type LLMUsageReport struct {
+	From    time.Time       `json:"from"`
+	To      time.Time       `json:"to"`
+	Models  []LLMModelUsage `json:"models"`
+	Daily   LLMQuotaStatus  `json:"daily"`
+	Monthly LLMQuotaStatus  `json:"monthly"`
+}
@@ -0,0 +1,18 @@
+package model
+
+// PowChallenge 工作量证明挑战
+// 客户端需要找到后缀 suffix, 使 SHA-256(nonce + suffix) 的前 difficulty 个比特为0,
+// 然后以 challengeId 作为 humanCheckKey、suffix 作为 humanCheckCode 提交
+type PowChallenge struct {
+	ChallengeID string `json:"challengeId"` // 挑战ID, 作为 humanCheckKey 使用
+	Nonce       string `json:"nonce"`       // 服务端随机数(hex)
+	Difficulty  int    `json:"difficulty"`  // 要求的前导零比特数
+	Algorithm   string `json:"algorithm"`   // 哈希算法
+	ExpiresIn   int    `json:"expiresIn"`   // 有效期(秒)
+}
+
+// PowVerifyRequest 校验工作量证明请求
+type PowVerifyRequest struct {
+	ChallengeID string `json:"challengeId" binding:"required"`
+	Suffix      string `json:"suffix" binding:"required,max=128"`
+}
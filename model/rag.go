@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// RAGChunk 索引中的一个文本片段
+type RAGChunk struct {
+	Source string    // 来源文件(相对静态资源目录), EPUB 内的章节以 #章节文件 表示
+	Title  string    // 文档标题
+	Text   string    // 片段文本
+	Vector []float32 // 归一化后的向量
+}
+
+// RAGCitation 检索结果引用, Index 与回复中的 [n] 对应
+type RAGCitation struct {
+	Index   int     `json:"index"`
+	Source  string  `json:"source"`
+	Title   string  `json:"title"`
+	URL     string  `json:"url"`
+	Score   float64 `json:"score"`
+	Snippet string  `json:"snippet"`
+}
+
+// RAGIndexStatus 索引状态
+type RAGIndexStatus struct {
+	Embedder  string    `json:"embedder"`  // local 或 服务商/模型
+	Documents int       `json:"documents"` // 文档数
+	Chunks    int       `json:"chunks"`    // 片段数
+	BuiltAt   time.Time `json:"builtAt"`   // 构建时间
+	Building  bool      `json:"building"`  // 是否正在重建
+}
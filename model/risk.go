@@ -0,0 +1,36 @@
+package model
+
+// 风险评估的业务场景
+const (
+	RiskActionRegister      = "register"
+	RiskActionResetPassword = "reset_password"
+	RiskActionLogin         = "login"
+)
+
+// 风险等级
+const (
+	RiskLevelLow    = "low"
+	RiskLevelMedium = "medium"
+	RiskLevelHigh   = "high"
+)
+
+// ClientMeta 发起请求的客户端信息
+type ClientMeta struct {
+	IP        string
+	UserAgent string
+}
+
+// RiskAssessment 风险评估结果
+type RiskAssessment struct {
+	Score             int      `json:"score"`             // 风险分(0-100)
+	Level             string   `json:"level"`             // 风险等级
+	Reasons           []string `json:"reasons"`           // 命中的风险项
+	RequireHumanCheck bool     `json:"requireHumanCheck"` // 是否需要人机验证
+	RequireEmailCode  bool     `json:"requireEmailCode"`  // 是否需要邮箱验证码
+}
+
+// RiskCheckRequest 风险预检请求
+type RiskCheckRequest struct {
+	Action   string `json:"action" binding:"required,oneof=register reset_password login"` // 业务场景
+	Identity string `json:"identity" binding:"max=255"`                                    // 用户名或邮箱(可选)
+}
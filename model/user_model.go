@@ -29,8 +29,10 @@ type Register struct {
 }
 
 type Login struct {
-	Username string `json:"username" binding:"required"` // 用户名
-	A        string `json:"A" binding:"required"`        // 客户端公钥
+	Username       string `json:"username" binding:"required"` // 用户名
+	A              string `json:"A" binding:"required"`        // 客户端公钥
+	HumanCheckKey  string `json:"humanCheckKey"`               // 人机验证key(风险较高时必填)
+	HumanCheckCode string `json:"humanCheckCode"`              // 人机验证验证码(风险较高时必填)
 }
 
 type LoginStep2 struct {
@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// 公钥算法
+const (
+	PublicKeyAlgorithmRSAOAEP = "RSA-OAEP"
+	PublicKeyAlgorithmX25519  = "X25519"
+)
+
+// UserPublicKey 用户公开的加密公钥, 其他人可以用它加密发给该用户的消息
+type UserPublicKey struct {
+	ID          uint64    `json:"-" gorm:"column:id;primaryKey;autoIncrement"`
+	KeyID       string    `json:"keyId" gorm:"column:keyId;type:varchar(64);uniqueIndex"` // 公钥ID
+	UserID      string    `json:"-" gorm:"column:userId;type:varchar(64);index"`          // 所属用户ID, 对应 user.userId
+	Algorithm   string    `json:"algorithm" gorm:"column:algorithm;type:varchar(16)"`     // RSA-OAEP 或 X25519
+	PublicKey   string    `json:"publicKey" gorm:"column:publicKey;type:text"`            // base64编码, RSA为SPKI DER, X25519为32字节原始公钥
+	Fingerprint string    `json:"fingerprint" gorm:"column:fingerprint;type:varchar(64)"` // 公钥SHA-256指纹
+	Label       string    `json:"label" gorm:"column:label;type:varchar(64)"`             // 备注(如设备名)
+	CreatedAt   time.Time `json:"createdAt" gorm:"column:createdAt;autoCreateTime"`       // 创建时间
+}
+
+// TableName 指定表名
+func (UserPublicKey) TableName() string {
+	return "userpublickey"
+}
+
+// PublishKeyRequest 发布公钥请求
+type PublishKeyRequest struct {
+	Algorithm string `json:"algorithm" binding:"required,oneof=RSA-OAEP X25519"`
+	PublicKey string `json:"publicKey" binding:"required,max=4096"`
+	Label     string `json:"label" binding:"max=64"`
+}
+
+// InboxMessage 收件箱中的消息摘要
+type InboxMessage struct {
+	UUID           string    `json:"uuid"`
+	SenderUsername string    `json:"senderUsername"`
+	RecipientKeyID string    `json:"recipientKeyId"`
+	RemainingViews int       `json:"remainingViews"`
+	ExpiresAt      time.Time `json:"expiresAt"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
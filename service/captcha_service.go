@@ -1,23 +1,114 @@
 package service
 
 import (
+	"fmt"
+	"gin/config"
+	"gin/model"
 	"gin/utils"
+	"strconv"
+	"strings"
+
 	"github.com/mojocn/base64Captcha"
-	"fmt"
 )
 
+// 字母数字验证码字符集, 去掉了容易混淆的 0/O、1/I/l
+const captchaStringSource = "23456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghjkmnpqrstuvwxyz"
+
+// 中文验证码驱动在启动时创建一次, 避免每次请求重新加载字体; 生成时复制后按请求参数修改尺寸和长度
+var chineseCaptchaDriver = base64Captcha.NewDriverChinese(0, 0, 10, base64Captcha.OptionShowSlimeLine, 0, base64Captcha.TxtChineseCharaters, nil, nil, []string{"wqy-microhei.ttc"})
+
+/*
+根据服务端策略校验并补全验证码参数
+*/
+func ResolveCaptchaOptions(options model.CaptchaOptions) (model.CaptchaOptions, error) {
+	options.Type = strings.ToLower(strings.TrimSpace(options.Type))
+	if options.Type == "" {
+		options.Type = config.CaptchaDefaultType
+	}
+	if options.Type == "alphanumeric" {
+		options.Type = model.CaptchaTypeString
+	}
+
+	allowed := false
+	for _, captchaType := range config.CaptchaAllowedTypes {
+		if captchaType == options.Type {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return options, fmt.Errorf("不支持的验证码类型: %s", options.Type)
+	}
+	// 滑块验证码使用固定尺寸
+	if options.Type == model.CaptchaTypeSlider {
+		return model.CaptchaOptions{Type: options.Type, Width: sliderWidth, Height: sliderHeight}, nil
+	}
+
+	if options.Width == 0 {
+		options.Width = 240
+	}
+	if options.Height == 0 {
+		options.Height = 80
+	}
+	if options.Length == 0 {
+		options.Length = 6
+		if options.Type == model.CaptchaTypeChinese {
+			options.Length = 4
+		}
+	}
+	if options.Width < 80 || options.Width > config.CaptchaMaxWidth {
+		return options, fmt.Errorf("验证码宽度需在 80-%d 之间", config.CaptchaMaxWidth)
+	}
+	if options.Height < 30 || options.Height > config.CaptchaMaxHeight {
+		return options, fmt.Errorf("验证码高度需在 30-%d 之间", config.CaptchaMaxHeight)
+	}
+	if options.Length < config.CaptchaMinLength || options.Length > config.CaptchaMaxLength {
+		return options, fmt.Errorf("验证码长度需在 %d-%d 之间", config.CaptchaMinLength, config.CaptchaMaxLength)
+	}
+
+	switch options.Language {
+	case "":
+		options.Language = "zh"
+	case "en", "zh", "ja", "ru":
+	default:
+		return options, fmt.Errorf("不支持的语音验证码语言: %s", options.Language)
+	}
+	return options, nil
+}
+
+func newCaptchaDriver(options model.CaptchaOptions) (base64Captcha.Driver, error) {
+	switch options.Type {
+	case model.CaptchaTypeDigit:
+		return base64Captcha.NewDriverDigit(options.Height, options.Width, options.Length, 0.7, 80), nil // 高度,宽度,长度,最大扭曲度,背景噪点数
+	case model.CaptchaTypeString:
+		return base64Captcha.NewDriverString(options.Height, options.Width, 20, base64Captcha.OptionShowSlimeLine, options.Length, captchaStringSource, nil, nil, nil), nil
+	case model.CaptchaTypeMath:
+		return base64Captcha.NewDriverMath(options.Height, options.Width, 10, base64Captcha.OptionShowSlimeLine, nil, nil, nil), nil
+	case model.CaptchaTypeChinese:
+		driver := *chineseCaptchaDriver
+		driver.Height, driver.Width, driver.Length = options.Height, options.Width, options.Length
+		return &driver, nil
+	case model.CaptchaTypeAudio:
+		return base64Captcha.NewDriverAudio(options.Length, options.Language), nil
+	}
+	return nil, fmt.Errorf("不支持的验证码类型: %s", options.Type)
+}
+
 /*
 生成验证码
 */
-func GenerateCaptcha(uuid string) (string, string, error) {
-	driver := base64Captcha.NewDriverDigit(80, 240, 6, 0.7, 80) // 高度,宽度,长度,最大扭曲度,背景噪点数
-	captcha := base64Captcha.NewCaptcha(driver, utils.RedisStore{})
+func GenerateCaptcha(uuid string, options model.CaptchaOptions) (string, string, error) {
+	driver, err := newCaptchaDriver(options)
+	if err != nil {
+		return "", "", err
+	}
+	captcha := base64Captcha.NewCaptcha(driver, utils.RedisStore{Kind: options.Type})
 
 	id, b64s, _, err := captcha.Generate()
 
-	fmt.Printf("生成的验证码ID: %s, UUID: %s\n", id, uuid)
+	fmt.Printf("生成的验证码ID: %s, UUID: %s, 类型: %s\n", id, uuid, options.Type)
 
-	// 验证码(不是图片) 6个字符 打印出日志
+	// 验证码答案 打印出日志
 	fmt.Printf("生成的验证码是: %s\n", utils.RedisStore{}.Get(id, false))
 
 	if err != nil {
@@ -30,5 +121,36 @@ func GenerateCaptcha(uuid string) (string, string, error) {
 验证验证码
 */
 func VerifyCaptcha(uuid, code string) bool {
-	return utils.RedisStore{}.Verify(uuid, code, true)
+	entry, ok := utils.RedisStore{}.GetEntry(uuid, true)
+	if !ok {
+		return false
+	}
+	return matchCaptchaAnswer(entry, code)
+}
+
+// matchCaptchaAnswer 按验证码类型比较答案
+func matchCaptchaAnswer(entry utils.CaptchaEntry, code string) bool {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return false
+	}
+	switch entry.Kind {
+	case model.CaptchaTypeSlider:
+		if err := verifySliderAnswer(entry.Answer, code); err != nil {
+			fmt.Println("滑块验证码校验失败:", err)
+			return false
+		}
+		return true
+	case model.CaptchaTypeString, model.CaptchaTypeChinese:
+		return strings.EqualFold(code, entry.Answer)
+	case model.CaptchaTypeMath:
+		expected, err := strconv.Atoi(entry.Answer)
+		if err != nil {
+			return false
+		}
+		actual, err := strconv.Atoi(code)
+		return err == nil && actual == expected
+	default:
+		return code == entry.Answer
+	}
 }
@@ -0,0 +1,306 @@
+package service
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"gin/config"
+	"gin/model"
+	"gin/utils"
+	"io"
+	"strings"
+	"sync"
+
+	"gopkg.in/gomail.v2"
+)
+
+var (
+	dkimSigner     *utils.DKIMSigner
+	dkimSignerErr  error
+	dkimSignerOnce sync.Once
+)
+
+// dkimEnabled 域名、选择器和私钥路径都配置后才启用DKIM
+func dkimEnabled() bool {
+	return config.DKIMDomain != "" && config.DKIMSelector != "" && config.DKIMKeyPath != ""
+}
+
+// GetDKIMSigner 获取DKIM签名器, 未启用时返回 nil
+func GetDKIMSigner() (*utils.DKIMSigner, error) {
+	if !dkimEnabled() {
+		return nil, nil
+	}
+	dkimSignerOnce.Do(func() {
+		dkimSigner, dkimSignerErr = utils.LoadDKIMSigner(config.DKIMDomain, config.DKIMSelector, config.DKIMKeyPath, config.DKIMAlgorithm)
+		if dkimSignerErr == nil {
+			fmt.Printf("DKIM签名已启用 - 域名: %s, 选择器: %s, 算法: %s\n", config.DKIMDomain, config.DKIMSelector, dkimSigner.Algorithm)
+		}
+	})
+	return dkimSigner, dkimSignerErr
+}
+
+// dkimSender 在发送前对邮件进行DKIM签名
+type dkimSender struct {
+	gomail.SendCloser
+	signer *utils.DKIMSigner
+}
+
+type rawMessage []byte
+
+func (m rawMessage) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(m)
+	return int64(n), err
+}
+
+func (s dkimSender) Send(from string, to []string, msg io.WriterTo) error {
+	var buf bytes.Buffer
+	if _, err := msg.WriteTo(&buf); err != nil {
+		return err
+	}
+	signed, err := s.signer.Sign(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	return s.SendCloser.Send(from, to, rawMessage(signed))
+}
+
+// sendMail 发送邮件, 启用DKIM时自动签名
+// DKIM配置错误时记录日志并以未签名的方式发送, 避免验证码邮件完全不可用
+func sendMail(d *gomail.Dialer, m *gomail.Message) error {
+	signer, err := GetDKIMSigner()
+	if err != nil {
+		fmt.Println("警告: DKIM签名器加载失败, 邮件将不签名发送:", err)
+	}
+	if signer == nil {
+		return d.DialAndSend(m)
+	}
+
+	sc, err := d.Dial()
+	if err != nil {
+		return err
+	}
+	defer sc.Close()
+	return gomail.Send(dkimSender{SendCloser: sc, signer: signer}, m)
+}
+
+// CheckEmailDNSRecords 检查发信域名的 DKIM / SPF / DMARC 记录
+// DKIM 记录会与当前配置的私钥对应的公钥进行比对
+func CheckEmailDNSRecords() model.EmailDNSReport {
+	domain := config.DKIMDomain
+	if domain == "" {
+		domain, _ = emailDomain(config.Email)
+	}
+	report := model.EmailDNSReport{
+		Domain:      domain,
+		DKIMEnabled: dkimEnabled(),
+	}
+
+	report.DKIM = checkDKIMRecord()
+	report.SPF = checkTXTRecord(domain, "v=spf1", checkSPFRecord)
+	report.DMARC = checkTXTRecord("_dmarc."+domain, "v=DMARC1", checkDMARCRecord)
+	report.Passed = report.DKIM.Passed && report.SPF.Passed && report.DMARC.Passed
+	return report
+}
+
+func checkDKIMRecord() model.DNSRecordCheck {
+	result := model.DNSRecordCheck{Issues: []string{}}
+	if !dkimEnabled() {
+		result.Issues = append(result.Issues, "未配置 DKIM_DOMAIN / DKIM_SELECTOR / DKIM_KEY_PATH，DKIM签名未启用")
+		return result
+	}
+	result.Name = config.DKIMSelector + "._domainkey." + config.DKIMDomain
+
+	signer, err := GetDKIMSigner()
+	if err != nil {
+		result.Issues = append(result.Issues, err.Error())
+		return result
+	}
+	publicKey, err := signer.PublicKeyRecord()
+	if err != nil {
+		result.Issues = append(result.Issues, "导出公钥失败: "+err.Error())
+		return result
+	}
+	result.Expected = "v=DKIM1; k=" + signer.KeyType() + "; p=" + publicKey
+
+	records, err := lookupTXT(result.Name)
+	if err != nil {
+		result.Issues = append(result.Issues, err.Error())
+		return result
+	}
+	for _, record := range records {
+		tags := parseTagList(record)
+		if _, ok := tags["p"]; !ok {
+			continue
+		}
+		result.Found = true
+		result.Record = record
+
+		if v, ok := tags["v"]; ok && v != "DKIM1" {
+			result.Issues = append(result.Issues, "v= 标签应为 DKIM1")
+		}
+		keyType := tags["k"]
+		if keyType == "" {
+			keyType = "rsa"
+		}
+		if keyType != signer.KeyType() {
+			result.Issues = append(result.Issues, fmt.Sprintf("k= 标签为 %s，与配置的算法 %s 不匹配", keyType, signer.Algorithm))
+		}
+		if tags["p"] == "" {
+			result.Issues = append(result.Issues, "p= 为空，该密钥已被吊销")
+		} else if !dkimPublicKeyMatches(tags["p"], signer.PublicKey()) {
+			result.Issues = append(result.Issues, "DNS中发布的公钥与配置的私钥不匹配")
+		}
+		if strings.Contains(tags["t"], "y") {
+			result.Issues = append(result.Issues, "t=y 表示测试模式，收件方可能忽略签名结果")
+		}
+		break
+	}
+	if !result.Found {
+		result.Issues = append(result.Issues, "未找到DKIM记录")
+	}
+	result.Passed = result.Found && len(result.Issues) == 0
+	return result
+}
+
+// dkimPublicKeyMatches 比较DNS中的 p= 值与本地公钥
+func dkimPublicKeyMatches(record string, local crypto.PublicKey) bool {
+	der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(record), ""))
+	if err != nil {
+		return false
+	}
+	if localKey, ok := local.(ed25519.PublicKey); ok {
+		return localKey.Equal(ed25519.PublicKey(der))
+	}
+
+	type equaler interface {
+		Equal(crypto.PublicKey) bool
+	}
+	localKey, ok := local.(equaler)
+	if !ok {
+		return false
+	}
+	if remote, err := x509.ParsePKIXPublicKey(der); err == nil {
+		return localKey.Equal(remote)
+	}
+	if remote, err := x509.ParsePKCS1PublicKey(der); err == nil {
+		return localKey.Equal(remote)
+	}
+	return false
+}
+
+func checkTXTRecord(name, prefix string, validate func(string) []string) model.DNSRecordCheck {
+	result := model.DNSRecordCheck{Name: name, Issues: []string{}}
+	records, err := lookupTXT(name)
+	if err != nil {
+		result.Issues = append(result.Issues, err.Error())
+		return result
+	}
+
+	var matched []string
+	for _, record := range records {
+		if strings.HasPrefix(strings.ToLower(record), strings.ToLower(prefix)) {
+			matched = append(matched, record)
+		}
+	}
+	switch len(matched) {
+	case 0:
+		result.Issues = append(result.Issues, "未找到 "+prefix+" 记录")
+	case 1:
+		result.Found = true
+		result.Record = matched[0]
+		result.Issues = append(result.Issues, validate(matched[0])...)
+	default:
+		result.Found = true
+		result.Record = strings.Join(matched, " | ")
+		result.Issues = append(result.Issues, "存在多条 "+prefix+" 记录，收件方会视为无效")
+	}
+	result.Passed = result.Found && len(result.Issues) == 0
+	return result
+}
+
+func checkSPFRecord(record string) []string {
+	var issues []string
+	lower := strings.ToLower(record)
+	if strings.HasSuffix(lower, "+all") || strings.HasSuffix(lower, " all") {
+		issues = append(issues, "SPF 以 +all 结尾，允许任意服务器代发")
+	}
+	if !strings.Contains(lower, "all") && !strings.Contains(lower, "redirect=") {
+		issues = append(issues, "SPF 缺少 all 或 redirect 结尾机制")
+	}
+	return issues
+}
+
+func checkDMARCRecord(record string) []string {
+	var issues []string
+	tags := parseTagList(record)
+	switch tags["p"] {
+	case "quarantine", "reject":
+	case "none":
+		issues = append(issues, "DMARC 策略为 p=none，仅监控不拦截")
+	case "":
+		issues = append(issues, "DMARC 缺少 p= 策略")
+	default:
+		issues = append(issues, "DMARC p= 策略无效: "+tags["p"])
+	}
+	return issues
+}
+
+// lookupTXT 通过DNS服务查询TXT记录, 合并被拆分的多段字符串
+func lookupTXT(name string) ([]string, error) {
+	resp, err := QueryDNS(name, "TXT")
+	if err != nil {
+		return nil, fmt.Errorf("查询 %s 的TXT记录失败: %v", name, err)
+	}
+	if resp.Status != 0 && resp.Status != 3 {
+		return nil, fmt.Errorf("查询 %s 的TXT记录失败: DNS状态码 %d", name, resp.Status)
+	}
+	var records []string
+	for _, answer := range resp.Answer {
+		// 16 为TXT
+		if answer.Type == 16 {
+			records = append(records, joinTXTSegments(answer.Data))
+		}
+	}
+	return records, nil
+}
+
+// joinTXTSegments 将 "part1" "part2" 形式的TXT数据合并为一个字符串
+func joinTXTSegments(data string) string {
+	data = strings.TrimSpace(data)
+	if !strings.HasPrefix(data, `"`) {
+		return data
+	}
+	var builder strings.Builder
+	inQuote := false
+	escaped := false
+	for _, r := range data {
+		switch {
+		case escaped:
+			builder.WriteRune(r)
+			escaped = false
+		case r == '\\' && inQuote:
+			escaped = true
+		case r == '"':
+			inQuote = !inQuote
+		case inQuote:
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String()
+}
+
+// parseTagList 解析 tag=value; 形式的记录(DKIM/DMARC)
+func parseTagList(record string) map[string]string {
+	tags := make(map[string]string)
+	for _, part := range strings.Split(record, ";") {
+		key, value, found := strings.Cut(part, "=")
+		if !found {
+			continue
+		}
+		tags[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return tags
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"gin/config"
+	"gin/db"
+	"gin/utils"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 内置的一次性邮箱域名列表,可通过 EMAIL_DISPOSABLE_FILE 追加
+var builtinDisposableDomains = []string{
+	"10minutemail.com", "20minutemail.com", "33mail.com", "guerrillamail.com",
+	"guerrillamail.net", "guerrillamail.org", "sharklasers.com", "grr.la",
+	"mailinator.com", "mailinator.net", "maildrop.cc", "yopmail.com",
+	"yopmail.net", "trashmail.com", "trashmail.de", "tempmail.com",
+	"temp-mail.org", "temp-mail.io", "tempmailo.com", "tempr.email",
+	"throwawaymail.com", "dispostable.com", "getnada.com", "nada.email",
+	"fakeinbox.com", "mohmal.com", "mintemail.com", "mailnesia.com",
+	"emailondeck.com", "spamgourmet.com", "mytemp.email", "moakt.com",
+	"burnermail.io", "mailcatch.com", "inboxkitten.com", "linshiyouxiang.net",
+	"bccto.me", "chacuo.net", "027168.com", "besttempmail.com",
+}
+
+var (
+	disposableDomains     map[string]struct{}
+	disposableDomainsOnce sync.Once
+	disposableDomainsMu   sync.RWMutex
+)
+
+// LoadDisposableDomains 加载一次性邮箱域名列表(内置列表 + 配置文件)
+// 返回加载后的域名总数,可在更新列表文件后重新调用
+func LoadDisposableDomains() (int, error) {
+	domains := make(map[string]struct{}, len(builtinDisposableDomains))
+	for _, domain := range builtinDisposableDomains {
+		domains[domain] = struct{}{}
+	}
+
+	var loadErr error
+	if config.EmailDisposableFile != "" {
+		file, err := os.Open(config.EmailDisposableFile)
+		if err != nil {
+			loadErr = fmt.Errorf("读取一次性邮箱域名文件失败: %v", err)
+		} else {
+			scanner := bufio.NewScanner(file)
+			for scanner.Scan() {
+				line := strings.ToLower(strings.TrimSpace(scanner.Text()))
+				if line == "" || strings.HasPrefix(line, "#") {
+					continue
+				}
+				domains[line] = struct{}{}
+			}
+			if err := scanner.Err(); err != nil {
+				loadErr = fmt.Errorf("解析一次性邮箱域名文件失败: %v", err)
+			}
+			file.Close()
+		}
+	}
+
+	disposableDomainsMu.Lock()
+	disposableDomains = domains
+	disposableDomainsMu.Unlock()
+
+	return len(domains), loadErr
+}
+
+// isDisposableDomain 判断域名(或其上级域名)是否属于一次性邮箱
+func isDisposableDomain(domain string) bool {
+	disposableDomainsOnce.Do(func() {
+		if count, err := LoadDisposableDomains(); err != nil {
+			fmt.Println("警告:", err)
+		} else {
+			fmt.Println("一次性邮箱域名列表加载完成, 数量:", count)
+		}
+	})
+
+	disposableDomainsMu.RLock()
+	defer disposableDomainsMu.RUnlock()
+	for _, candidate := range domainCandidates(domain) {
+		if _, ok := disposableDomains[candidate]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// domainCandidates 返回域名本身及其所有上级域名, 如 a.b.com -> [a.b.com b.com]
+func domainCandidates(domain string) []string {
+	var candidates []string
+	parts := strings.Split(domain, ".")
+	for i := 0; i < len(parts)-1; i++ {
+		candidates = append(candidates, strings.Join(parts[i:], "."))
+	}
+	return candidates
+}
+
+func matchDomainList(domain string, list []string) bool {
+	for _, candidate := range domainCandidates(domain) {
+		for _, item := range list {
+			if candidate == item {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// emailDomain 提取邮箱地址的域名部分
+func emailDomain(email string) (string, error) {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 || at == len(email)-1 {
+		return "", errors.New("邮箱地址格式错误")
+	}
+	return strings.ToLower(strings.TrimSuffix(email[at+1:], ".")), nil
+}
+
+// CheckEmailPolicy 检查邮箱是否允许接收邮件
+// 依次检查白名单、黑名单、一次性邮箱列表以及MX记录
+func CheckEmailPolicy(email string) error {
+	domain, err := emailDomain(email)
+	if err != nil {
+		return err
+	}
+
+	if len(config.EmailAllowDomains) > 0 && !matchDomainList(domain, config.EmailAllowDomains) {
+		return fmt.Errorf("不支持该邮箱域名: %s", domain)
+	}
+	if matchDomainList(domain, config.EmailDenyDomains) {
+		return fmt.Errorf("该邮箱域名已被禁止: %s", domain)
+	}
+	if isDisposableDomain(domain) {
+		return errors.New("不支持使用一次性邮箱")
+	}
+
+	if config.EmailCheckMX {
+		if err := checkDomainMX(domain); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkDomainMX 通过DNS服务检查域名是否能接收邮件
+// 没有MX记录时按 RFC 5321 回退检查A记录
+func checkDomainMX(domain string) error {
+	cacheKey := "email_mx:" + domain
+	if cached, err := db.RDB.Get(db.Ctx, cacheKey).Result(); err == nil {
+		if cached == "1" {
+			return nil
+		}
+		return fmt.Errorf("邮箱域名无法接收邮件: %s", domain)
+	}
+
+	ok, err := domainAcceptsMail(domain)
+	if err != nil {
+		fmt.Println("MX记录查询失败:", err)
+		return errors.New("暂时无法校验邮箱域名,请稍后再试")
+	}
+
+	result := "0"
+	if ok {
+		result = "1"
+	}
+	db.RDB.Set(db.Ctx, cacheKey, result, time.Hour)
+
+	if !ok {
+		return fmt.Errorf("邮箱域名无法接收邮件: %s", domain)
+	}
+	return nil
+}
+
+func domainAcceptsMail(domain string) (bool, error) {
+	for _, recordType := range []string{"MX", "A"} {
+		resp, err := QueryDNS(domain, recordType)
+		if err != nil {
+			return false, err
+		}
+		// Status 3 表示域名不存在
+		if resp.Status == 3 {
+			return false, nil
+		}
+		for _, answer := range resp.Answer {
+			// 15 为MX, 1 为A; 空MX(". ")表示域名明确不接收邮件(RFC 7505)
+			if answer.Type == 15 {
+				fields := strings.Fields(answer.Data)
+				if len(fields) == 2 && fields[1] == "." {
+					return false, nil
+				}
+				return true, nil
+			}
+			if answer.Type == 1 && recordType == "A" {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// consumeDailyQuota 占用每日发送配额, 超出时返回 TooFrequentError
+func consumeDailyQuota(key string, limit int, message string) error {
+	if limit <= 0 {
+		return nil
+	}
+	count, err := db.RDB.Incr(db.Ctx, key).Result()
+	if err != nil {
+		return err
+	}
+	if count == 1 {
+		if err := db.RDB.Expire(db.Ctx, key, 25*time.Hour).Err(); err != nil {
+			return err
+		}
+	}
+	if count > int64(limit) {
+		return &TooFrequentError{Message: message}
+	}
+	return nil
+}
+
+// consumeEmailQuotas 依次占用IP和全站的每日发送配额
+func consumeEmailQuotas(clientIP string) error {
+	today := time.Now().Format("20060102")
+	if clientIP != "" {
+		if err := consumeDailyQuota("email_quota:ip:"+utils.ClientIPKey(clientIP)+":"+today, config.EmailIPDailyLimit, "今日发送次数已达上限，请明天再试"); err != nil {
+			return err
+		}
+	}
+	return consumeDailyQuota("email_quota:global:"+today, config.EmailGlobalDailyLimit, "邮件服务繁忙，请稍后再试")
+}
@@ -7,6 +7,7 @@ import (
     "gin/db"
     "math/big"
 	"gopkg.in/gomail.v2"
+    "github.com/google/uuid"
     "github.com/redis/go-redis/v9"
 	"time"
 )
@@ -30,8 +31,15 @@ func GenerateCode() string{
 	return string(code)
 }
 
-func SendEmailCode(email string) error {
+func SendEmailCode(email, clientIP string) error {
 	key := "verify:" + email
+	if err := CheckEmailPolicy(email); err != nil {
+		return err
+	}
+	if err := CheckSuppression(email, true); err != nil {
+		return err
+	}
+
     rateLimitKey := "rate_limit:" + email
     count, err := db.RDB.Incr(db.Ctx, rateLimitKey).Result()
     if err != nil {
@@ -53,6 +61,9 @@ func SendEmailCode(email string) error {
 	if ttl > 0 {
 		return fmt.Errorf("验证码仍在有效期内，请 %.0f 秒后再试", ttl.Seconds())
 	}
+	if err := consumeEmailQuotas(clientIP); err != nil {
+		return err
+	}
 
 	code := GenerateCode()
 	if err := db.RDB.Set(db.Ctx, key, code, 3*time.Minute).Err(); err != nil {
@@ -66,12 +77,20 @@ func SendEmailCode(email string) error {
 }
 
 
+// OutgoingMail 待发送的邮件
+type OutgoingMail struct {
+	To        string
+	Subject   string
+	HTMLBody  string
+	Essential bool // 必要邮件(如验证码)不受退订影响,也不附带退订链接
+}
+
 func SendEmail(to, code string) error {
-	m := gomail.NewMessage()
-	m.SetHeader("From", config.Email)
-	m.SetHeader("To", to)
-	m.SetHeader("Subject", "数通中台 `-- 通用验证码服务")
-	m.SetBody("text/html", `
+	return DeliverMail(OutgoingMail{
+		To:        to,
+		Subject:   "数通中台 `-- 通用验证码服务",
+		Essential: true,
+		HTMLBody: `
 <div style="width: 400px;height: 50px;display: flex;flex-direction: row ;align-items: center;">
   <img style="width:50px;height:50px;margin-right: 10px;" src="https://github.com/xieleihan/QingluanSearch-AndroidDev/raw/main/peacock_flat.png" alt="" />
   <span style="font-weight: bold;font-family: kaiti;">
@@ -89,8 +108,35 @@ func SendEmail(to, code string) error {
 <p>您当前正在使用南秋的邮箱验证服务，验证码告知他人将会导致数据信息被盗，请勿泄露!</p>
 <p>他人之招,谨防上当受骗.</p>
 <p style="font-size: 1.5rem;color:#999;">3分钟内有效</p>
-`)
+`,
+	})
+}
 
+// DeliverMail 统一的邮件发送入口
+// 发送前检查屏蔽名单, 非必要邮件会附带 List-Unsubscribe 退订头
+func DeliverMail(mail OutgoingMail) error {
+	if err := CheckSuppression(mail.To, mail.Essential); err != nil {
+		return err
+	}
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", config.Email)
+	m.SetHeader("To", mail.To)
+	m.SetHeader("Subject", mail.Subject)
+	m.SetHeader("Message-ID", newMessageID())
+
+	body := mail.HTMLBody
+	if !mail.Essential {
+		unsubscribeURL, err := UnsubscribeURL(mail.To)
+		if err != nil {
+			return err
+		}
+		// RFC 8058 一键退订
+		m.SetHeader("List-Unsubscribe", "<"+unsubscribeURL+">")
+		m.SetHeader("List-Unsubscribe-Post", "List-Unsubscribe=One-Click")
+		body += `<p style="font-size: 12px;color:#999;">不想再收到此类邮件？<a href="` + unsubscribeURL + `">点击退订</a></p>`
+	}
+	m.SetBody("text/html", body)
 
 	d := gomail.NewDialer(config.EmailHost, config.EmailPort, config.Email, config.EmailPassword)
 
@@ -98,7 +144,19 @@ func SendEmail(to, code string) error {
 		d.SSL = true
 	}
 
-	return d.DialAndSend(m)
+	return sendMail(d, m)
+}
+
+// newMessageID 生成邮件的 Message-ID, 优先使用DKIM签名域名
+func newMessageID() string {
+	domain := config.DKIMDomain
+	if domain == "" {
+		domain, _ = emailDomain(config.Email)
+	}
+	if domain == "" {
+		domain = "localhost"
+	}
+	return "<" + uuid.NewString() + "@" + domain + ">"
 }
 
 /*
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"gin/config"
+	"gin/db"
+	"gin/model"
+	"net/url"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SuppressedError 收件人在屏蔽名单中
+type SuppressedError struct {
+	Email  string
+	Reason string
+}
+
+func (e *SuppressedError) Error() string {
+	if e.Reason == model.SuppressionReasonUnsubscribe {
+		return "该邮箱已退订邮件通知"
+	}
+	return "该邮箱已被列入屏蔽名单，无法发送邮件"
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// CheckSuppression 检查邮箱是否允许发送
+// 必要邮件(如验证码)只受退信、投诉和手动屏蔽影响,不受退订影响
+func CheckSuppression(email string, essential bool) error {
+	var record model.EmailSuppression
+	err := db.DB.Where("email = ?", normalizeEmail(email)).First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("查询邮件屏蔽名单失败: %v", err)
+	}
+	if essential && record.Reason == model.SuppressionReasonUnsubscribe {
+		return nil
+	}
+	return &SuppressedError{Email: record.Email, Reason: record.Reason}
+}
+
+// SuppressEmails 批量加入屏蔽名单, 已存在的记录会更新原因和来源
+func SuppressEmails(emails []string, reason, source string) (int, error) {
+	if reason == "" {
+		reason = model.SuppressionReasonManual
+	}
+	now := time.Now()
+	seen := make(map[string]struct{}, len(emails))
+	records := make([]model.EmailSuppression, 0, len(emails))
+	for _, email := range emails {
+		email = normalizeEmail(email)
+		if _, ok := seen[email]; ok || email == "" {
+			continue
+		}
+		seen[email] = struct{}{}
+		records = append(records, model.EmailSuppression{
+			Email:     email,
+			Reason:    reason,
+			Source:    source,
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+	}
+	if len(records) == 0 {
+		return 0, nil
+	}
+
+	err := db.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "email"}},
+		DoUpdates: clause.AssignmentColumns([]string{"reason", "source", "updatedAt"}),
+	}).CreateInBatches(&records, 500).Error
+	if err != nil {
+		return 0, fmt.Errorf("写入邮件屏蔽名单失败: %v", err)
+	}
+	return len(records), nil
+}
+
+// UnsuppressEmails 从屏蔽名单中批量移除
+func UnsuppressEmails(emails []string) (int64, error) {
+	normalized := make([]string, 0, len(emails))
+	for _, email := range emails {
+		normalized = append(normalized, normalizeEmail(email))
+	}
+	result := db.DB.Where("email IN ?", normalized).Delete(&model.EmailSuppression{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("移除邮件屏蔽名单失败: %v", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// ListSuppressions 分页查询屏蔽名单
+func ListSuppressions(page, pageSize int) ([]model.EmailSuppression, int64, error) {
+	var total int64
+	if err := db.DB.Model(&model.EmailSuppression{}).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询邮件屏蔽名单失败: %v", err)
+	}
+	var records []model.EmailSuppression
+	if err := db.DB.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&records).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询邮件屏蔽名单失败: %v", err)
+	}
+	return records, total, nil
+}
+
+func unsubscribeSecret() ([]byte, error) {
+	if config.EmailUnsubscribeSecret != "" {
+		return []byte(config.EmailUnsubscribeSecret), nil
+	}
+	if config.SecretKey != "" {
+		return []byte(config.SecretKey), nil
+	}
+	return nil, errors.New("未配置退订链接签名密钥")
+}
+
+func signUnsubscribe(secret []byte, email string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("unsubscribe:" + email))
+	return mac.Sum(nil)
+}
+
+// GenerateUnsubscribeToken 生成退订token: base64url(邮箱).base64url(HMAC-SHA256)
+func GenerateUnsubscribeToken(email string) (string, error) {
+	secret, err := unsubscribeSecret()
+	if err != nil {
+		return "", err
+	}
+	email = normalizeEmail(email)
+	return base64.RawURLEncoding.EncodeToString([]byte(email)) + "." +
+		base64.RawURLEncoding.EncodeToString(signUnsubscribe(secret, email)), nil
+}
+
+// ParseUnsubscribeToken 校验退订token并返回对应邮箱
+func ParseUnsubscribeToken(token string) (string, error) {
+	secret, err := unsubscribeSecret()
+	if err != nil {
+		return "", err
+	}
+	parts := strings.SplitN(token, ".", 2)
+	if len(parts) != 2 {
+		return "", errors.New("退订链接无效")
+	}
+	emailBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", errors.New("退订链接无效")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", errors.New("退订链接无效")
+	}
+	email := string(emailBytes)
+	if !hmac.Equal(signature, signUnsubscribe(secret, email)) {
+		return "", errors.New("退订链接无效")
+	}
+	return email, nil
+}
+
+// UnsubscribeURL 生成邮件中使用的退订链接
+func UnsubscribeURL(email string) (string, error) {
+	if config.PublicBaseURL == "" {
+		return "", errors.New("未配置 PUBLIC_BASE_URL，无法生成退订链接")
+	}
+	token, err := GenerateUnsubscribeToken(email)
+	if err != nil {
+		return "", err
+	}
+	return config.PublicBaseURL + "/api/unsubscribe?token=" + url.QueryEscape(token), nil
+}
+
+// Unsubscribe 通过退订token将邮箱加入屏蔽名单
+func Unsubscribe(token string) (string, error) {
+	email, err := ParseUnsubscribeToken(token)
+	if err != nil {
+		return "", err
+	}
+
+	// 已因退信/投诉等原因屏蔽的邮箱保持原有原因
+	if err := CheckSuppression(email, false); err != nil {
+		var suppressed *SuppressedError
+		if errors.As(err, &suppressed) {
+			return email, nil
+		}
+		return "", err
+	}
+
+	if _, err := SuppressEmails([]string{email}, model.SuppressionReasonUnsubscribe, "退订链接"); err != nil {
+		return "", err
+	}
+	return email, nil
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"encoding/base64"
+	"gin/config"
+	"strings"
+	"testing"
+)
+
+func TestUnsubscribeTokenRoundTrip(t *testing.T) {
+	defer func(secret string) { config.EmailUnsubscribeSecret = secret }(config.EmailUnsubscribeSecret)
+	config.EmailUnsubscribeSecret = "test-secret"
+
+	tests := []struct {
+		email string
+		want  string
+	}{
+		{"user@example.com", "user@example.com"},
+		{"  User@Example.COM ", "user@example.com"},
+		{"名字+tag@例子.中国", "名字+tag@例子.中国"},
+	}
+	for _, tt := range tests {
+		token, err := GenerateUnsubscribeToken(tt.email)
+		if err != nil {
+			t.Fatalf("GenerateUnsubscribeToken(%q) error = %v", tt.email, err)
+		}
+		got, err := ParseUnsubscribeToken(token)
+		if err != nil {
+			t.Fatalf("ParseUnsubscribeToken(%q) error = %v", token, err)
+		}
+		if got != tt.want {
+			t.Errorf("ParseUnsubscribeToken() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestParseUnsubscribeTokenRejectsTampering(t *testing.T) {
+	defer func(secret string) { config.EmailUnsubscribeSecret = secret }(config.EmailUnsubscribeSecret)
+	config.EmailUnsubscribeSecret = "test-secret"
+
+	token, err := GenerateUnsubscribeToken("victim@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	emailPart, signaturePart, _ := strings.Cut(token, ".")
+	otherEmail := base64.RawURLEncoding.EncodeToString([]byte("other@example.com"))
+	signature, _ := base64.RawURLEncoding.DecodeString(signaturePart)
+	signature[0] ^= 0x01
+	flipped := base64.RawURLEncoding.EncodeToString(signature)
+
+	config.EmailUnsubscribeSecret = "other-secret"
+	otherSecretToken, err := GenerateUnsubscribeToken("victim@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.EmailUnsubscribeSecret = "test-secret"
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"空token", ""},
+		{"缺少签名", emailPart},
+		{"空签名", emailPart + "."},
+		{"替换邮箱", otherEmail + "." + signaturePart},
+		{"修改签名", emailPart + "." + flipped},
+		{"截断签名", emailPart + "." + signaturePart[:len(signaturePart)-2]},
+		{"邮箱不是base64", "!!!." + signaturePart},
+		{"签名不是base64", emailPart + ".!!!"},
+		{"其它密钥签发", otherSecretToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if email, err := ParseUnsubscribeToken(tt.token); err == nil {
+				t.Errorf("ParseUnsubscribeToken(%q) = %q, 应返回错误", tt.token, email)
+			}
+		})
+	}
+}
@@ -0,0 +1,440 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gin/config"
+	"gin/db"
+	"gin/model"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidAttachment     = errors.New("附件参数错误")
+	ErrAttachmentNotFound    = errors.New("附件不存在")
+	ErrAttachmentForbidden   = errors.New("上传凭证无效")
+	ErrAttachmentIncomplete  = errors.New("附件分片未全部上传")
+	ErrAttachmentUnavailable = errors.New("附件下载链接无效或已过期")
+	ErrAttachmentQuota       = errors.New("附件存储空间不足, 请稍后再试")
+)
+
+// attachmentQuotaMu 串行化存储配额检查与附件创建, 避免并发请求同时通过检查
+var attachmentQuotaMu sync.Mutex
+
+/*
+初始化附件存储目录
+附件只能通过下载接口获取, 存储目录不能位于公开的静态资源目录下
+*/
+func InitAttachmentStorage() error {
+	dir, err := filepath.Abs(config.AttachmentDir)
+	if err != nil {
+		return fmt.Errorf("解析附件目录失败: %v", err)
+	}
+	publicDir, err := filepath.Abs("./public")
+	if err != nil {
+		return fmt.Errorf("解析公开目录失败: %v", err)
+	}
+	if dir == publicDir || strings.HasPrefix(dir, publicDir+string(filepath.Separator)) {
+		return fmt.Errorf("附件目录 %s 不能位于公开目录下", config.AttachmentDir)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("创建附件目录失败: %v", err)
+	}
+	return nil
+}
+
+func attachmentFilePath(attachmentID string) string {
+	return filepath.Join(config.AttachmentDir, attachmentID+".bin")
+}
+
+func attachmentChunkDir(attachmentID string) string {
+	return filepath.Join(config.AttachmentDir, attachmentID+".parts")
+}
+
+func attachmentChunkPath(attachmentID string, index int) string {
+	return filepath.Join(attachmentChunkDir(attachmentID), fmt.Sprintf("%06d", index))
+}
+
+// randomToken 生成随机凭证
+func randomToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成随机凭证失败: %v", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// hashUploadToken 上传凭证只保存SHA-256哈希
+func hashUploadToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// messageAcceptsAttachments 只有尚未被查看且未过期的消息可以继续上传附件
+func messageAcceptsAttachments(msg model.EncryptionMessage, uploadToken string) error {
+	if msg.UploadTokenHash == "" || subtle.ConstantTimeCompare([]byte(hashUploadToken(uploadToken)), []byte(msg.UploadTokenHash)) != 1 {
+		return ErrAttachmentForbidden
+	}
+	if msg.Views > 0 || msg.CipherText == "" || (msg.ExpiresAt != nil && time.Now().After(*msg.ExpiresAt)) {
+		return ErrEncryptionMessageGone
+	}
+	return nil
+}
+
+func findEncryptionMessage(messageUUID string) (model.EncryptionMessage, error) {
+	return encryptionStore.Get(messageUUID)
+}
+
+/*
+创建附件上传任务
+*/
+func InitAttachment(req model.AttachmentInitRequest) (model.EncryptionAttachment, error) {
+	msg, err := findEncryptionMessage(req.MessageUUID)
+	if err != nil {
+		return model.EncryptionAttachment{}, err
+	}
+	if err := messageAcceptsAttachments(msg, req.UploadToken); err != nil {
+		return model.EncryptionAttachment{}, err
+	}
+	if req.Size > config.AttachmentMaxSize {
+		return model.EncryptionAttachment{}, fmt.Errorf("%w: 附件大小不能超过 %d 字节", ErrInvalidAttachment, config.AttachmentMaxSize)
+	}
+	if req.ChunkSize > config.AttachmentMaxChunkSize {
+		return model.EncryptionAttachment{}, fmt.Errorf("%w: 分片大小不能超过 %d 字节", ErrInvalidAttachment, config.AttachmentMaxChunkSize)
+	}
+	// 分片过小会产生大量分片文件和上传请求, 只有一个分片时不限制
+	if req.ChunkSize < req.Size && req.ChunkSize < config.AttachmentMinChunkSize {
+		return model.EncryptionAttachment{}, fmt.Errorf("%w: 分片大小不能小于 %d 字节", ErrInvalidAttachment, config.AttachmentMinChunkSize)
+	}
+
+	attachmentQuotaMu.Lock()
+	defer attachmentQuotaMu.Unlock()
+
+	var count int64
+	if err := db.DB.Model(&model.EncryptionAttachment{}).Where("messageUuid = ?", msg.UUID).Count(&count).Error; err != nil {
+		return model.EncryptionAttachment{}, fmt.Errorf("查询附件数量失败: %v", err)
+	}
+	if count >= int64(config.AttachmentMaxPerMessage) {
+		return model.EncryptionAttachment{}, fmt.Errorf("%w: 每条消息最多 %d 个附件", ErrInvalidAttachment, config.AttachmentMaxPerMessage)
+	}
+	// 按声明的大小预占存储空间, 附件被清理后释放
+	var used int64
+	if err := db.DB.Model(&model.EncryptionAttachment{}).Select("COALESCE(SUM(size), 0)").Scan(&used).Error; err != nil {
+		return model.EncryptionAttachment{}, fmt.Errorf("查询附件占用空间失败: %v", err)
+	}
+	if used+req.Size > config.AttachmentStorageQuota {
+		return model.EncryptionAttachment{}, ErrAttachmentQuota
+	}
+
+	attachment := model.EncryptionAttachment{
+		AttachmentID:  strings.ReplaceAll(uuid.NewString(), "-", ""),
+		MessageUUID:   msg.UUID,
+		EncryptedName: req.EncryptedName,
+		Iv:            req.Iv,
+		Size:          req.Size,
+		ChunkSize:     req.ChunkSize,
+		TotalChunks:   int((req.Size + req.ChunkSize - 1) / req.ChunkSize),
+		Status:        model.AttachmentStatusUploading,
+		CreatedAt:     time.Now(),
+	}
+	if msg.ExpiresAt != nil {
+		attachment.ExpiresAt = *msg.ExpiresAt
+	} else {
+		attachment.ExpiresAt = time.Now().Add(config.EncryptionDefaultTTL)
+	}
+	if err := os.MkdirAll(attachmentChunkDir(attachment.AttachmentID), 0700); err != nil {
+		return model.EncryptionAttachment{}, fmt.Errorf("创建分片目录失败: %v", err)
+	}
+	if err := db.DB.Create(&attachment).Error; err != nil {
+		os.RemoveAll(attachmentChunkDir(attachment.AttachmentID))
+		return model.EncryptionAttachment{}, fmt.Errorf("保存附件信息失败: %v", err)
+	}
+	return attachment, nil
+}
+
+// loadUploadingAttachment 校验上传凭证并返回上传中的附件
+func loadUploadingAttachment(attachmentID, uploadToken string) (model.EncryptionAttachment, error) {
+	var attachment model.EncryptionAttachment
+	err := db.DB.Where("attachmentId = ?", attachmentID).First(&attachment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return attachment, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return attachment, fmt.Errorf("查找附件失败: %v", err)
+	}
+	msg, err := findEncryptionMessage(attachment.MessageUUID)
+	if err != nil {
+		return attachment, err
+	}
+	if err := messageAcceptsAttachments(msg, uploadToken); err != nil {
+		return attachment, err
+	}
+	return attachment, nil
+}
+
+// expectedChunkSize 返回指定分片应有的大小
+func expectedChunkSize(attachment model.EncryptionAttachment, index int) int64 {
+	if index == attachment.TotalChunks-1 {
+		return attachment.Size - attachment.ChunkSize*int64(attachment.TotalChunks-1)
+	}
+	return attachment.ChunkSize
+}
+
+/*
+保存附件分片, 同一分片可以重复上传(断点续传时覆盖)
+*/
+func SaveAttachmentChunk(attachmentID, uploadToken string, index int, body io.Reader) error {
+	attachment, err := loadUploadingAttachment(attachmentID, uploadToken)
+	if err != nil {
+		return err
+	}
+	if attachment.Status != model.AttachmentStatusUploading {
+		return fmt.Errorf("%w: 附件已上传完成", ErrInvalidAttachment)
+	}
+	if index < 0 || index >= attachment.TotalChunks {
+		return fmt.Errorf("%w: 分片序号需在 0-%d 之间", ErrInvalidAttachment, attachment.TotalChunks-1)
+	}
+
+	expected := expectedChunkSize(attachment, index)
+	tmp, err := os.CreateTemp(attachmentChunkDir(attachmentID), "upload-*")
+	if err != nil {
+		return fmt.Errorf("创建分片文件失败: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	written, err := io.Copy(tmp, io.LimitReader(body, expected+1))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("写入分片失败: %v", err)
+	}
+	if written != expected {
+		return fmt.Errorf("%w: 分片 %d 大小应为 %d 字节, 实际 %d 字节", ErrInvalidAttachment, index, expected, written)
+	}
+	if err := os.Rename(tmp.Name(), attachmentChunkPath(attachmentID, index)); err != nil {
+		return fmt.Errorf("保存分片失败: %v", err)
+	}
+	return nil
+}
+
+// uploadedChunks 返回已上传的分片序号
+func uploadedChunks(attachment model.EncryptionAttachment) []int {
+	chunks := []int{}
+	entries, err := os.ReadDir(attachmentChunkDir(attachment.AttachmentID))
+	if err != nil {
+		return chunks
+	}
+	for _, entry := range entries {
+		index, err := strconv.Atoi(entry.Name())
+		if err != nil || index < 0 || index >= attachment.TotalChunks {
+			continue
+		}
+		chunks = append(chunks, index)
+	}
+	sort.Ints(chunks)
+	return chunks
+}
+
+/*
+查询附件上传进度
+*/
+func GetAttachmentUploadStatus(attachmentID, uploadToken string) (model.AttachmentUploadStatus, error) {
+	attachment, err := loadUploadingAttachment(attachmentID, uploadToken)
+	if err != nil {
+		return model.AttachmentUploadStatus{}, err
+	}
+	status := model.AttachmentUploadStatus{
+		AttachmentID:   attachment.AttachmentID,
+		Status:         attachment.Status,
+		TotalChunks:    attachment.TotalChunks,
+		UploadedChunks: []int{},
+	}
+	if attachment.Status == model.AttachmentStatusUploading {
+		status.UploadedChunks = uploadedChunks(attachment)
+	}
+	return status, nil
+}
+
+/*
+合并分片, 完成附件上传
+*/
+func CompleteAttachment(attachmentID, uploadToken string) error {
+	attachment, err := loadUploadingAttachment(attachmentID, uploadToken)
+	if err != nil {
+		return err
+	}
+	if attachment.Status == model.AttachmentStatusComplete {
+		return nil
+	}
+	if len(uploadedChunks(attachment)) != attachment.TotalChunks {
+		return ErrAttachmentIncomplete
+	}
+
+	tmp, err := os.CreateTemp(config.AttachmentDir, attachmentID+"-*.tmp")
+	if err != nil {
+		return fmt.Errorf("创建附件文件失败: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	var total int64
+	for i := 0; i < attachment.TotalChunks; i++ {
+		chunk, err := os.Open(attachmentChunkPath(attachmentID, i))
+		if err != nil {
+			tmp.Close()
+			return fmt.Errorf("读取分片失败: %v", err)
+		}
+		n, err := io.Copy(tmp, chunk)
+		chunk.Close()
+		if err != nil {
+			tmp.Close()
+			return fmt.Errorf("合并分片失败: %v", err)
+		}
+		total += n
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("合并分片失败: %v", err)
+	}
+	if total != attachment.Size {
+		return fmt.Errorf("%w: 合并后大小 %d 与声明大小 %d 不一致", ErrInvalidAttachment, total, attachment.Size)
+	}
+	if err := os.Rename(tmp.Name(), attachmentFilePath(attachmentID)); err != nil {
+		return fmt.Errorf("保存附件失败: %v", err)
+	}
+	os.RemoveAll(attachmentChunkDir(attachmentID))
+
+	if err := db.DB.Model(&model.EncryptionAttachment{}).Where("id = ?", attachment.ID).
+		Update("status", model.AttachmentStatusComplete).Error; err != nil {
+		return fmt.Errorf("更新附件状态失败: %v", err)
+	}
+	return nil
+}
+
+// listMessageAttachments 查询消息已上传完成的附件
+// burned 为 true 表示消息已被销毁, 附件在下载有效期结束后删除
+func listMessageAttachments(messageUUID string, burned bool) ([]model.EncryptionAttachment, error) {
+	var attachments []model.EncryptionAttachment
+	if err := db.DB.Where("messageUuid = ? AND status = ?", messageUUID, model.AttachmentStatusComplete).
+		Find(&attachments).Error; err != nil {
+		return nil, fmt.Errorf("查询附件失败: %v", err)
+	}
+	if burned {
+		if err := scheduleAttachmentDeletion(messageUUID, time.Now().Add(config.AttachmentDownloadTTL)); err != nil {
+			return nil, err
+		}
+	}
+	return attachments, nil
+}
+
+// scheduleAttachmentDeletion 设置消息下所有附件的删除时间
+func scheduleAttachmentDeletion(messageUUID string, deleteAfter time.Time) error {
+	if err := db.DB.Model(&model.EncryptionAttachment{}).Where("messageUuid = ?", messageUUID).
+		Update("deleteAfter", deleteAfter).Error; err != nil {
+		return fmt.Errorf("更新附件删除时间失败: %v", err)
+	}
+	return nil
+}
+
+// issueAttachmentDownloads 为附件签发下载凭证
+func issueAttachmentDownloads(attachments []model.EncryptionAttachment) ([]model.AttachmentDownload, error) {
+	downloads := make([]model.AttachmentDownload, 0, len(attachments))
+	for _, attachment := range attachments {
+		token, err := randomToken()
+		if err != nil {
+			return nil, err
+		}
+		ttl := config.AttachmentDownloadTTL
+		if remaining := time.Until(attachment.ExpiresAt); remaining < ttl {
+			ttl = remaining
+		}
+		if ttl <= 0 {
+			continue
+		}
+		if err := db.RDB.Set(db.Ctx, "attachment:download:"+token, attachment.AttachmentID, ttl).Err(); err != nil {
+			return nil, fmt.Errorf("保存附件下载凭证失败: %v", err)
+		}
+		downloads = append(downloads, model.AttachmentDownload{
+			AttachmentID:  attachment.AttachmentID,
+			EncryptedName: attachment.EncryptedName,
+			Iv:            attachment.Iv,
+			Size:          attachment.Size,
+			DownloadToken: token,
+		})
+	}
+	return downloads, nil
+}
+
+/*
+根据下载凭证打开附件, 调用方负责关闭文件
+*/
+func OpenAttachmentDownload(token string) (*os.File, model.EncryptionAttachment, error) {
+	var attachment model.EncryptionAttachment
+	attachmentID, err := db.RDB.Get(db.Ctx, "attachment:download:"+token).Result()
+	if err != nil {
+		return nil, attachment, ErrAttachmentUnavailable
+	}
+	if err := db.DB.Where("attachmentId = ?", attachmentID).First(&attachment).Error; err != nil {
+		return nil, attachment, ErrAttachmentUnavailable
+	}
+	now := time.Now()
+	if now.After(attachment.ExpiresAt) || (attachment.DeleteAfter != nil && now.After(*attachment.DeleteAfter)) {
+		return nil, attachment, ErrAttachmentUnavailable
+	}
+	file, err := os.Open(attachmentFilePath(attachment.AttachmentID))
+	if err != nil {
+		return nil, attachment, ErrAttachmentUnavailable
+	}
+	return file, attachment, nil
+}
+
+/*
+分批删除过期或已随消息销毁的附件, 返回删除的数量
+*/
+func CleanupEncryptionAttachments() (int64, error) {
+	now := time.Now()
+	batch := config.EncryptionCleanupBatch
+	if batch <= 0 {
+		batch = 500
+	}
+	var total int64
+	for {
+		var attachments []model.EncryptionAttachment
+		if err := db.DB.Where("expiresAt <= ? OR deleteAfter <= ?", now, now).
+			Order("id").Limit(batch).Find(&attachments).Error; err != nil {
+			return total, fmt.Errorf("查询过期附件失败: %v", err)
+		}
+		if len(attachments) == 0 {
+			return total, nil
+		}
+		ids := make([]uint64, 0, len(attachments))
+		for _, attachment := range attachments {
+			if err := os.Remove(attachmentFilePath(attachment.AttachmentID)); err != nil && !os.IsNotExist(err) {
+				fmt.Println("删除附件文件失败:", err)
+			}
+			os.RemoveAll(attachmentChunkDir(attachment.AttachmentID))
+			ids = append(ids, attachment.ID)
+		}
+		result := db.DB.Where("id IN ?", ids).Delete(&model.EncryptionAttachment{})
+		if result.Error != nil {
+			return total, fmt.Errorf("删除过期附件失败: %v", result.Error)
+		}
+		total += result.RowsAffected
+		if len(attachments) < batch {
+			return total, nil
+		}
+	}
+}
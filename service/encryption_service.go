@@ -1,26 +1,295 @@
 package service
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"gin/db"
+	"gin/config"
 	"gin/model"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2id 参数(OWASP 推荐的最低配置)
+const (
+	accessKeyArgonTime    = 2
+	accessKeyArgonMemory  = 19 * 1024
+	accessKeyArgonThreads = 1
+	accessKeyArgonKeyLen  = 32
 )
 
-func EncryptMessage(req model.EncryptionMessage) error {
-	if err := db.DB.Create(&req).Error; err != nil {
-		return fmt.Errorf("保存加密消息失败: %v", err)
+var (
+	ErrInvalidEncryptionOptions  = errors.New("加密消息参数错误")
+	ErrEncryptionMessageNotFound = errors.New("消息不存在")
+	ErrEncryptionMessageGone     = errors.New("消息已被查看或已过期")
+	ErrEncryptionAccessKeyNeeded = errors.New("该消息需要访问口令")
+	ErrEncryptionMessageDenied   = errors.New("该消息只有收件人可以查看")
+)
+
+// 密文字段只允许 base64 / base64url / hex 字符
+var encodedFieldPattern = regexp.MustCompile(`^[A-Za-z0-9+/=_-]+$`)
+
+// validateEncryptionFields 校验客户端提交的密文字段格式和长度
+func validateEncryptionFields(req model.EncryptionMessage) error {
+	fields := []struct {
+		name  string
+		value string
+		max   int
+	}{
+		{"encryptedAESKey", req.EncryptedAESKey, 4096},
+		{"iv", req.Iv, 64},
+		{"cipherText", req.CipherText, config.EncryptionMaxCipherText},
+	}
+	for _, field := range fields {
+		if field.value == "" {
+			return fmt.Errorf("%w: %s 不能为空", ErrInvalidEncryptionOptions, field.name)
+		}
+		if len(field.value) > field.max {
+			return fmt.Errorf("%w: %s 长度不能超过 %d", ErrInvalidEncryptionOptions, field.name, field.max)
+		}
+		if !encodedFieldPattern.MatchString(field.value) {
+			return fmt.Errorf("%w: %s 必须是 base64 或 hex 编码", ErrInvalidEncryptionOptions, field.name)
+		}
+	}
+	if len(req.RecipientKeyID) > 64 {
+		return fmt.Errorf("%w: recipientKeyId 格式错误", ErrInvalidEncryptionOptions)
 	}
 	return nil
 }
 
-func DecryptMessage(req model.DecryptionMessage) (model.DecryptionResponse, error) {
-	var encryptMsg model.EncryptionMessage
-	if err := db.DB.Where("uuid = ?", req.UUID).First(&encryptMsg).Error; err != nil {
-		return model.DecryptionResponse{}, fmt.Errorf("查找加密消息失败: %v", err)
+// AccessKeyError 访问口令错误
+type AccessKeyError struct {
+	Remaining int // 剩余可尝试次数
+}
+
+func (e *AccessKeyError) Error() string {
+	return fmt.Sprintf("访问口令错误，还可尝试 %d 次", e.Remaining)
+}
+
+func hashAccessKey(accessKey string, salt []byte) []byte {
+	return argon2.IDKey([]byte(accessKey), salt, accessKeyArgonTime, accessKeyArgonMemory, accessKeyArgonThreads, accessKeyArgonKeyLen)
+}
+
+// verifyAccessKey 校验访问口令派生密钥
+func verifyAccessKey(msg model.EncryptionMessage, accessKey string) bool {
+	salt, err := hex.DecodeString(msg.AccessSalt)
+	if err != nil {
+		return false
+	}
+	expected, err := hex.DecodeString(msg.AccessHash)
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(hashAccessKey(accessKey, salt), expected) == 1
+}
+
+/*
+存入加密消息, 消息ID由服务端生成, 同时返回用于上传附件的凭证
+指定了收件人时消息进入收件人的收件箱, 只有收件人登录后可以取出
+*/
+func EncryptMessage(req model.EncryptionMessage, senderUsername string) (model.EncryptResult, error) {
+	if err := validateEncryptionFields(req); err != nil {
+		return model.EncryptResult{}, err
+	}
+	if req.MaxViews == 0 {
+		req.MaxViews = config.EncryptionDefaultMaxViews
+	}
+	if req.MaxViews < 1 || req.MaxViews > config.EncryptionMaxViews {
+		return model.EncryptResult{}, fmt.Errorf("%w: 查看次数需在 1-%d 之间", ErrInvalidEncryptionOptions, config.EncryptionMaxViews)
+	}
+	ttl := config.EncryptionDefaultTTL
+	if req.ExpiresIn != 0 {
+		ttl = time.Duration(req.ExpiresIn) * time.Second
+	}
+	if ttl < config.EncryptionMinTTL || ttl > config.EncryptionMaxTTL {
+		return model.EncryptResult{}, fmt.Errorf("%w: 有效期需在 %d-%d 秒之间", ErrInvalidEncryptionOptions,
+			int(config.EncryptionMinTTL.Seconds()), int(config.EncryptionMaxTTL.Seconds()))
+	}
+	if req.AccessKey != "" {
+		if len(req.AccessKey) > 512 {
+			return model.EncryptResult{}, fmt.Errorf("%w: 访问口令密钥过长", ErrInvalidEncryptionOptions)
+		}
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return model.EncryptResult{}, fmt.Errorf("生成访问口令盐值失败: %v", err)
+		}
+		req.AccessSalt = hex.EncodeToString(salt)
+		req.AccessHash = hex.EncodeToString(hashAccessKey(req.AccessKey, salt))
+		req.AccessKey = ""
+	}
+	req.FailedAttempts = 0
+	if err := validateNotifyTargets(&req, senderUsername); err != nil {
+		return model.EncryptResult{}, err
+	}
+	req.SenderUsername = senderUsername
+	req.RecipientUserID = ""
+	if req.RecipientUsername != "" || req.RecipientKeyID != "" {
+		if req.RecipientUsername == "" || req.RecipientKeyID == "" {
+			return model.EncryptResult{}, fmt.Errorf("%w: 收件人和收件人公钥ID需要同时提供", ErrInvalidEncryptionOptions)
+		}
+		recipientID, err := resolveRecipient(req.RecipientUsername, req.RecipientKeyID)
+		if err != nil {
+			return model.EncryptResult{}, err
+		}
+		req.RecipientUserID = recipientID
+	}
+
+	uploadToken, err := randomToken()
+	if err != nil {
+		return model.EncryptResult{}, err
+	}
+	req.UploadTokenHash = hashUploadToken(uploadToken)
+
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	req.ID = 0
+	req.UUID = uuid.NewString()
+	req.CreatedAt = now
+	req.ExpiresAt = &expiresAt
+	req.Views = 0
+
+	if err := encryptionStore.Create(&req); err != nil {
+		return model.EncryptResult{}, err
+	}
+	return model.EncryptResult{UUID: req.UUID, UploadToken: uploadToken, ExpiresAt: expiresAt}, nil
+}
+
+// wipeEncryptionMessage 清空消息内容, 保留记录用于区分"已销毁"和"不存在"
+func wipeEncryptionMessage(msg *model.EncryptionMessage) {
+	msg.EncryptedAESKey = ""
+	msg.Iv = ""
+	msg.CipherText = ""
+}
+
+/*
+取出加密消息
+在事务中加行锁, 每次查看计数加1, 达到最大查看次数或过期后立即清空密文
+设置了访问口令的消息需要先校验口令, 错误次数过多时销毁消息
+requester 为当前登录的用户名(未登录为空), 发给指定收件人的消息只有收件人可以取出
+clientIP 仅用于给发送方的回执中显示大致位置
+*/
+func DecryptMessage(req model.DecryptionMessage, requester, clientIP string) (model.DecryptionResponse, error) {
+	requesterID := ""
+	if requester != "" {
+		user, err := findUserByUsername(requester)
+		if err != nil {
+			return model.DecryptionResponse{}, err
+		}
+		requesterID = user.UserId
+	}
+
+	var response model.DecryptionResponse
+	// 口令错误、消息已销毁时也需要保存修改, 因此通过变量带出错误
+	var resultErr error
+	var burned, destroyed bool
+	// 保存成功后再发送回执
+	var notifyMsg model.EncryptionMessage
+	notifyEvent := ""
+	err := encryptionStore.Update(req.UUID, func(encryptMsg *model.EncryptionMessage) error {
+		if encryptMsg.RecipientUserID != "" && encryptMsg.RecipientUserID != requesterID {
+			return ErrEncryptionMessageDenied
+		}
+
+		expired := encryptMsg.ExpiresAt != nil && time.Now().After(*encryptMsg.ExpiresAt)
+		if encryptMsg.Views >= encryptMsg.MaxViews || expired {
+			wipeEncryptionMessage(encryptMsg)
+			resultErr = ErrEncryptionMessageGone
+			return nil
+		}
+
+		if encryptMsg.AccessHash != "" {
+			if req.AccessKey == "" {
+				return ErrEncryptionAccessKeyNeeded
+			}
+			if !verifyAccessKey(*encryptMsg, req.AccessKey) {
+				encryptMsg.FailedAttempts++
+				if encryptMsg.FailedAttempts >= config.EncryptionMaxAttempts {
+					fmt.Println("访问口令错误次数过多, 销毁消息:", encryptMsg.UUID)
+					wipeEncryptionMessage(encryptMsg)
+					encryptMsg.Views = encryptMsg.MaxViews
+					destroyed = true
+					resultErr = ErrEncryptionMessageGone
+					notifyMsg, notifyEvent = *encryptMsg, model.MessageEventDestroyed
+				} else {
+					resultErr = &AccessKeyError{Remaining: config.EncryptionMaxAttempts - encryptMsg.FailedAttempts}
+				}
+				return nil
+			}
+		}
+
+		response = model.DecryptionResponse{
+			EncryptedAESKey: encryptMsg.EncryptedAESKey,
+			Iv:              encryptMsg.Iv,
+			CipherText:      encryptMsg.CipherText,
+		}
+		if encryptMsg.ExpiresAt != nil {
+			response.ExpiresAt = *encryptMsg.ExpiresAt
+		}
+		encryptMsg.Views++
+		response.RemainingViews = encryptMsg.MaxViews - encryptMsg.Views
+		if encryptMsg.Views >= encryptMsg.MaxViews {
+			wipeEncryptionMessage(encryptMsg)
+			burned = true
+		}
+		notifyMsg, notifyEvent = *encryptMsg, model.MessageEventRetrieved
+		return nil
+	})
+	if err != nil {
+		return model.DecryptionResponse{}, err
+	}
+	if notifyEvent != "" {
+		NotifyMessageEvent(notifyMsg, notifyEvent, clientIP, response.RemainingViews)
+	}
+
+	// 附件元数据保存在MySQL中, 随消息的销毁规则一起处理
+	if destroyed {
+		if err := scheduleAttachmentDeletion(req.UUID, time.Now()); err != nil {
+			fmt.Println(err)
+		}
+	}
+	if resultErr != nil {
+		return model.DecryptionResponse{}, resultErr
+	}
+	attachments, err := listMessageAttachments(req.UUID, burned)
+	if err != nil {
+		return model.DecryptionResponse{}, err
+	}
+	response.Attachments, err = issueAttachmentDownloads(attachments)
+	if err != nil {
+		return model.DecryptionResponse{}, err
+	}
+	return response, nil
+}
+
+/*
+分批删除已过期的加密消息, 返回删除的数量
+*/
+func CleanupExpiredEncryptionMessages() (int64, error) {
+	now := time.Now()
+	batch := config.EncryptionCleanupBatch
+	if batch <= 0 {
+		batch = 500
+	}
+	var total int64
+	for {
+		expired, err := encryptionStore.DeleteExpired(now, batch)
+		total += int64(len(expired))
+		// 从未被查看过的消息通知发送方
+		for _, msg := range expired {
+			if msg.Views == 0 {
+				NotifyMessageEvent(msg, model.MessageEventExpiredUnread, "", 0)
+			}
+		}
+		if err != nil {
+			return total, err
+		}
+		if len(expired) < batch {
+			return total, nil
+		}
 	}
-	return model.DecryptionResponse{
-		EncryptedAESKey: encryptMsg.EncryptedAESKey,
-		Iv:              encryptMsg.Iv,
-		CipherText:      encryptMsg.CipherText,
-	}, nil
 }
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"fmt"
+	"gin/config"
+	"gin/model"
+	"time"
+)
+
+// EncryptionMessageStore 加密消息的存储接口
+// 通过 ENCRYPTION_STORE 选择 mysql / redis / memory 实现
+type EncryptionMessageStore interface {
+	// Create 保存新消息, UUID 已存在时返回错误
+	Create(msg *model.EncryptionMessage) error
+	// Get 按UUID读取消息, 不存在时返回 ErrEncryptionMessageNotFound
+	Get(uuid string) (model.EncryptionMessage, error)
+	// Update 在排他条件下读取消息并交给 fn 修改, fn 返回 nil 时保存修改
+	Update(uuid string, fn func(msg *model.EncryptionMessage) error) error
+	// ListByRecipient 分页查询收件人尚未销毁的消息
+	ListByRecipient(userID string, page, pageSize int) ([]model.EncryptionMessage, int64, error)
+	// DeleteExpired 删除最多 limit 条在 now 之前过期的消息, 返回被删除的消息
+	DeleteExpired(now time.Time, limit int) ([]model.EncryptionMessage, error)
+}
+
+var encryptionStore EncryptionMessageStore = &mysqlEncryptionStore{}
+
+/*
+根据配置初始化加密消息存储
+*/
+func InitEncryptionStore() error {
+	switch config.EncryptionStore {
+	case "", "mysql":
+		if err := backfillEncryptionExpiry(); err != nil {
+			return err
+		}
+		encryptionStore = &mysqlEncryptionStore{}
+	case "redis":
+		encryptionStore = &redisEncryptionStore{}
+	case "memory":
+		encryptionStore = newMemoryEncryptionStore()
+	default:
+		return fmt.Errorf("不支持的加密消息存储类型: %s", config.EncryptionStore)
+	}
+	fmt.Println("加密消息存储:", config.EncryptionStore)
+	return nil
+}
+
+// messageAlive 消息未过期且还有剩余查看次数
+func messageAlive(msg model.EncryptionMessage, now time.Time) bool {
+	return msg.Views < msg.MaxViews && (msg.ExpiresAt == nil || now.Before(*msg.ExpiresAt))
+}
+
+// paginateMessages 对已排序的消息做内存分页
+func paginateMessages(messages []model.EncryptionMessage, page, pageSize int) []model.EncryptionMessage {
+	start := (page - 1) * pageSize
+	if start >= len(messages) {
+		return []model.EncryptionMessage{}
+	}
+	end := min(start+pageSize, len(messages))
+	return messages[start:end]
+}
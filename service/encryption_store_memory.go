@@ -0,0 +1,86 @@
+package service
+
+import (
+	"fmt"
+	"gin/model"
+	"sort"
+	"sync"
+	"time"
+)
+
+// memoryEncryptionStore 进程内存储, 重启后数据丢失, 适合开发调试或单机部署
+type memoryEncryptionStore struct {
+	mu       sync.Mutex
+	messages map[string]model.EncryptionMessage
+	nextID   uint64
+}
+
+func newMemoryEncryptionStore() *memoryEncryptionStore {
+	return &memoryEncryptionStore{messages: make(map[string]model.EncryptionMessage)}
+}
+
+func (s *memoryEncryptionStore) Create(msg *model.EncryptionMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.messages[msg.UUID]; ok {
+		return fmt.Errorf("保存加密消息失败: 消息ID %s 已存在", msg.UUID)
+	}
+	s.nextID++
+	msg.ID = s.nextID
+	s.messages[msg.UUID] = *msg
+	return nil
+}
+
+func (s *memoryEncryptionStore) Get(uuid string) (model.EncryptionMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	msg, ok := s.messages[uuid]
+	if !ok {
+		return msg, ErrEncryptionMessageNotFound
+	}
+	return msg, nil
+}
+
+func (s *memoryEncryptionStore) Update(uuid string, fn func(msg *model.EncryptionMessage) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	msg, ok := s.messages[uuid]
+	if !ok {
+		return ErrEncryptionMessageNotFound
+	}
+	if err := fn(&msg); err != nil {
+		return err
+	}
+	s.messages[uuid] = msg
+	return nil
+}
+
+func (s *memoryEncryptionStore) ListByRecipient(userID string, page, pageSize int) ([]model.EncryptionMessage, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	var messages []model.EncryptionMessage
+	for _, msg := range s.messages {
+		if msg.RecipientUserID == userID && messageAlive(msg, now) {
+			messages = append(messages, msg)
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool { return messages[i].ID > messages[j].ID })
+	return paginateMessages(messages, page, pageSize), int64(len(messages)), nil
+}
+
+func (s *memoryEncryptionStore) DeleteExpired(now time.Time, limit int) ([]model.EncryptionMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var expired []model.EncryptionMessage
+	for uuid, msg := range s.messages {
+		if len(expired) >= limit {
+			break
+		}
+		if msg.ExpiresAt != nil && !now.Before(*msg.ExpiresAt) {
+			expired = append(expired, msg)
+			delete(s.messages, uuid)
+		}
+	}
+	return expired, nil
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"gin/config"
+	"gin/db"
+	"gin/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// mysqlEncryptionStore 基于MySQL的存储, 使用行锁保证查看计数的原子性
+type mysqlEncryptionStore struct{}
+
+// backfillEncryptionExpiry 升级前的旧消息没有过期时间, 启动时补全一次, 从现在起按默认有效期计算
+func backfillEncryptionExpiry() error {
+	result := db.DB.Model(&model.EncryptionMessage{}).Where("expiresAt IS NULL").
+		Update("expiresAt", time.Now().Add(config.EncryptionDefaultTTL))
+	if result.Error != nil {
+		return fmt.Errorf("补全加密消息过期时间失败: %v", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		fmt.Println("已补全旧加密消息的过期时间:", result.RowsAffected)
+	}
+	return nil
+}
+
+func (s *mysqlEncryptionStore) Create(msg *model.EncryptionMessage) error {
+	if err := db.DB.Create(msg).Error; err != nil {
+		return fmt.Errorf("保存加密消息失败: %v", err)
+	}
+	return nil
+}
+
+func (s *mysqlEncryptionStore) Get(uuid string) (model.EncryptionMessage, error) {
+	var msg model.EncryptionMessage
+	err := db.DB.Where("uuid = ?", uuid).First(&msg).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return msg, ErrEncryptionMessageNotFound
+	}
+	if err != nil {
+		return msg, fmt.Errorf("查找加密消息失败: %v", err)
+	}
+	return msg, nil
+}
+
+func (s *mysqlEncryptionStore) Update(uuid string, fn func(msg *model.EncryptionMessage) error) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		var msg model.EncryptionMessage
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("uuid = ?", uuid).First(&msg).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrEncryptionMessageNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("查找加密消息失败: %v", err)
+		}
+		if err := fn(&msg); err != nil {
+			return err
+		}
+		if err := tx.Save(&msg).Error; err != nil {
+			return fmt.Errorf("更新加密消息失败: %v", err)
+		}
+		return nil
+	})
+}
+
+func (s *mysqlEncryptionStore) ListByRecipient(userID string, page, pageSize int) ([]model.EncryptionMessage, int64, error) {
+	query := db.DB.Model(&model.EncryptionMessage{}).
+		Where("recipientUserId = ? AND views < maxViews AND expiresAt > ?", userID, time.Now())
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询收件箱失败: %v", err)
+	}
+	var messages []model.EncryptionMessage
+	if err := query.Select("uuid", "senderUsername", "recipientKeyId", "maxViews", "views", "expiresAt", "createdAt").
+		Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&messages).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询收件箱失败: %v", err)
+	}
+	return messages, total, nil
+}
+
+func (s *mysqlEncryptionStore) DeleteExpired(now time.Time, limit int) ([]model.EncryptionMessage, error) {
+	// 按主键分批删除, 避免长时间锁表
+	var expired []model.EncryptionMessage
+	if err := db.DB.Select("id", "uuid", "views", "notifyEmail", "notifyWebhook").
+		Where("expiresAt <= ?", now).Order("id").Limit(limit).Find(&expired).Error; err != nil {
+		return nil, fmt.Errorf("查询过期加密消息失败: %v", err)
+	}
+	if len(expired) == 0 {
+		return nil, nil
+	}
+	ids := make([]uint64, 0, len(expired))
+	for _, msg := range expired {
+		ids = append(ids, msg.ID)
+	}
+	if err := db.DB.Where("id IN ?", ids).Delete(&model.EncryptionMessage{}).Error; err != nil {
+		return nil, fmt.Errorf("删除过期加密消息失败: %v", err)
+	}
+	return expired, nil
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"gin/db"
+	"gin/model"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	redisEncryptionKeyPrefix   = "encmsg:"
+	redisEncryptionExpiryIndex = "encmsg:index:expiry" // 按过期时间排序的消息ID, 供清理任务发送过期回执
+	redisEncryptionInboxPrefix = "encmsg:inbox:"       // 收件箱, 按创建时间排序
+	redisEncryptionIDKey       = "encmsg:seq"
+	// 过期后多保留一段时间, 让清理任务有机会发送过期回执, 之后由Redis自动删除
+	redisEncryptionTTLGrace = time.Hour
+	redisUpdateRetries      = 5
+)
+
+// redisEncryptionStore 基于Redis的存储, 依靠键的TTL自动过期, 用 WATCH 乐观锁保证查看计数的原子性
+type redisEncryptionStore struct{}
+
+func redisEncryptionKey(uuid string) string {
+	return redisEncryptionKeyPrefix + uuid
+}
+
+// 使用gob编码保存全部字段(模型的json标签会隐藏部分字段)
+func encodeEncryptionMessage(msg model.EncryptionMessage) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(msg); err != nil {
+		return nil, fmt.Errorf("编码加密消息失败: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeEncryptionMessage(data []byte) (model.EncryptionMessage, error) {
+	var msg model.EncryptionMessage
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&msg); err != nil {
+		return msg, fmt.Errorf("解码加密消息失败: %v", err)
+	}
+	return msg, nil
+}
+
+func redisMessageTTL(msg model.EncryptionMessage) time.Duration {
+	if msg.ExpiresAt == nil {
+		return 0
+	}
+	return time.Until(*msg.ExpiresAt) + redisEncryptionTTLGrace
+}
+
+func (s *redisEncryptionStore) Create(msg *model.EncryptionMessage) error {
+	id, err := db.RDB.Incr(db.Ctx, redisEncryptionIDKey).Result()
+	if err != nil {
+		return fmt.Errorf("生成消息序号失败: %v", err)
+	}
+	msg.ID = uint64(id)
+	data, err := encodeEncryptionMessage(*msg)
+	if err != nil {
+		return err
+	}
+	ok, err := db.RDB.SetNX(db.Ctx, redisEncryptionKey(msg.UUID), data, redisMessageTTL(*msg)).Result()
+	if err != nil {
+		return fmt.Errorf("保存加密消息失败: %v", err)
+	}
+	if !ok {
+		return fmt.Errorf("保存加密消息失败: 消息ID %s 已存在", msg.UUID)
+	}
+
+	pipe := db.RDB.TxPipeline()
+	if msg.ExpiresAt != nil {
+		pipe.ZAdd(db.Ctx, redisEncryptionExpiryIndex, redis.Z{Score: float64(msg.ExpiresAt.Unix()), Member: msg.UUID})
+	}
+	if msg.RecipientUserID != "" {
+		pipe.ZAdd(db.Ctx, redisEncryptionInboxPrefix+msg.RecipientUserID, redis.Z{Score: float64(msg.CreatedAt.UnixNano()), Member: msg.UUID})
+	}
+	if _, err := pipe.Exec(db.Ctx); err != nil {
+		return fmt.Errorf("保存加密消息索引失败: %v", err)
+	}
+	return nil
+}
+
+func (s *redisEncryptionStore) Get(uuid string) (model.EncryptionMessage, error) {
+	data, err := db.RDB.Get(db.Ctx, redisEncryptionKey(uuid)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return model.EncryptionMessage{}, ErrEncryptionMessageNotFound
+	}
+	if err != nil {
+		return model.EncryptionMessage{}, fmt.Errorf("查找加密消息失败: %v", err)
+	}
+	return decodeEncryptionMessage(data)
+}
+
+func (s *redisEncryptionStore) Update(uuid string, fn func(msg *model.EncryptionMessage) error) error {
+	key := redisEncryptionKey(uuid)
+	for i := 0; i < redisUpdateRetries; i++ {
+		err := db.RDB.Watch(db.Ctx, func(tx *redis.Tx) error {
+			data, err := tx.Get(db.Ctx, key).Bytes()
+			if errors.Is(err, redis.Nil) {
+				return ErrEncryptionMessageNotFound
+			}
+			if err != nil {
+				return fmt.Errorf("查找加密消息失败: %v", err)
+			}
+			msg, err := decodeEncryptionMessage(data)
+			if err != nil {
+				return err
+			}
+			if err := fn(&msg); err != nil {
+				return err
+			}
+			updated, err := encodeEncryptionMessage(msg)
+			if err != nil {
+				return err
+			}
+			_, err = tx.TxPipelined(db.Ctx, func(pipe redis.Pipeliner) error {
+				pipe.SetArgs(db.Ctx, key, updated, redis.SetArgs{KeepTTL: true})
+				return nil
+			})
+			return err
+		}, key)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return errors.New("更新加密消息失败: 并发冲突")
+}
+
+func (s *redisEncryptionStore) ListByRecipient(userID string, page, pageSize int) ([]model.EncryptionMessage, int64, error) {
+	inboxKey := redisEncryptionInboxPrefix + userID
+	uuids, err := db.RDB.ZRevRange(db.Ctx, inboxKey, 0, -1).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询收件箱失败: %v", err)
+	}
+	if len(uuids) == 0 {
+		return []model.EncryptionMessage{}, 0, nil
+	}
+	keys := make([]string, 0, len(uuids))
+	for _, uuid := range uuids {
+		keys = append(keys, redisEncryptionKey(uuid))
+	}
+	values, err := db.RDB.MGet(db.Ctx, keys...).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询收件箱失败: %v", err)
+	}
+
+	now := time.Now()
+	var messages []model.EncryptionMessage
+	var stale []interface{}
+	for i, value := range values {
+		raw, ok := value.(string)
+		if !ok {
+			stale = append(stale, uuids[i])
+			continue
+		}
+		msg, err := decodeEncryptionMessage([]byte(raw))
+		if err != nil || !messageAlive(msg, now) {
+			stale = append(stale, uuids[i])
+			continue
+		}
+		messages = append(messages, msg)
+	}
+	// 顺便清理收件箱中已销毁的消息
+	if len(stale) > 0 {
+		db.RDB.ZRem(db.Ctx, inboxKey, stale...)
+	}
+	return paginateMessages(messages, page, pageSize), int64(len(messages)), nil
+}
+
+func (s *redisEncryptionStore) DeleteExpired(now time.Time, limit int) ([]model.EncryptionMessage, error) {
+	uuids, err := db.RDB.ZRangeByScore(db.Ctx, redisEncryptionExpiryIndex, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(now.Unix(), 10),
+		Count: int64(limit),
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("查询过期加密消息失败: %v", err)
+	}
+
+	var expired []model.EncryptionMessage
+	for _, uuid := range uuids {
+		msg, err := s.Get(uuid)
+		if err == nil {
+			expired = append(expired, msg)
+		}
+		pipe := db.RDB.TxPipeline()
+		pipe.Del(db.Ctx, redisEncryptionKey(uuid))
+		pipe.ZRem(db.Ctx, redisEncryptionExpiryIndex, uuid)
+		if err == nil && msg.RecipientUserID != "" {
+			pipe.ZRem(db.Ctx, redisEncryptionInboxPrefix+msg.RecipientUserID, uuid)
+		}
+		if _, err := pipe.Exec(db.Ctx); err != nil {
+			return expired, fmt.Errorf("删除过期加密消息失败: %v", err)
+		}
+	}
+	return expired, nil
+}
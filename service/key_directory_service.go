@@ -0,0 +1,218 @@
+package service
+
+import (
+	"crypto/ecdh"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gin/config"
+	"gin/db"
+	"gin/model"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidPublicKey  = errors.New("公钥格式错误")
+	ErrPublicKeyNotFound = errors.New("公钥不存在")
+	ErrUserNotFound      = errors.New("用户不存在")
+)
+
+// findUserByUsername 按用户名查找用户
+func findUserByUsername(username string) (model.User, error) {
+	var user model.User
+	err := db.DB.Where("username = ?", username).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return user, ErrUserNotFound
+	}
+	if err != nil {
+		return user, fmt.Errorf("查询用户失败: %v", err)
+	}
+	return user, nil
+}
+
+func decodeKeyBase64(value string) ([]byte, error) {
+	value = strings.TrimSpace(value)
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if data, err := encoding.DecodeString(value); err == nil {
+			return data, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: 不是有效的base64", ErrInvalidPublicKey)
+}
+
+// normalizePublicKey 校验公钥并返回规范化后的字节
+// RSA-OAEP 使用 SPKI DER, X25519 使用32字节原始公钥(也接受SPKI格式)
+func normalizePublicKey(algorithm, value string) ([]byte, error) {
+	data, err := decodeKeyBase64(value)
+	if err != nil {
+		return nil, err
+	}
+	switch algorithm {
+	case model.PublicKeyAlgorithmRSAOAEP:
+		parsed, err := x509.ParsePKIXPublicKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: 无法解析RSA公钥", ErrInvalidPublicKey)
+		}
+		rsaKey, ok := parsed.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("%w: 不是RSA公钥", ErrInvalidPublicKey)
+		}
+		if rsaKey.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("%w: RSA公钥长度不能小于2048位", ErrInvalidPublicKey)
+		}
+		return data, nil
+	case model.PublicKeyAlgorithmX25519:
+		if len(data) != 32 {
+			parsed, err := x509.ParsePKIXPublicKey(data)
+			if err != nil {
+				return nil, fmt.Errorf("%w: X25519公钥应为32字节", ErrInvalidPublicKey)
+			}
+			ecdhKey, ok := parsed.(*ecdh.PublicKey)
+			if !ok || ecdhKey.Curve() != ecdh.X25519() {
+				return nil, fmt.Errorf("%w: 不是X25519公钥", ErrInvalidPublicKey)
+			}
+			data = ecdhKey.Bytes()
+		}
+		if _, err := ecdh.X25519().NewPublicKey(data); err != nil {
+			return nil, fmt.Errorf("%w: 无效的X25519公钥", ErrInvalidPublicKey)
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("%w: 不支持的算法 %s", ErrInvalidPublicKey, algorithm)
+}
+
+/*
+发布公钥, 同一公钥重复发布时返回已有记录
+*/
+func PublishUserKey(username string, req model.PublishKeyRequest) (model.UserPublicKey, error) {
+	user, err := findUserByUsername(username)
+	if err != nil {
+		return model.UserPublicKey{}, err
+	}
+	keyBytes, err := normalizePublicKey(req.Algorithm, req.PublicKey)
+	if err != nil {
+		return model.UserPublicKey{}, err
+	}
+	sum := sha256.Sum256(keyBytes)
+	fingerprint := hex.EncodeToString(sum[:])
+
+	var existing model.UserPublicKey
+	err = db.DB.Where("userId = ? AND fingerprint = ?", user.UserId, fingerprint).First(&existing).Error
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.UserPublicKey{}, fmt.Errorf("查询公钥失败: %v", err)
+	}
+
+	var count int64
+	if err := db.DB.Model(&model.UserPublicKey{}).Where("userId = ?", user.UserId).Count(&count).Error; err != nil {
+		return model.UserPublicKey{}, fmt.Errorf("查询公钥数量失败: %v", err)
+	}
+	if count >= int64(config.UserMaxPublicKeys) {
+		return model.UserPublicKey{}, fmt.Errorf("%w: 每个用户最多发布 %d 个公钥", ErrInvalidPublicKey, config.UserMaxPublicKeys)
+	}
+
+	key := model.UserPublicKey{
+		KeyID:       strings.ReplaceAll(uuid.NewString(), "-", ""),
+		UserID:      user.UserId,
+		Algorithm:   req.Algorithm,
+		PublicKey:   base64.StdEncoding.EncodeToString(keyBytes),
+		Fingerprint: fingerprint,
+		Label:       req.Label,
+		CreatedAt:   time.Now(),
+	}
+	if err := db.DB.Create(&key).Error; err != nil {
+		return model.UserPublicKey{}, fmt.Errorf("保存公钥失败: %v", err)
+	}
+	fmt.Println("用户发布公钥 - 用户名:", username, "公钥ID:", key.KeyID, "算法:", key.Algorithm)
+	return key, nil
+}
+
+/*
+查询用户公开的公钥(公钥目录)
+*/
+func ListUserKeys(username string) ([]model.UserPublicKey, error) {
+	user, err := findUserByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	keys := []model.UserPublicKey{}
+	if err := db.DB.Where("userId = ?", user.UserId).Order("id").Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("查询公钥失败: %v", err)
+	}
+	return keys, nil
+}
+
+/*
+删除自己的公钥
+*/
+func DeleteUserKey(username, keyID string) error {
+	user, err := findUserByUsername(username)
+	if err != nil {
+		return err
+	}
+	result := db.DB.Where("userId = ? AND keyId = ?", user.UserId, keyID).Delete(&model.UserPublicKey{})
+	if result.Error != nil {
+		return fmt.Errorf("删除公钥失败: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrPublicKeyNotFound
+	}
+	return nil
+}
+
+// resolveRecipient 校验收件人和公钥, 返回收件人用户ID
+func resolveRecipient(username, keyID string) (string, error) {
+	user, err := findUserByUsername(username)
+	if err != nil {
+		return "", err
+	}
+	var key model.UserPublicKey
+	err = db.DB.Where("userId = ? AND keyId = ?", user.UserId, keyID).First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", ErrPublicKeyNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("查询公钥失败: %v", err)
+	}
+	return user.UserId, nil
+}
+
+/*
+分页查询收件箱中尚未销毁的消息
+*/
+func ListInbox(username string, page, pageSize int) ([]model.InboxMessage, int64, error) {
+	user, err := findUserByUsername(username)
+	if err != nil {
+		return nil, 0, err
+	}
+	messages, total, err := encryptionStore.ListByRecipient(user.UserId, page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	inbox := make([]model.InboxMessage, 0, len(messages))
+	for _, msg := range messages {
+		item := model.InboxMessage{
+			UUID:           msg.UUID,
+			SenderUsername: msg.SenderUsername,
+			RecipientKeyID: msg.RecipientKeyID,
+			RemainingViews: msg.MaxViews - msg.Views,
+			CreatedAt:      msg.CreatedAt,
+		}
+		if msg.ExpiresAt != nil {
+			item.ExpiresAt = *msg.ExpiresAt
+		}
+		inbox = append(inbox, item)
+	}
+	return inbox, total, nil
+}
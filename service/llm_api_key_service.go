@@ -0,0 +1,150 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gin/config"
+	"gin/db"
+	"gin/model"
+	"gin/utils"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// API密钥前缀, 用于和JWT区分
+const LLMAPIKeyPrefix = "sk-"
+
+// 未登录调用者标识的前缀, 注册时不允许使用此前缀的用户名
+const AnonymousLLMCallerPrefix = "anon:"
+
+var (
+	ErrLLMAPIKeyInvalid  = errors.New("无效的API密钥")
+	ErrLLMAPIKeyNotFound = errors.New("API密钥不存在")
+	ErrLLMAPIKeyLimit    = errors.New("API密钥数量已达上限")
+)
+
+func hashLLMAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+/*
+创建API密钥, 明文密钥只在创建时返回一次
+*/
+func CreateLLMAPIKey(username, name string) (model.LLMAPIKey, string, error) {
+	user, err := findUserByUsername(username)
+	if err != nil {
+		return model.LLMAPIKey{}, "", err
+	}
+	var count int64
+	if err := db.DB.Model(&model.LLMAPIKey{}).Where("userId = ?", user.UserId).Count(&count).Error; err != nil {
+		return model.LLMAPIKey{}, "", fmt.Errorf("查询API密钥数量失败: %v", err)
+	}
+	if count >= int64(config.LLMMaxAPIKeys) {
+		return model.LLMAPIKey{}, "", fmt.Errorf("%w: 每个用户最多创建 %d 个", ErrLLMAPIKeyLimit, config.LLMMaxAPIKeys)
+	}
+
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return model.LLMAPIKey{}, "", fmt.Errorf("生成API密钥失败: %v", err)
+	}
+	secret := LLMAPIKeyPrefix + hex.EncodeToString(buf)
+	idBuf := make([]byte, 8)
+	if _, err := rand.Read(idBuf); err != nil {
+		return model.LLMAPIKey{}, "", fmt.Errorf("生成API密钥失败: %v", err)
+	}
+
+	key := model.LLMAPIKey{
+		KeyID:     hex.EncodeToString(idBuf),
+		UserID:    user.UserId,
+		Name:      strings.TrimSpace(name),
+		Prefix:    secret[:len(LLMAPIKeyPrefix)+6],
+		KeyHash:   hashLLMAPIKey(secret),
+		CreatedAt: time.Now(),
+	}
+	if err := db.DB.Create(&key).Error; err != nil {
+		return model.LLMAPIKey{}, "", fmt.Errorf("保存API密钥失败: %v", err)
+	}
+	fmt.Println("用户创建API密钥 - 用户名:", username, "密钥ID:", key.KeyID)
+	return key, secret, nil
+}
+
+/*
+查询自己的API密钥
+*/
+func ListLLMAPIKeys(username string) ([]model.LLMAPIKey, error) {
+	user, err := findUserByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	keys := []model.LLMAPIKey{}
+	if err := db.DB.Where("userId = ?", user.UserId).Order("id").Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("查询API密钥失败: %v", err)
+	}
+	return keys, nil
+}
+
+/*
+删除自己的API密钥
+*/
+func DeleteLLMAPIKey(username, keyID string) error {
+	user, err := findUserByUsername(username)
+	if err != nil {
+		return err
+	}
+	result := db.DB.Where("userId = ? AND keyId = ?", user.UserId, keyID).Delete(&model.LLMAPIKey{})
+	if result.Error != nil {
+		return fmt.Errorf("删除API密钥失败: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrLLMAPIKeyNotFound
+	}
+	return nil
+}
+
+/*
+校验API密钥, 返回调用者身份
+*/
+func AuthenticateLLMAPIKey(secret string) (model.LLMCaller, error) {
+	if !strings.HasPrefix(secret, LLMAPIKeyPrefix) {
+		return model.LLMCaller{}, ErrLLMAPIKeyInvalid
+	}
+	var key model.LLMAPIKey
+	err := db.DB.Where("keyHash = ?", hashLLMAPIKey(secret)).First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.LLMCaller{}, ErrLLMAPIKeyInvalid
+	}
+	if err != nil {
+		return model.LLMCaller{}, fmt.Errorf("查询API密钥失败: %v", err)
+	}
+	var user model.User
+	if err := db.DB.Where("userId = ?", key.UserID).First(&user).Error; err != nil {
+		return model.LLMCaller{}, ErrLLMAPIKeyInvalid
+	}
+	db.DB.Model(&key).Update("lastUsedAt", time.Now())
+	return model.LLMCaller{Username: user.Username, UserID: user.UserId, APIKeyID: key.KeyID}, nil
+}
+
+/*
+根据登录用户名获取调用者身份
+*/
+func LLMCallerFromUsername(username string) (model.LLMCaller, error) {
+	user, err := findUserByUsername(username)
+	if err != nil {
+		return model.LLMCaller{}, err
+	}
+	return model.LLMCaller{Username: user.Username, UserID: user.UserId}, nil
+}
+
+/*
+未登录调用者的身份, 按客户端地址(IPv6 按 /64 网段)区分, 用于计量匿名配额和续传归属
+*/
+func AnonymousLLMCaller(clientIP string) model.LLMCaller {
+	id := AnonymousLLMCallerPrefix + utils.ClientIPKey(clientIP)
+	return model.LLMCaller{Username: id, UserID: id, Anonymous: true}
+}
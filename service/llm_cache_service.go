@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gin/config"
+	"gin/db"
+	"gin/model"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 缓存键格式为 llm:cache:<服务商>:<模型>:<请求摘要>, 便于按服务商和模型清除
+const llmCacheKeyPrefix = "llm:cache:"
+
+// llmCacheEntry 缓存的一次回复
+type llmCacheEntry struct {
+	Events   []model.LLMStreamEvent `json:"events"` // 回复和推理片段, 按原始分块保存
+	CachedAt time.Time              `json:"cachedAt"`
+}
+
+// llmCacheable 只缓存确定性的请求: 调用方开启缓存、temperature 为 0 且不带工具
+func llmCacheable(params model.LLMChatParams) bool {
+	return params.Cache && config.LLMCacheTTL > 0 && len(params.Tools) == 0 &&
+		params.Temperature != nil && *params.Temperature == 0
+}
+
+// llmCacheKey 按服务商、模型、消息和最大输出 token 计算缓存键
+func llmCacheKey(providerName string, params model.LLMChatParams) string {
+	payload, _ := json.Marshal(struct {
+		Messages  []model.Message `json:"messages"`
+		MaxTokens int             `json:"maxTokens"`
+	}{params.Messages, params.MaxTokens})
+	sum := sha256.Sum256(payload)
+	return llmCacheKeyPrefix + providerName + ":" + params.Model + ":" + hex.EncodeToString(sum[:])
+}
+
+// getLLMCache 读取缓存, 未命中或读取失败时返回 nil
+func getLLMCache(key string) *llmCacheEntry {
+	data, err := db.RDB.Get(db.Ctx, key).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			fmt.Println("读取LLM缓存失败:", err)
+		}
+		return nil
+	}
+	var entry llmCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		fmt.Println("解析LLM缓存失败:", err)
+		return nil
+	}
+	return &entry
+}
+
+// replayLLMCache 按原始分块重放缓存的回复, 不消耗 token
+func replayLLMCache(ctx context.Context, entry *llmCacheEntry) (<-chan model.LLMStreamEvent, <-chan error) {
+	eventChan := make(chan model.LLMStreamEvent, 10)
+	errChan := make(chan error, 1)
+	go func() {
+		defer close(eventChan)
+		defer close(errChan)
+
+		events := append([]model.LLMStreamEvent{marshalLLMEvent(model.LLMEventCached, model.LLMCachedEvent{CachedAt: entry.CachedAt})}, entry.Events...)
+		events = append(events, marshalLLMEvent(model.LLMEventUsage, model.LLMUsage{}))
+		for _, event := range events {
+			select {
+			case eventChan <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return eventChan, errChan
+}
+
+// cacheLLMStream 转发生成的事件, 成功完成后把回复写入缓存
+func cacheLLMStream(ctx context.Context, key string, upstream <-chan model.LLMStreamEvent, upstreamErr <-chan error) (<-chan model.LLMStreamEvent, <-chan error) {
+	eventChan := make(chan model.LLMStreamEvent, 10)
+	errChan := make(chan error, 1)
+	go func() {
+		defer close(eventChan)
+		defer close(errChan)
+
+		entry := llmCacheEntry{}
+		size := 0
+		for event := range upstream {
+			if event.Event == model.LLMEventDelta || event.Event == model.LLMEventReasoning {
+				entry.Events = append(entry.Events, event)
+				size += len(event.Data)
+			}
+			select {
+			case eventChan <- event:
+			case <-ctx.Done():
+			}
+		}
+		if err := <-upstreamErr; err != nil {
+			errChan <- err
+			return
+		}
+		// 客户端取消、回复为空或过大时不缓存
+		if ctx.Err() != nil || len(entry.Events) == 0 || (config.LLMCacheMaxBytes > 0 && size > config.LLMCacheMaxBytes) {
+			return
+		}
+		entry.CachedAt = time.Now()
+		data, _ := json.Marshal(entry)
+		if err := db.RDB.Set(db.Ctx, key, data, config.LLMCacheTTL).Err(); err != nil {
+			fmt.Println("写入LLM缓存失败:", err)
+		}
+	}()
+	return eventChan, errChan
+}
+
+// escapeRedisPattern 转义 SCAN 匹配模式中的特殊字符
+func escapeRedisPattern(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+	return replacer.Replace(value)
+}
+
+/*
+清除LLM响应缓存
+指定服务商时只清除该服务商的缓存, 同时指定模型时只清除该模型的缓存, 返回删除的数量
+*/
+func PurgeLLMCache(providerName, modelName string) (int64, error) {
+	prefix := llmCacheKeyPrefix
+	if providerName != "" {
+		prefix += strings.ToLower(providerName) + ":"
+		if modelName != "" {
+			prefix += modelName + ":"
+		}
+	}
+	pattern := escapeRedisPattern(prefix) + "*"
+
+	var deleted int64
+	var cursor uint64
+	for {
+		keys, next, err := db.RDB.Scan(db.Ctx, cursor, pattern, 500).Result()
+		if err != nil {
+			return deleted, fmt.Errorf("查询LLM缓存失败: %v", err)
+		}
+		if modelName != "" {
+			// 模型名可能包含冒号(如 qwen3:8b), 排除以该模型名为前缀的其他模型
+			matched := keys[:0]
+			for _, key := range keys {
+				if !strings.Contains(key[len(prefix):], ":") {
+					matched = append(matched, key)
+				}
+			}
+			keys = matched
+		}
+		if len(keys) > 0 {
+			n, err := db.RDB.Del(db.Ctx, keys...).Result()
+			if err != nil {
+				return deleted, fmt.Errorf("删除LLM缓存失败: %v", err)
+			}
+			deleted += n
+		}
+		if cursor = next; cursor == 0 {
+			break
+		}
+	}
+	fmt.Println("清除LLM缓存 - 服务商:", providerName, "模型:", modelName, "数量:", deleted)
+	return deleted, nil
+}
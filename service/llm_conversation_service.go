@@ -0,0 +1,273 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gin/config"
+	"gin/db"
+	"gin/model"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrConversationNotFound = errors.New("对话不存在")
+	ErrInvalidConversation  = errors.New("对话参数错误")
+)
+
+// 未设置标题时取首条消息的前若干个字符作为标题
+const conversationTitleLength = 30
+
+// findConversation 查询属于该用户的对话
+func findConversation(userID, conversationID string) (model.LLMConversation, error) {
+	var conversation model.LLMConversation
+	err := db.DB.Where("conversationId = ? AND userId = ?", conversationID, userID).First(&conversation).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return conversation, ErrConversationNotFound
+	}
+	if err != nil {
+		return conversation, fmt.Errorf("查询对话失败: %v", err)
+	}
+	return conversation, nil
+}
+
+/*
+创建对话
+*/
+func CreateConversation(username, title string) (model.LLMConversation, error) {
+	user, err := findUserByUsername(username)
+	if err != nil {
+		return model.LLMConversation{}, err
+	}
+	now := time.Now()
+	conversation := model.LLMConversation{
+		ConversationID: strings.ReplaceAll(uuid.NewString(), "-", ""),
+		UserID:         user.UserId,
+		Title:          strings.TrimSpace(title),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if err := db.DB.Create(&conversation).Error; err != nil {
+		return model.LLMConversation{}, fmt.Errorf("创建对话失败: %v", err)
+	}
+	return conversation, nil
+}
+
+/*
+分页查询用户的对话, 最近对话过的排在前面
+*/
+func ListConversations(username string, page, pageSize int) ([]model.LLMConversation, int64, error) {
+	user, err := findUserByUsername(username)
+	if err != nil {
+		return nil, 0, err
+	}
+	query := db.DB.Model(&model.LLMConversation{}).Where("userId = ?", user.UserId)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询对话数量失败: %v", err)
+	}
+	conversations := []model.LLMConversation{}
+	if err := query.Order("updatedAt DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&conversations).Error; err != nil {
+		return nil, 0, fmt.Errorf("查询对话失败: %v", err)
+	}
+	return conversations, total, nil
+}
+
+/*
+重命名对话
+*/
+func RenameConversation(username, conversationID, title string) (model.LLMConversation, error) {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return model.LLMConversation{}, fmt.Errorf("%w: 标题不能为空", ErrInvalidConversation)
+	}
+	user, err := findUserByUsername(username)
+	if err != nil {
+		return model.LLMConversation{}, err
+	}
+	conversation, err := findConversation(user.UserId, conversationID)
+	if err != nil {
+		return model.LLMConversation{}, err
+	}
+	if err := db.DB.Model(&conversation).Update("title", title).Error; err != nil {
+		return model.LLMConversation{}, fmt.Errorf("重命名对话失败: %v", err)
+	}
+	conversation.Title = title
+	return conversation, nil
+}
+
+/*
+删除对话及其全部消息
+*/
+func DeleteConversation(username, conversationID string) error {
+	user, err := findUserByUsername(username)
+	if err != nil {
+		return err
+	}
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("conversationId = ? AND userId = ?", conversationID, user.UserId).Delete(&model.LLMConversation{})
+		if result.Error != nil {
+			return fmt.Errorf("删除对话失败: %v", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrConversationNotFound
+		}
+		if err := tx.Where("conversationId = ?", conversationID).Delete(&model.LLMConversationMessage{}).Error; err != nil {
+			return fmt.Errorf("删除对话消息失败: %v", err)
+		}
+		return nil
+	})
+}
+
+/*
+查询对话中的全部消息
+*/
+func ListConversationMessages(username, conversationID string) ([]model.LLMConversationMessage, error) {
+	user, err := findUserByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := findConversation(user.UserId, conversationID); err != nil {
+		return nil, err
+	}
+	messages := []model.LLMConversationMessage{}
+	if err := db.DB.Where("conversationId = ?", conversationID).Order("id").Find(&messages).Error; err != nil {
+		return nil, fmt.Errorf("查询对话消息失败: %v", err)
+	}
+	return messages, nil
+}
+
+// loadConversationContext 读取最近的历史消息, 按条数和字符数裁剪到上下文窗口内
+// 最后一条(本次用户消息)始终保留
+func loadConversationContext(conversationID string) ([]model.Message, error) {
+	var history []model.LLMConversationMessage
+	if err := db.DB.Where("conversationId = ?", conversationID).
+		Order("id DESC").Limit(config.LLMContextMessages).Find(&history).Error; err != nil {
+		return nil, fmt.Errorf("查询对话消息失败: %v", err)
+	}
+
+	messages := make([]model.Message, 0, len(history))
+	chars := 0
+	for i, msg := range history {
+		chars += len([]rune(msg.Content))
+		if i > 0 && chars > config.LLMContextChars {
+			break
+		}
+		messages = append(messages, model.Message{Role: msg.Role, Content: msg.Content})
+	}
+	// 查询结果是倒序的, 翻转为时间顺序
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return messages, nil
+}
+
+/*
+在对话中发送消息并流式返回回复
+用户消息先保存, 请求会带上裁剪后的历史消息; 回复在流正常结束后保存
+请求计入当前用户的 token 配额, 用户消息和回复都经过内容审核, 保存的是审核后的内容
+*/
+func StreamConversationReply(ctx context.Context, username, conversationID string, req model.ConversationMessageRequest) (<-chan model.LLMStreamEvent, <-chan error, error) {
+	content := strings.TrimSpace(req.Content)
+	if content == "" {
+		return nil, nil, fmt.Errorf("%w: 消息内容不能为空", ErrInvalidConversation)
+	}
+	provider, modelName, err := ResolveLLMProvider(req.Provider, req.Model)
+	if err != nil {
+		return nil, nil, err
+	}
+	user, err := findUserByUsername(username)
+	if err != nil {
+		return nil, nil, err
+	}
+	conversation, err := findConversation(user.UserId, conversationID)
+	if err != nil {
+		return nil, nil, err
+	}
+	caller := model.LLMCaller{Username: user.Username, UserID: user.UserId}
+	if err := CheckLLMQuota(caller); err != nil {
+		return nil, nil, err
+	}
+	content, inputEvents, err := moderateLLMInput(ctx, caller, provider.Name(), modelName, content)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	userMessage := model.LLMConversationMessage{
+		ConversationID: conversationID,
+		Role:           model.ChatRoleUser,
+		Content:        content,
+		CreatedAt:      time.Now(),
+	}
+	if err := db.DB.Create(&userMessage).Error; err != nil {
+		return nil, nil, fmt.Errorf("保存对话消息失败: %v", err)
+	}
+	updates := map[string]interface{}{"updatedAt": userMessage.CreatedAt}
+	if conversation.Title == "" {
+		title := []rune(content)
+		if len(title) > conversationTitleLength {
+			title = title[:conversationTitleLength]
+		}
+		updates["title"] = string(title)
+	}
+	if err := db.DB.Model(&conversation).Updates(updates).Error; err != nil {
+		fmt.Println("更新对话失败:", err)
+	}
+
+	messages, err := loadConversationContext(conversationID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 回复经过审核后再转发和保存
+	params := model.LLMChatParams{Model: modelName, Messages: messages}
+	upstreamData, upstreamErr := moderateLLMStream(ctx, caller, provider.Name(), modelName, func(ctx context.Context) (<-chan model.LLMStreamEvent, <-chan error) {
+		return streamChatWithTools(ctx, caller, provider, params)
+	})
+	upstreamData = prependLLMEvents(ctx, upstreamData, inputEvents...)
+	dataChan := make(chan model.LLMStreamEvent, 10)
+	errChan := make(chan error, 1)
+	go func() {
+		defer close(dataChan)
+		defer close(errChan)
+
+		var reply strings.Builder
+		for event := range upstreamData {
+			if event.Event == model.LLMEventDelta {
+				var delta model.LLMDeltaEvent
+				if json.Unmarshal([]byte(event.Data), &delta) == nil {
+					reply.WriteString(delta.Content)
+				}
+			}
+			select {
+			case dataChan <- event:
+			case <-ctx.Done():
+			}
+		}
+		if err := <-upstreamErr; err != nil {
+			errChan <- err
+			return
+		}
+		if ctx.Err() != nil || reply.Len() == 0 {
+			return
+		}
+
+		assistantMessage := model.LLMConversationMessage{
+			ConversationID: conversationID,
+			Role:           model.ChatRoleAssistant,
+			Content:        reply.String(),
+			CreatedAt:      time.Now(),
+		}
+		if err := db.DB.Create(&assistantMessage).Error; err != nil {
+			fmt.Println("保存LLM回复失败:", err)
+			return
+		}
+		db.DB.Model(&model.LLMConversation{}).Where("conversationId = ?", conversationID).Update("updatedAt", assistantMessage.CreatedAt)
+	}()
+	return dataChan, errChan, nil
+}
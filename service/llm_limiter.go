@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"gin/config"
+	"sync"
+	"time"
+)
+
+var (
+	ErrLLMQueueFull    = errors.New("LLM服务繁忙, 排队人数已满, 请稍后再试")
+	ErrLLMQueueTimeout = errors.New("LLM服务繁忙, 排队超时, 请稍后再试")
+)
+
+// llmLimiter 全局和单用户并发限制, 超出时按先来后到排队
+// 排在前面但已达到单用户并发上限的请求不会阻塞其他用户的请求
+type llmLimiter struct {
+	mu      sync.Mutex
+	running int
+	perUser map[string]int
+	queued  map[string]int
+	queue   []*llmSlot
+}
+
+// llmSlot 一个请求占用的并发名额
+type llmSlot struct {
+	limiter  *llmLimiter
+	user     string
+	granted  bool
+	released bool
+	ready    chan struct{}
+	position chan int // 排队位置变化, 只保留最新值
+
+	lastPosition int
+}
+
+var defaultLLMLimiter = &llmLimiter{perUser: map[string]int{}, queued: map[string]int{}}
+
+// acquireLLMSlot 申请并发名额, 有空闲名额时立即获得, 否则进入排队
+func acquireLLMSlot(user string) (*llmSlot, error) {
+	return defaultLLMLimiter.acquire(user)
+}
+
+func (l *llmLimiter) acquire(user string) (*llmSlot, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	slot := &llmSlot{limiter: l, user: user, ready: make(chan struct{}), position: make(chan int, 1)}
+	if l.available(user) && len(l.queue) == 0 {
+		l.grant(slot)
+		return slot, nil
+	}
+	if config.LLMMaxQueue > 0 && len(l.queue) >= config.LLMMaxQueue {
+		return nil, ErrLLMQueueFull
+	}
+	if config.LLMMaxQueuePerUser > 0 && l.queued[user] >= config.LLMMaxQueuePerUser {
+		return nil, ErrLLMQueueFull
+	}
+	l.queue = append(l.queue, slot)
+	l.queued[user]++
+	l.dispatch()
+	return slot, nil
+}
+
+// available 是否还有空闲的全局和单用户名额
+func (l *llmLimiter) available(user string) bool {
+	if config.LLMMaxConcurrent > 0 && l.running >= config.LLMMaxConcurrent {
+		return false
+	}
+	return config.LLMMaxConcurrentPerUser <= 0 || l.perUser[user] < config.LLMMaxConcurrentPerUser
+}
+
+func (l *llmLimiter) grant(slot *llmSlot) {
+	slot.granted = true
+	l.running++
+	l.perUser[slot.user]++
+	close(slot.ready)
+}
+
+// dispatch 按排队顺序分配空闲名额, 然后通知仍在排队的请求当前位置
+func (l *llmLimiter) dispatch() {
+	remaining := l.queue[:0]
+	for _, slot := range l.queue {
+		if l.available(slot.user) {
+			l.queued[slot.user]--
+			l.grant(slot)
+			continue
+		}
+		remaining = append(remaining, slot)
+	}
+	clear(l.queue[len(remaining):])
+	l.queue = remaining
+	for user, count := range l.queued {
+		if count <= 0 {
+			delete(l.queued, user)
+		}
+	}
+
+	for i, slot := range l.queue {
+		if slot.lastPosition == i+1 {
+			continue
+		}
+		slot.lastPosition = i + 1
+		select {
+		case <-slot.position:
+		default:
+		}
+		slot.position <- i + 1
+	}
+}
+
+// Ready 是否已获得名额
+func (s *llmSlot) Ready() bool {
+	select {
+	case <-s.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+// Wait 等待获得名额, 排队位置变化时调用 onPosition; 超时或 ctx 结束时退出排队
+func (s *llmSlot) Wait(ctx context.Context, onPosition func(position int)) error {
+	var timeout <-chan time.Time
+	if config.LLMQueueTimeout > 0 {
+		timer := time.NewTimer(config.LLMQueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	for {
+		select {
+		case <-s.ready:
+			return nil
+		case position := <-s.position:
+			if onPosition != nil {
+				onPosition(position)
+			}
+		case <-timeout:
+			s.Release()
+			return ErrLLMQueueTimeout
+		case <-ctx.Done():
+			s.Release()
+			return ctx.Err()
+		}
+	}
+}
+
+// Release 归还名额, 尚在排队时退出排队; 可以重复调用
+func (s *llmSlot) Release() {
+	l := s.limiter
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if s.released {
+		return
+	}
+	s.released = true
+	if s.granted {
+		l.running--
+		if l.perUser[s.user]--; l.perUser[s.user] <= 0 {
+			delete(l.perUser, s.user)
+		}
+	} else {
+		for i, slot := range l.queue {
+			if slot == s {
+				l.queue = append(l.queue[:i], l.queue[i+1:]...)
+				l.queued[s.user]--
+				break
+			}
+		}
+	}
+	l.dispatch()
+}
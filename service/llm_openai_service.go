@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gin/model"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// resolveOpenAIModel 解析 服务商/模型 格式的模型ID
+// 只写模型名时在已注册的服务商中查找, 优先使用默认服务商
+func resolveOpenAIModel(id string) (string, string) {
+	id = strings.TrimSpace(id)
+	if providerName, modelName, ok := strings.Cut(id, "/"); ok {
+		if _, err := findLLMProvider(strings.ToLower(providerName)); err == nil {
+			return providerName, modelName
+		}
+	}
+	if id == "" {
+		return "", ""
+	}
+	if provider, _, err := ResolveLLMProvider("", id); err == nil {
+		return provider.Name(), id
+	}
+	for _, provider := range llmProviders {
+		for _, name := range provider.Models() {
+			if name == id {
+				return provider.Name(), id
+			}
+		}
+	}
+	return "", id
+}
+
+// openAIChatParams 把 OpenAI 格式的请求转换为对话参数
+func openAIChatParams(req model.OpenAIChatRequest) (string, model.LLMChatParams, error) {
+	if len(req.Tools) > 0 {
+		return "", model.LLMChatParams{}, fmt.Errorf("%w: 暂不支持客户端定义的工具", ErrInvalidLLMParams)
+	}
+	providerName, modelName := resolveOpenAIModel(req.Model)
+	params := model.LLMChatParams{
+		Model:       modelName,
+		Messages:    make([]model.Message, 0, len(req.Messages)),
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+		Cache:       req.Cache,
+	}
+	if req.MaxCompletionTokens > 0 {
+		params.MaxTokens = req.MaxCompletionTokens
+	}
+	for _, msg := range req.Messages {
+		role := msg.Role
+		switch role {
+		case "developer":
+			role = model.ChatRoleSystem
+		case model.ChatRoleSystem, model.ChatRoleUser, model.ChatRoleAssistant, model.ChatRoleTool:
+		default:
+			return "", params, fmt.Errorf("%w: 不支持的消息角色 %s", ErrInvalidLLMParams, msg.Role)
+		}
+		params.Messages = append(params.Messages, model.Message{
+			Role:       role,
+			Content:    string(msg.Content),
+			ToolCalls:  msg.ToolCalls,
+			ToolCallID: msg.ToolCallID,
+		})
+	}
+	return providerName, params, nil
+}
+
+/*
+OpenAI 兼容的流式对话
+请求经过与 /api/llm-message 相同的服务商选择、配额检查和用量记录, 事件转换为 chat.completion.chunk
+includeUsage 为 true 时最后推送一个只携带用量的数据块
+*/
+func OpenAIChatStream(ctx context.Context, caller model.LLMCaller, req model.OpenAIChatRequest) (<-chan model.OpenAIChatChunk, <-chan error, error) {
+	providerName, params, err := openAIChatParams(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	provider, modelName, err := ResolveLLMProvider(providerName, params.Model)
+	if err != nil {
+		return nil, nil, err
+	}
+	params.Model = modelName
+	// 全部消息都由客户端提供, 审核提示事件无法以 OpenAI 格式推送
+	if _, err := moderateLLMMessages(ctx, caller, provider.Name(), modelName, params.Messages); err != nil {
+		return nil, nil, err
+	}
+
+	// OpenAI 格式无法推送排队位置, 超出并发限制时阻塞等待
+	slot, err := acquireLLMSlot(caller.Username)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := slot.Wait(ctx, nil); err != nil {
+		return nil, nil, err
+	}
+	events, upstreamErr, err := SendChatStream(ctx, caller, provider.Name(), params)
+	if err != nil {
+		slot.Release()
+		return nil, nil, err
+	}
+	fmt.Println("OpenAI兼容接口请求 - 用户:", caller.Username, "模型:", provider.Name()+"/"+modelName, "流式:", req.Stream)
+
+	includeUsage := req.StreamOptions != nil && req.StreamOptions.IncludeUsage
+	base := model.OpenAIChatChunk{
+		ID:      "chatcmpl-" + strings.ReplaceAll(uuid.NewString(), "-", ""),
+		Object:  "chat.completion.chunk",
+		Created: time.Now().Unix(),
+		Model:   provider.Name() + "/" + modelName,
+	}
+	chunkChan := make(chan model.OpenAIChatChunk, 10)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(chunkChan)
+		defer close(errChan)
+		defer slot.Release()
+
+		send := func(delta model.OpenAIChunkDelta, finishReason *string) {
+			chunk := base
+			chunk.Choices = []model.OpenAIChunkChoice{{Delta: delta, FinishReason: finishReason}}
+			select {
+			case chunkChan <- chunk:
+			case <-ctx.Done():
+			}
+		}
+
+		send(model.OpenAIChunkDelta{Role: model.ChatRoleAssistant}, nil)
+		var usage *model.LLMUsage
+		for event := range events {
+			switch event.Event {
+			case model.LLMEventDelta, model.LLMEventReasoning:
+				var delta model.LLMDeltaEvent
+				if err := json.Unmarshal([]byte(event.Data), &delta); err != nil || delta.Content == "" {
+					continue
+				}
+				if event.Event == model.LLMEventDelta {
+					send(model.OpenAIChunkDelta{Content: delta.Content}, nil)
+				} else {
+					send(model.OpenAIChunkDelta{ReasoningContent: delta.Content}, nil)
+				}
+			case model.LLMEventUsage:
+				usage = &model.LLMUsage{}
+				json.Unmarshal([]byte(event.Data), usage)
+			}
+		}
+		finishReason := "stop"
+		if err := <-upstreamErr; errors.Is(err, ErrLLMOutputBlocked) {
+			// 与 OpenAI 一致, 回复被内容审核中止时以 content_filter 结束
+			finishReason = "content_filter"
+		} else if err != nil {
+			errChan <- err
+			return
+		}
+
+		send(model.OpenAIChunkDelta{}, &finishReason)
+		if includeUsage && usage != nil {
+			chunk := base
+			chunk.Choices = []model.OpenAIChunkChoice{}
+			chunk.Usage = usage
+			select {
+			case chunkChan <- chunk:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return chunkChan, errChan, nil
+}
+
+/*
+OpenAI 兼容的非流式对话, 汇总流式结果后一次返回
+*/
+func OpenAIChatCompletion(ctx context.Context, caller model.LLMCaller, req model.OpenAIChatRequest) (model.OpenAIChatCompletion, error) {
+	req.StreamOptions = &model.ChatStreamOptions{IncludeUsage: true}
+	chunks, errChan, err := OpenAIChatStream(ctx, caller, req)
+	if err != nil {
+		return model.OpenAIChatCompletion{}, err
+	}
+
+	var completion model.OpenAIChatCompletion
+	var content, reasoning strings.Builder
+	finishReason := "stop"
+	for chunk := range chunks {
+		completion.ID, completion.Created, completion.Model = chunk.ID, chunk.Created, chunk.Model
+		if chunk.Usage != nil {
+			completion.Usage = chunk.Usage
+		}
+		for _, choice := range chunk.Choices {
+			content.WriteString(choice.Delta.Content)
+			reasoning.WriteString(choice.Delta.ReasoningContent)
+			if choice.FinishReason != nil {
+				finishReason = *choice.FinishReason
+			}
+		}
+	}
+	if err := <-errChan; err != nil {
+		return model.OpenAIChatCompletion{}, err
+	}
+	if err := ctx.Err(); err != nil {
+		return model.OpenAIChatCompletion{}, err
+	}
+
+	completion.Object = "chat.completion"
+	completion.Choices = []model.OpenAIChoice{{
+		Message: model.OpenAIChoiceMessage{
+			Role:             model.ChatRoleAssistant,
+			Content:          content.String(),
+			ReasoningContent: reasoning.String(),
+		},
+		FinishReason: finishReason,
+	}}
+	return completion, nil
+}
+
+/*
+列出可用模型, ID 为 服务商/模型
+*/
+func ListOpenAIModels() []model.OpenAIModel {
+	list := []model.OpenAIModel{}
+	for _, provider := range llmProviders {
+		for _, name := range provider.Models() {
+			list = append(list, model.OpenAIModel{
+				ID:      provider.Name() + "/" + name,
+				Object:  "model",
+				Created: llmProvidersLoadedAt.Unix(),
+				OwnedBy: provider.Name(),
+			})
+		}
+	}
+	return list
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"gin/config"
+	"gin/db"
+	"gin/model"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrLLMPersonaNotFound = errors.New("角色不存在")
+	ErrInvalidLLMPersona  = errors.New("角色参数错误")
+)
+
+// validateLLMPersona 校验角色配置的服务商和模型
+func validateLLMPersona(persona *model.LLMPersona) error {
+	persona.Name = strings.TrimSpace(persona.Name)
+	persona.Provider = strings.ToLower(strings.TrimSpace(persona.Provider))
+	persona.Model = strings.TrimSpace(persona.Model)
+	if persona.Name == "" || strings.TrimSpace(persona.SystemPrompt) == "" {
+		return fmt.Errorf("%w: 名称和系统提示词不能为空", ErrInvalidLLMPersona)
+	}
+	if persona.MaxTokens > config.LLMMaxOutputTokens {
+		return fmt.Errorf("%w: 最大输出 token 不能超过 %d", ErrInvalidLLMPersona, config.LLMMaxOutputTokens)
+	}
+	if persona.Provider != "" || persona.Model != "" {
+		if _, _, err := ResolveLLMProvider(persona.Provider, persona.Model); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidLLMPersona, err)
+		}
+	}
+	return nil
+}
+
+/*
+查询全部角色(管理员)
+*/
+func ListLLMPersonas() ([]model.LLMPersona, error) {
+	personas := []model.LLMPersona{}
+	if err := db.DB.Order("id").Find(&personas).Error; err != nil {
+		return nil, fmt.Errorf("查询角色失败: %v", err)
+	}
+	return personas, nil
+}
+
+/*
+查询已启用的角色, 不返回系统提示词
+*/
+func ListEnabledLLMPersonas() ([]model.LLMPersonaInfo, error) {
+	var personas []model.LLMPersona
+	if err := db.DB.Where("enabled = ?", true).Order("id").Find(&personas).Error; err != nil {
+		return nil, fmt.Errorf("查询角色失败: %v", err)
+	}
+	list := make([]model.LLMPersonaInfo, 0, len(personas))
+	for _, persona := range personas {
+		list = append(list, model.LLMPersonaInfo{
+			ID:          persona.ID,
+			Name:        persona.Name,
+			Description: persona.Description,
+			Provider:    persona.Provider,
+			Model:       persona.Model,
+			LockModel:   persona.LockModel,
+		})
+	}
+	return list, nil
+}
+
+/*
+创建角色
+*/
+func CreateLLMPersona(persona model.LLMPersona) (model.LLMPersona, error) {
+	if err := validateLLMPersona(&persona); err != nil {
+		return model.LLMPersona{}, err
+	}
+	now := time.Now()
+	persona.ID = 0
+	persona.CreatedAt = now
+	persona.UpdatedAt = now
+	if err := db.DB.Create(&persona).Error; err != nil {
+		return model.LLMPersona{}, fmt.Errorf("保存角色失败: %v", err)
+	}
+	return persona, nil
+}
+
+/*
+更新角色, 整体覆盖除ID和创建时间外的字段
+*/
+func UpdateLLMPersona(id uint64, persona model.LLMPersona) (model.LLMPersona, error) {
+	existing, err := findLLMPersona(id)
+	if err != nil {
+		return model.LLMPersona{}, err
+	}
+	if err := validateLLMPersona(&persona); err != nil {
+		return model.LLMPersona{}, err
+	}
+	persona.ID = existing.ID
+	persona.CreatedAt = existing.CreatedAt
+	persona.UpdatedAt = time.Now()
+	if err := db.DB.Save(&persona).Error; err != nil {
+		return model.LLMPersona{}, fmt.Errorf("更新角色失败: %v", err)
+	}
+	return persona, nil
+}
+
+/*
+删除角色
+*/
+func DeleteLLMPersona(id uint64) error {
+	result := db.DB.Delete(&model.LLMPersona{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("删除角色失败: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrLLMPersonaNotFound
+	}
+	return nil
+}
+
+func findLLMPersona(id uint64) (model.LLMPersona, error) {
+	var persona model.LLMPersona
+	err := db.DB.First(&persona, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return persona, ErrLLMPersonaNotFound
+	}
+	if err != nil {
+		return persona, fmt.Errorf("查询角色失败: %v", err)
+	}
+	return persona, nil
+}
+
+/*
+应用角色配置: 在消息前加入系统提示词, 调用方未指定或角色锁定的参数使用角色的配置
+调用方自带的 system 消息会被丢弃, 避免绕过角色设定
+*/
+func applyLLMPersona(personaID uint64, providerName *string, params *model.LLMChatParams) error {
+	persona, err := findLLMPersona(personaID)
+	if err != nil {
+		return err
+	}
+	if !persona.Enabled {
+		return ErrLLMPersonaNotFound
+	}
+
+	if persona.LockModel || (*providerName == "" && params.Model == "") {
+		*providerName = persona.Provider
+		params.Model = persona.Model
+	}
+	if persona.LockTemperature || params.Temperature == nil {
+		params.Temperature = persona.Temperature
+	}
+	if persona.LockMaxTokens || params.MaxTokens == 0 {
+		params.MaxTokens = persona.MaxTokens
+	}
+
+	messages := make([]model.Message, 0, len(params.Messages)+1)
+	messages = append(messages, model.Message{Role: model.ChatRoleSystem, Content: persona.SystemPrompt})
+	for _, msg := range params.Messages {
+		if msg.Role != model.ChatRoleSystem {
+			messages = append(messages, msg)
+		}
+	}
+	params.Messages = messages
+	return nil
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gin/config"
+	"gin/model"
+	"strings"
+	"time"
+)
+
+var (
+	ErrLLMProviderNotFound = errors.New("LLM服务商不存在")
+	ErrLLMModelNotAllowed  = errors.New("该服务商不支持此模型")
+	ErrInvalidLLMParams    = errors.New("LLM请求参数错误")
+)
+
+// LLMProvider LLM服务商
+type LLMProvider interface {
+	// Name 服务商名称
+	Name() string
+	// Models 允许使用的模型, 第一个为默认模型
+	Models() []string
+	// ChatStream 流式对话, ctx 取消后停止读取并关闭通道, 服务商返回的用量在数据块的 Usage 中
+	ChatStream(ctx context.Context, params model.LLMChatParams) (<-chan model.LLMChunk, <-chan error)
+	// Chat 非流式对话, 返回完整回复和用量(服务商未返回时为nil)
+	Chat(ctx context.Context, params model.LLMChatParams) (string, *model.LLMUsage, error)
+}
+
+// LLMEmbeddingProvider 支持文本向量化的服务商(可选接口)
+type LLMEmbeddingProvider interface {
+	// Embed 按输入顺序返回每段文本的向量
+	Embed(ctx context.Context, model string, inputs []string) ([][]float32, error)
+}
+
+// 已注册的服务商, 按配置顺序排列
+var llmProviders []LLMProvider
+
+// 服务商注册时间, 作为 /v1/models 中模型的创建时间
+var llmProvidersLoadedAt time.Time
+
+/*
+根据配置注册LLM服务商
+*/
+func InitLLMProviders() error {
+	providers := make([]LLMProvider, 0, len(config.LLMProviders))
+	for _, cfg := range config.LLMProviders {
+		provider, err := newLLMProvider(cfg)
+		if err != nil {
+			return fmt.Errorf("LLM服务商 %s 配置错误: %v", cfg.Name, err)
+		}
+		providers = append(providers, provider)
+	}
+	llmProviders = providers
+	llmProvidersLoadedAt = time.Now()
+	if len(providers) == 0 {
+		fmt.Println("警告: 未配置任何LLM服务商, LLM相关接口不可用")
+		return nil
+	}
+	if _, err := findLLMProvider(config.LLMDefaultProvider); err != nil {
+		return fmt.Errorf("默认LLM服务商 %s 未注册", config.LLMDefaultProvider)
+	}
+	return nil
+}
+
+func newLLMProvider(cfg config.LLMProviderConfig) (LLMProvider, error) {
+	switch cfg.Type {
+	case "deepseek":
+		if cfg.BaseURL == "" {
+			cfg.BaseURL = "https://api.deepseek.com"
+		}
+		if len(cfg.Models) == 0 {
+			cfg.Models = []string{"deepseek-chat", "deepseek-reasoner"}
+		}
+		return newOpenAICompatibleProvider(cfg)
+	case "openai":
+		if cfg.BaseURL == "" {
+			cfg.BaseURL = "https://api.openai.com/v1"
+		}
+		return newOpenAICompatibleProvider(cfg)
+	case "ollama":
+		if cfg.BaseURL == "" {
+			cfg.BaseURL = "http://127.0.0.1:11434"
+		}
+		return newOllamaProvider(cfg)
+	}
+	return nil, fmt.Errorf("不支持的服务商类型: %s", cfg.Type)
+}
+
+func findLLMProvider(name string) (LLMProvider, error) {
+	for _, provider := range llmProviders {
+		if provider.Name() == name {
+			return provider, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrLLMProviderNotFound, name)
+}
+
+/*
+按请求选择服务商和模型, 未指定时使用默认服务商和该服务商的默认模型
+*/
+func ResolveLLMProvider(providerName, modelName string) (LLMProvider, string, error) {
+	providerName = strings.ToLower(strings.TrimSpace(providerName))
+	if providerName == "" {
+		providerName = config.LLMDefaultProvider
+	}
+	provider, err := findLLMProvider(providerName)
+	if err != nil {
+		return nil, "", err
+	}
+	models := provider.Models()
+	if modelName == "" {
+		return provider, models[0], nil
+	}
+	for _, name := range models {
+		if name == modelName {
+			return provider, modelName, nil
+		}
+	}
+	return nil, "", fmt.Errorf("%w: %s/%s", ErrLLMModelNotAllowed, providerName, modelName)
+}
+
+/*
+列出已注册的服务商和模型
+*/
+func ListLLMProviders() []model.LLMProviderInfo {
+	list := make([]model.LLMProviderInfo, 0, len(llmProviders))
+	for _, provider := range llmProviders {
+		list = append(list, model.LLMProviderInfo{
+			Name:    provider.Name(),
+			Models:  provider.Models(),
+			Default: provider.Name() == config.LLMDefaultProvider,
+		})
+	}
+	return list
+}
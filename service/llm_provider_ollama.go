@@ -0,0 +1,268 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gin/config"
+	"gin/model"
+	"io"
+	"net/http"
+)
+
+// ollamaProvider 本地 Ollama 服务, 使用其原生 /api/chat 接口
+type ollamaProvider struct {
+	name    string
+	baseURL string
+	models  []string
+	client  *http.Client
+}
+
+// ollamaChatRequest Ollama 对话请求
+type ollamaChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Tools    []model.LLMTool `json:"tools,omitempty"`
+	Options  map[string]any  `json:"options,omitempty"`
+}
+
+// ollamaMessage Ollama 的消息格式, 工具调用参数是 JSON 对象而不是字符串
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	Thinking  string           `json:"thinking,omitempty"` // 推理模型的思考过程
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+}
+
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+func toOllamaMessages(messages []model.Message) []ollamaMessage {
+	result := make([]ollamaMessage, 0, len(messages))
+	for _, msg := range messages {
+		converted := ollamaMessage{Role: msg.Role, Content: msg.Content}
+		for _, call := range msg.ToolCalls {
+			var toolCall ollamaToolCall
+			toolCall.Function.Name = call.Function.Name
+			toolCall.Function.Arguments = json.RawMessage("{}")
+			if json.Valid([]byte(call.Function.Arguments)) {
+				toolCall.Function.Arguments = json.RawMessage(call.Function.Arguments)
+			}
+			converted.ToolCalls = append(converted.ToolCalls, toolCall)
+		}
+		result = append(result, converted)
+	}
+	return result
+}
+
+// toolCalls 转换为统一的工具调用, Ollama 不返回调用ID, 按顺序生成
+func (m ollamaMessage) toolCalls() []model.ToolCall {
+	var calls []model.ToolCall
+	for i, call := range m.ToolCalls {
+		calls = append(calls, model.ToolCall{
+			ID:   fmt.Sprintf("call_%d", i),
+			Type: "function",
+			Function: model.ToolCallFunction{
+				Name:      call.Function.Name,
+				Arguments: string(call.Function.Arguments),
+			},
+		})
+	}
+	return calls
+}
+
+func newOllamaChatRequest(params model.LLMChatParams, stream bool) ollamaChatRequest {
+	body := ollamaChatRequest{Model: params.Model, Messages: toOllamaMessages(params.Messages), Stream: stream, Tools: params.Tools}
+	options := map[string]any{}
+	if params.Temperature != nil {
+		options["temperature"] = *params.Temperature
+	}
+	if params.MaxTokens > 0 {
+		options["num_predict"] = params.MaxTokens
+	}
+	if len(options) > 0 {
+		body.Options = options
+	}
+	return body
+}
+
+// ollamaChatResponse Ollama 对话响应, 流式时每行一个
+// 结束时的数据块携带 prompt_eval_count / eval_count
+type ollamaChatResponse struct {
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	Error           string        `json:"error"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+}
+
+// usage 将 Ollama 的计数转换为统一的用量
+func (r ollamaChatResponse) usage() *model.LLMUsage {
+	if !r.Done {
+		return nil
+	}
+	return &model.LLMUsage{
+		PromptTokens:     r.PromptEvalCount,
+		CompletionTokens: r.EvalCount,
+		TotalTokens:      r.PromptEvalCount + r.EvalCount,
+	}
+}
+
+func newOllamaProvider(cfg config.LLMProviderConfig) (*ollamaProvider, error) {
+	if len(cfg.Models) == 0 {
+		return nil, errors.New("未配置可用模型")
+	}
+	return &ollamaProvider{
+		name:    cfg.Name,
+		baseURL: cfg.BaseURL,
+		models:  cfg.Models,
+		client:  &http.Client{Timeout: config.LLMRequestTimeout},
+	}, nil
+}
+
+func (p *ollamaProvider) Name() string {
+	return p.name
+}
+
+func (p *ollamaProvider) Models() []string {
+	return p.models
+}
+
+func (p *ollamaProvider) post(ctx context.Context, body ollamaChatRequest) (*http.Response, error) {
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("无法编码请求体: %v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/api/chat", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("无法创建请求: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("Ollama 错误 (状态码 %d): %s", resp.StatusCode, string(data))
+	}
+	return resp, nil
+}
+
+func (p *ollamaProvider) Chat(ctx context.Context, params model.LLMChatParams) (string, *model.LLMUsage, error) {
+	resp, err := p.post(ctx, newOllamaChatRequest(params, false))
+	if err != nil {
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+
+	var chatResponse ollamaChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResponse); err != nil {
+		return "", nil, fmt.Errorf("解析响应失败: %v", err)
+	}
+	if chatResponse.Error != "" {
+		return "", nil, fmt.Errorf("Ollama 错误: %s", chatResponse.Error)
+	}
+	return chatResponse.Message.Content, chatResponse.usage(), nil
+}
+
+func (p *ollamaProvider) ChatStream(ctx context.Context, params model.LLMChatParams) (<-chan model.LLMChunk, <-chan error) {
+	dataChan := make(chan model.LLMChunk, 10)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(dataChan)
+		defer close(errChan)
+
+		resp, err := p.post(ctx, newOllamaChatRequest(params, true))
+		if err != nil {
+			errChan <- err
+			return
+		}
+		defer resp.Body.Close()
+
+		// Ollama 流式响应每行是一个完整的 JSON 对象
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := scanner.Bytes()
+			if len(line) == 0 {
+				continue
+			}
+			var chunk ollamaChatResponse
+			if err := json.Unmarshal(line, &chunk); err != nil {
+				fmt.Printf("警告: 无法解析流数据: %v\n", err)
+				continue
+			}
+			if chunk.Error != "" {
+				errChan <- fmt.Errorf("Ollama 错误: %s", chunk.Error)
+				return
+			}
+			toolCalls := chunk.Message.toolCalls()
+			if chunk.Message.Content != "" || chunk.Message.Thinking != "" || len(toolCalls) > 0 || chunk.Done {
+				select {
+				case dataChan <- model.LLMChunk{Content: chunk.Message.Content, Reasoning: chunk.Message.Thinking, ToolCalls: toolCalls, Usage: chunk.usage()}:
+				case <-ctx.Done():
+					errChan <- ctx.Err()
+					return
+				}
+			}
+			if chunk.Done {
+				break
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			errChan <- fmt.Errorf("读取流数据失败: %v", err)
+		}
+	}()
+
+	return dataChan, errChan
+}
+
+// Embed 调用 /api/embed 接口
+func (p *ollamaProvider) Embed(ctx context.Context, modelName string, inputs []string) ([][]float32, error) {
+	jsonData, err := json.Marshal(map[string]any{"model": modelName, "input": inputs})
+	if err != nil {
+		return nil, fmt.Errorf("无法编码请求体: %v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/api/embed", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("无法创建请求: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("Ollama 错误 (状态码 %d): %s", resp.StatusCode, string(data))
+	}
+
+	var embeddingResponse struct {
+		Embeddings [][]float32 `json:"embeddings"`
+		Error      string      `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&embeddingResponse); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %v", err)
+	}
+	if embeddingResponse.Error != "" {
+		return nil, fmt.Errorf("Ollama 错误: %s", embeddingResponse.Error)
+	}
+	if len(embeddingResponse.Embeddings) != len(inputs) {
+		return nil, errors.New("向量结果数量与输入不一致")
+	}
+	return embeddingResponse.Embeddings, nil
+}
@@ -0,0 +1,244 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gin/config"
+	"gin/model"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// 单次回复中工具调用序号的上限, 超出或为负数的序号视为上游数据异常
+const maxStreamToolCallIndex = 64
+
+// openAICompatibleProvider 兼容 OpenAI Chat Completions 接口的服务商(DeepSeek 也使用该格式)
+type openAICompatibleProvider struct {
+	name    string
+	baseURL string
+	apiKey  string
+	models  []string
+	client  *http.Client
+}
+
+func newOpenAICompatibleProvider(cfg config.LLMProviderConfig) (*openAICompatibleProvider, error) {
+	if len(cfg.Models) == 0 {
+		return nil, errors.New("未配置可用模型")
+	}
+	return &openAICompatibleProvider{
+		name:    cfg.Name,
+		baseURL: cfg.BaseURL,
+		apiKey:  cfg.APIKey,
+		models:  cfg.Models,
+		client:  &http.Client{Timeout: config.LLMRequestTimeout}, // 流式传输需要更长的超时
+	}, nil
+}
+
+func (p *openAICompatibleProvider) Name() string {
+	return p.name
+}
+
+func (p *openAICompatibleProvider) Models() []string {
+	return p.models
+}
+
+// chatRequest 转换为 chat/completions 请求体
+func (p *openAICompatibleProvider) chatRequest(params model.LLMChatParams, stream bool) model.ChatRequest {
+	body := model.ChatRequest{
+		Model:       params.Model,
+		Messages:    params.Messages,
+		Temperature: params.Temperature,
+		MaxTokens:   params.MaxTokens,
+		Tools:       params.Tools,
+	}
+	if stream {
+		body.Stream = true
+		body.StreamOptions = &model.ChatStreamOptions{IncludeUsage: true}
+	}
+	return body
+}
+
+// post 发送 chat/completions 请求, 非200状态码时返回错误
+func (p *openAICompatibleProvider) post(ctx context.Context, body model.ChatRequest) (*http.Response, error) {
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("无法编码请求体: %v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/chat/completions", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("无法创建请求: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API 错误 (状态码 %d): %s", resp.StatusCode, string(data))
+	}
+	return resp, nil
+}
+
+func (p *openAICompatibleProvider) Chat(ctx context.Context, params model.LLMChatParams) (string, *model.LLMUsage, error) {
+	resp, err := p.post(ctx, p.chatRequest(params, false))
+	if err != nil {
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+
+	var chatResponse model.ChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResponse); err != nil {
+		return "", nil, fmt.Errorf("解析响应失败: %v", err)
+	}
+	if len(chatResponse.Choices) == 0 {
+		return "", nil, errors.New("响应中没有内容")
+	}
+	return chatResponse.Choices[0].Message.Content, chatResponse.Usage, nil
+}
+
+func (p *openAICompatibleProvider) ChatStream(ctx context.Context, params model.LLMChatParams) (<-chan model.LLMChunk, <-chan error) {
+	dataChan := make(chan model.LLMChunk, 10)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(dataChan)
+		defer close(errChan)
+
+		resp, err := p.post(ctx, p.chatRequest(params, true))
+		if err != nil {
+			errChan <- err
+			return
+		}
+		defer resp.Body.Close()
+
+		// 工具调用按 index 分多个数据块返回, 需要拼接参数
+		var toolCalls []model.ToolCall
+
+		// 使用 bufio.Scanner 逐行读取流式数据, 格式为 data: {...json...}
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if !strings.HasPrefix(line, "data:") {
+				continue
+			}
+			jsonStr := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+			// 检查是否是结束标记
+			if jsonStr == "[DONE]" {
+				break
+			}
+
+			var streamResponse model.ChatStreamResponse
+			if err := json.Unmarshal([]byte(jsonStr), &streamResponse); err != nil {
+				// 跳过无法解析的行，继续处理下一行
+				fmt.Printf("警告: 无法解析流数据: %v\n", err)
+				continue
+			}
+			chunk := model.LLMChunk{Usage: streamResponse.Usage}
+			if len(streamResponse.Choices) > 0 {
+				delta := streamResponse.Choices[0].Delta
+				chunk.Content = delta.Content
+				chunk.Reasoning = delta.ReasoningContent
+				for _, call := range delta.ToolCalls {
+					if call.Index < 0 || call.Index >= maxStreamToolCallIndex {
+						fmt.Printf("警告: 工具调用序号无效: %d\n", call.Index)
+						continue
+					}
+					for len(toolCalls) <= call.Index {
+						toolCalls = append(toolCalls, model.ToolCall{Type: "function"})
+					}
+					if call.ID != "" {
+						toolCalls[call.Index].ID = call.ID
+					}
+					if call.Function.Name != "" {
+						toolCalls[call.Index].Function.Name = call.Function.Name
+					}
+					toolCalls[call.Index].Function.Arguments += call.Function.Arguments
+				}
+			}
+			if chunk.Content != "" || chunk.Reasoning != "" || chunk.Usage != nil {
+				select {
+				case dataChan <- chunk:
+				case <-ctx.Done():
+					errChan <- ctx.Err()
+					return
+				}
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			errChan <- fmt.Errorf("读取流数据失败: %v", err)
+			return
+		}
+		if len(toolCalls) > 0 {
+			select {
+			case dataChan <- model.LLMChunk{ToolCalls: toolCalls}:
+			case <-ctx.Done():
+				errChan <- ctx.Err()
+			}
+		}
+	}()
+
+	return dataChan, errChan
+}
+
+type openAIEmbeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// Embed 调用 /embeddings 接口
+func (p *openAICompatibleProvider) Embed(ctx context.Context, modelName string, inputs []string) ([][]float32, error) {
+	jsonData, err := json.Marshal(map[string]any{"model": modelName, "input": inputs})
+	if err != nil {
+		return nil, fmt.Errorf("无法编码请求体: %v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/embeddings", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("无法创建请求: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API 错误 (状态码 %d): %s", resp.StatusCode, string(data))
+	}
+
+	var embeddingResponse openAIEmbeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&embeddingResponse); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %v", err)
+	}
+	vectors := make([][]float32, len(inputs))
+	for _, item := range embeddingResponse.Data {
+		if item.Index < 0 || item.Index >= len(vectors) {
+			return nil, errors.New("向量结果序号无效")
+		}
+		vectors[item.Index] = item.Embedding
+	}
+	for _, vector := range vectors {
+		if len(vector) == 0 {
+			return nil, errors.New("向量结果数量与输入不一致")
+		}
+	}
+	return vectors, nil
+}
@@ -1,108 +1,130 @@
 package service
 
 import (
-	"bufio"
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"gin/model"
-	"io"
-	"net/http"
-	"strings"
-	"time"
 	"gin/config"
+	"gin/model"
 )
 
-// 流式请求 - 返回一个通道用于接收数据块
-func SendMessageToLLMStream(message string) (<-chan string, <-chan error) {
-	dataChan := make(chan string, 10)
-	errChan := make(chan error, 1)
-
-	go func() {
-		defer close(dataChan)
-		defer close(errChan)
-
-		url := "https://api.deepseek.com/chat/completions"
-
-		// 构建请求体，启用流式传输
-		requestBody := map[string]interface{}{
-			"model": "deepseek-chat",
-			"messages": []map[string]string{
-				{
-					"role":    "user",
-					"content": message,
-				},
-			},
-			"stream": true, // 启用流式传输
+// 单轮流式请求 - 返回一个通道用于接收数据块
+// provider / model 为空时使用默认服务商和模型, 指定角色时应用角色的系统提示词和参数
+func SendMessageToLLMStream(ctx context.Context, caller model.LLMCaller, req model.LLMMessageRequest) (<-chan model.LLMStreamEvent, <-chan error, error) {
+	params := model.LLMChatParams{
+		Model:       req.Model,
+		Messages:    []model.Message{{Role: model.ChatRoleUser, Content: req.Content}},
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+		Cache:       req.Cache,
+	}
+	if req.EnableTools {
+		params.Tools = LLMToolDefinitions()
+	}
+	providerName := req.Provider
+	if req.PersonaID != 0 {
+		if err := applyLLMPersona(req.PersonaID, &providerName, &params); err != nil {
+			return nil, nil, err
 		}
-
-		jsonData, err := json.Marshal(requestBody)
-		if err != nil {
-			errChan <- fmt.Errorf("无法编码请求体: %v", err)
-			return
+	}
+
+	// 只审核客户端发送的用户消息(最后一条), 角色的系统提示词由服务端提供
+	// 审核在检索之前进行, 被拦截的内容不会发送给向量接口
+	provider, modelName, err := ResolveLLMProvider(providerName, params.Model)
+	if err != nil {
+		return nil, nil, err
+	}
+	last := len(params.Messages) - 1
+	events, err := moderateLLMMessages(ctx, caller, provider.Name(), modelName, params.Messages[last:])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if req.EnableRetrieval {
+		// 检索会调用向量接口, 先检查配额, 超出配额的请求不再检索
+		if err := CheckLLMQuota(caller); err != nil {
+			return nil, nil, err
 		}
-
-		req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+		// 检索结果作为系统消息放在用户消息之前, 引用信息在回复开始前先推送给客户端
+		citations, texts, err := SearchRAG(ctx, params.Messages[last].Content, config.RAGTopK)
 		if err != nil {
-			errChan <- fmt.Errorf("无法创建请求: %v", err)
-			return
-		}
-
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("Authorization", "Bearer " + config.DeepseekAPIKey)
-
-		client := &http.Client{Timeout: 5 * time.Minute} // 流式传输需要更长的超时
-		resp, err := client.Do(req)
-		if err != nil {
-			errChan <- fmt.Errorf("请求失败: %v", err)
-			return
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			errChan <- fmt.Errorf("API 错误 (状态码 %d): %s", resp.StatusCode, string(body))
-			return
+			return nil, nil, err
 		}
+		params.Messages = append(params.Messages[:last:last], buildRAGMessage(citations, texts), params.Messages[last])
+		events = append(events, marshalLLMEvent(model.LLMEventCitations, citations))
+	}
+	eventChan, errChan, err := SendChatStream(ctx, caller, providerName, params)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(events) == 0 {
+		return eventChan, errChan, nil
+	}
+	return prependLLMEvents(ctx, eventChan, events...), errChan, nil
+}
 
-		// 使用 bufio.Scanner 逐行读取流式数据
-		scanner := bufio.NewScanner(resp.Body)
-		for scanner.Scan() {
-			line := scanner.Text()
-
-			// 跳过空行
-			if line == "" {
-				continue
-			}
-
-			// DeepSeek API 返回的格式：data: {...json...}
-			if strings.HasPrefix(line, "data: ") {
-				jsonStr := strings.TrimPrefix(line, "data: ")
-
-				// 检查是否是结束标记
-				if jsonStr == "[DONE]" {
-					break
-				}
-
-				// 解析 JSON
-				var streamResponse model.ChatStreamResponse
-				if err := json.Unmarshal([]byte(jsonStr), &streamResponse); err != nil {
-					// 跳过无法解析的行，继续处理下一行
-					fmt.Printf("警告: 无法解析流数据: %v\n", err)
-					continue
-				}
-
-				// 提取文本内容
-				if len(streamResponse.Choices) > 0 && streamResponse.Choices[0].Delta.Content != "" {
-					dataChan <- streamResponse.Choices[0].Delta.Content
-				}
+// prependLLMEvents 先推送给定事件, 再转发上游事件
+func prependLLMEvents(ctx context.Context, upstream <-chan model.LLMStreamEvent, events ...model.LLMStreamEvent) <-chan model.LLMStreamEvent {
+	eventChan := make(chan model.LLMStreamEvent, 10)
+	go func() {
+		defer close(eventChan)
+		for _, event := range events {
+			select {
+			case eventChan <- event:
+			case <-ctx.Done():
 			}
 		}
-
-		if err := scanner.Err(); err != nil {
-			errChan <- fmt.Errorf("读取流数据失败: %v", err)
+		for event := range upstream {
+			select {
+			case eventChan <- event:
+			case <-ctx.Done():
+			}
 		}
 	}()
+	return eventChan
+}
 
-	return dataChan, errChan
+// resolveChatParams 选择服务商和模型并检查参数
+func resolveChatParams(providerName string, params *model.LLMChatParams) (LLMProvider, error) {
+	provider, modelName, err := ResolveLLMProvider(providerName, params.Model)
+	if err != nil {
+		return nil, err
+	}
+	params.Model = modelName
+	if params.MaxTokens > config.LLMMaxOutputTokens {
+		return nil, fmt.Errorf("%w: 最大输出 token 不能超过 %d", ErrInvalidLLMParams, config.LLMMaxOutputTokens)
+	}
+	return provider, nil
+}
+
+// 携带完整上下文的流式请求, ctx 取消后停止读取并关闭通道
+// 请求前检查调用者的 token 配额, 结束后记录用量; 提供了工具时执行工具调用循环
+// 开启缓存的确定性请求先查缓存, 命中时重放缓存的回复, 不检查配额也不计量
+// 启用内容审核时回复(包括缓存的回复)经过审核后再转发, 客户端提供的消息由调用方审核
+func SendChatStream(ctx context.Context, caller model.LLMCaller, providerName string, params model.LLMChatParams) (<-chan model.LLMStreamEvent, <-chan error, error) {
+	provider, err := resolveChatParams(providerName, &params)
+	if err != nil {
+		return nil, nil, err
+	}
+	cacheKey := ""
+	if llmCacheable(params) {
+		cacheKey = llmCacheKey(provider.Name(), params)
+		if entry := getLLMCache(cacheKey); entry != nil {
+			fmt.Println("LLM缓存命中 - 用户:", caller.Username, "模型:", provider.Name()+"/"+params.Model)
+			// 缓存可能写入于启用审核或更新规则之前, 重放时同样经过审核
+			eventChan, errChan := moderateLLMStream(ctx, caller, provider.Name(), params.Model, func(ctx context.Context) (<-chan model.LLMStreamEvent, <-chan error) {
+				return replayLLMCache(ctx, entry)
+			})
+			return eventChan, errChan, nil
+		}
+	}
+	if err := CheckLLMQuota(caller); err != nil {
+		return nil, nil, err
+	}
+	eventChan, errChan := moderateLLMStream(ctx, caller, provider.Name(), params.Model, func(ctx context.Context) (<-chan model.LLMStreamEvent, <-chan error) {
+		return streamChatWithTools(ctx, caller, provider, params)
+	})
+	if cacheKey != "" {
+		eventChan, errChan = cacheLLMStream(ctx, cacheKey, eventChan, errChan)
+	}
+	return eventChan, errChan, nil
 }
@@ -0,0 +1,273 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gin/config"
+	"gin/db"
+	"gin/model"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrLLMStreamNotFound  = errors.New("流不存在或已过期")
+	ErrInvalidLastEventID = errors.New("Last-Event-ID 格式错误")
+	ErrLLMStreamIdle      = errors.New("LLM服务商长时间没有响应, 已中止")
+)
+
+// 续传时轮询Redis的间隔, 正常情况下由发布订阅通知
+const llmStreamPollInterval = time.Second
+
+func llmStreamEventsKey(streamID string) string {
+	return "llm:stream:" + streamID + ":events"
+}
+
+func llmStreamOwnerKey(streamID string) string {
+	return "llm:stream:" + streamID + ":owner"
+}
+
+func llmStreamReaderKey(streamID string) string {
+	return "llm:stream:" + streamID + ":reader"
+}
+
+func llmStreamNotifyChannel(streamID string) string {
+	return "llm:stream:" + streamID + ":notify"
+}
+
+// LLMEventID SSE 事件ID, 格式为 流ID:序号
+func LLMEventID(streamID string, seq int) string {
+	return streamID + ":" + strconv.Itoa(seq)
+}
+
+// ParseLLMEventID 解析 SSE 事件ID, 返回流ID和序号
+func ParseLLMEventID(eventID string) (string, int, error) {
+	streamID, seqText, ok := strings.Cut(strings.TrimSpace(eventID), ":")
+	if !ok || streamID == "" {
+		return "", 0, ErrInvalidLastEventID
+	}
+	seq, err := strconv.Atoi(seqText)
+	if err != nil || seq < 0 {
+		return "", 0, ErrInvalidLastEventID
+	}
+	return streamID, seq, nil
+}
+
+// isLLMTerminalEvent 结束事件之后不再有其他事件
+func isLLMTerminalEvent(event string) bool {
+	return event == model.LLMEventDone || event == model.LLMEventError
+}
+
+// bufferLLMStreamRecord 把事件追加到Redis缓存并通知正在续传的连接
+func bufferLLMStreamRecord(streamID string, record model.LLMStreamRecord) {
+	data, _ := json.Marshal(record)
+	pipe := db.RDB.TxPipeline()
+	pipe.RPush(db.Ctx, llmStreamEventsKey(streamID), data)
+	pipe.Expire(db.Ctx, llmStreamEventsKey(streamID), config.LLMStreamBufferTTL)
+	pipe.Expire(db.Ctx, llmStreamOwnerKey(streamID), config.LLMStreamBufferTTL)
+	pipe.Publish(db.Ctx, llmStreamNotifyChannel(streamID), record.Seq)
+	if _, err := pipe.Exec(db.Ctx); err != nil {
+		fmt.Println("缓存流式事件失败:", err)
+	}
+}
+
+/*
+在后台运行一次流式生成
+生成使用独立的 context(受 LLMRequestTimeout 限制), 客户端断开后继续生成并把事件缓存到Redis, 重连时可以续传;
+断开超过 LLMResumeGrace 仍无人续传时取消生成
+超出并发限制时先推送 queue 事件排队, 获得名额后再开始生成
+事件按顺序编号, 最后追加 done 或 error 事件; 返回的通道推送给当前连接, ctx 结束后不再推送
+*/
+func StartLLMStream(ctx context.Context, owner string, start func(ctx context.Context) (<-chan model.LLMStreamEvent, <-chan error, error)) (string, <-chan model.LLMStreamRecord, error) {
+	slot, err := acquireLLMSlot(owner)
+	if err != nil {
+		return "", nil, err
+	}
+	streamCtx, cancel := context.WithTimeout(context.Background(), config.LLMRequestTimeout)
+
+	// 立即获得名额时同步开始, 参数和配额错误直接返回给调用方
+	var events <-chan model.LLMStreamEvent
+	var errs <-chan error
+	if slot.Ready() {
+		events, errs, err = start(streamCtx)
+		if err != nil {
+			slot.Release()
+			cancel()
+			return "", nil, err
+		}
+	}
+
+	streamID := strings.ReplaceAll(uuid.NewString(), "-", "")
+	if err := db.RDB.Set(db.Ctx, llmStreamOwnerKey(streamID), owner, config.LLMStreamBufferTTL).Err(); err != nil {
+		fmt.Println("保存流信息失败:", err)
+	}
+	go holdLLMStreamReader(ctx, streamID)
+	go watchLLMStreamReaders(ctx, streamCtx, streamID, cancel)
+
+	live := make(chan model.LLMStreamRecord, 10)
+	go func() {
+		defer cancel()
+		defer close(live)
+		defer slot.Release()
+
+		seq := 0
+		emit := func(event, data string) {
+			seq++
+			record := model.LLMStreamRecord{Seq: seq, Event: event, Data: data}
+			bufferLLMStreamRecord(streamID, record)
+			select {
+			case live <- record:
+			case <-ctx.Done():
+			}
+		}
+		fail := func(err error) {
+			fmt.Println("LLM流式生成失败 - 流ID:", streamID, "错误:", err)
+			emit(model.LLMEventError, marshalLLMEvent("", model.LLMErrorEvent{Error: err.Error()}).Data)
+		}
+
+		if events == nil {
+			err := slot.Wait(streamCtx, func(position int) {
+				emit(model.LLMEventQueue, marshalLLMEvent("", model.LLMQueueEvent{Position: position}).Data)
+			})
+			if err == nil {
+				events, errs, err = start(streamCtx)
+			}
+			if err != nil {
+				fail(err)
+				return
+			}
+		}
+		for event := range events {
+			emit(event.Event, event.Data)
+		}
+		if err := <-errs; err != nil {
+			fail(err)
+			return
+		}
+		emit(model.LLMEventDone, marshalLLMEvent("", model.LLMDoneEvent{StreamID: streamID}).Data)
+	}()
+	return streamID, live, nil
+}
+
+// holdLLMStreamReader 连接期间定期刷新读者标记, 断开后标记在 LLMResumeGrace 后过期
+func holdLLMStreamReader(ctx context.Context, streamID string) {
+	if config.LLMResumeGrace <= 0 {
+		return
+	}
+	refresh := func() {
+		if err := db.RDB.Set(db.Ctx, llmStreamReaderKey(streamID), 1, config.LLMResumeGrace).Err(); err != nil {
+			fmt.Println("刷新流读者标记失败:", err)
+		}
+	}
+	refresh()
+	ticker := time.NewTicker(max(config.LLMResumeGrace/3, time.Second))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			refresh()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// watchLLMStreamReaders 发起生成的连接断开后, 读者标记过期(无人续传)时取消生成
+func watchLLMStreamReaders(ctx, streamCtx context.Context, streamID string, cancel context.CancelFunc) {
+	select {
+	case <-ctx.Done():
+	case <-streamCtx.Done():
+		return
+	}
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		exists, err := db.RDB.Exists(db.Ctx, llmStreamReaderKey(streamID)).Result()
+		if err != nil || exists == 0 {
+			if streamCtx.Err() == nil {
+				fmt.Println("客户端已断开且无人续传, 取消生成 - 流ID:", streamID)
+			}
+			cancel()
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-streamCtx.Done():
+			return
+		}
+	}
+}
+
+/*
+断线续传
+推送 Last-Event-ID 之后缓存的事件, 生成尚未结束时继续等待新事件, 直到 done / error 事件或 ctx 结束
+只有发起生成的用户可以续传
+*/
+func ResumeLLMStream(ctx context.Context, owner, lastEventID string) (string, <-chan model.LLMStreamRecord, error) {
+	streamID, after, err := ParseLLMEventID(lastEventID)
+	if err != nil {
+		return "", nil, err
+	}
+	storedOwner, err := db.RDB.Get(db.Ctx, llmStreamOwnerKey(streamID)).Result()
+	if errors.Is(err, redis.Nil) || (err == nil && storedOwner != owner) {
+		return "", nil, ErrLLMStreamNotFound
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("查询流信息失败: %v", err)
+	}
+
+	go holdLLMStreamReader(ctx, streamID)
+
+	// 先订阅再读取, 避免漏掉两者之间产生的事件
+	sub := db.RDB.Subscribe(ctx, llmStreamNotifyChannel(streamID))
+	records := make(chan model.LLMStreamRecord, 10)
+	go func() {
+		defer close(records)
+		defer sub.Close()
+
+		notify := sub.Channel()
+		ticker := time.NewTicker(llmStreamPollInterval)
+		defer ticker.Stop()
+		for {
+			items, err := db.RDB.LRange(db.Ctx, llmStreamEventsKey(streamID), int64(after), -1).Result()
+			if err != nil {
+				fmt.Println("读取流式事件失败:", err)
+				return
+			}
+			for _, item := range items {
+				var record model.LLMStreamRecord
+				if err := json.Unmarshal([]byte(item), &record); err != nil || record.Seq <= after {
+					continue
+				}
+				select {
+				case records <- record:
+				case <-ctx.Done():
+					return
+				}
+				after = record.Seq
+				if isLLMTerminalEvent(record.Event) {
+					return
+				}
+			}
+			if len(items) == 0 {
+				// 缓存已过期(如生成所在的进程已退出)
+				if exists, err := db.RDB.Exists(db.Ctx, llmStreamOwnerKey(streamID)).Result(); err != nil || exists == 0 {
+					return
+				}
+			}
+			select {
+			case <-notify:
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return streamID, records, nil
+}
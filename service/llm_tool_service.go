@@ -0,0 +1,322 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gin/config"
+	"gin/model"
+	"net"
+	"regexp"
+	"strings"
+)
+
+// 返回给模型的工具结果最大长度, 避免占满上下文
+const llmToolResultMaxLength = 8000
+
+var (
+	llmToolDomainPattern = regexp.MustCompile(`^(?i)([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,63}\.?$`)
+	llmToolUIDPattern    = regexp.MustCompile(`^\d{1,20}$`)
+)
+
+// llmTool 注册给模型的工具, Run 接收模型生成的 JSON 参数
+type llmTool struct {
+	Description string
+	Parameters  map[string]any
+	Run         func(args json.RawMessage) (any, error)
+}
+
+// 工具注册表, 包装已有的后端服务
+var llmToolRegistry = map[string]llmTool{
+	"get_bilibili_follow_anime": {
+		Description: "查询B站用户公开的追番列表",
+		Parameters: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"uid": map[string]any{"type": "string", "description": "B站用户UID(纯数字)"},
+			},
+			"required": []string{"uid"},
+		},
+		Run: func(args json.RawMessage) (any, error) {
+			var params struct {
+				UID string `json:"uid"`
+			}
+			if err := json.Unmarshal(args, &params); err != nil {
+				return nil, err
+			}
+			if !llmToolUIDPattern.MatchString(params.UID) {
+				return nil, errors.New("uid 必须是数字")
+			}
+			items, err := GetFollowAnime(params.UID)
+			if err != nil {
+				return nil, err
+			}
+			type anime struct {
+				Title string `json:"title"`
+				URL   string `json:"url"`
+				Badge string `json:"badge,omitempty"`
+			}
+			list := make([]anime, 0, len(items))
+			for _, item := range items {
+				list = append(list, anime{Title: item.Title, URL: item.Url, Badge: item.Badge})
+			}
+			return map[string]any{"uid": params.UID, "count": len(list), "anime": list}, nil
+		},
+	},
+	"query_dns": {
+		Description: "查询域名的DNS记录",
+		Parameters: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"domain": map[string]any{"type": "string", "description": "要查询的域名, 例如 example.com"},
+				"type": map[string]any{
+					"type":        "string",
+					"description": "记录类型, 默认 A",
+					"enum":        []string{"A", "AAAA", "CNAME", "MX", "TXT", "NS", "SOA", "CAA", "SRV"},
+				},
+			},
+			"required": []string{"domain"},
+		},
+		Run: func(args json.RawMessage) (any, error) {
+			var params struct {
+				Domain string `json:"domain"`
+				Type   string `json:"type"`
+			}
+			if err := json.Unmarshal(args, &params); err != nil {
+				return nil, err
+			}
+			params.Domain = strings.TrimSpace(params.Domain)
+			if !llmToolDomainPattern.MatchString(params.Domain) {
+				return nil, errors.New("域名格式错误")
+			}
+			params.Type = strings.ToUpper(strings.TrimSpace(params.Type))
+			switch params.Type {
+			case "":
+				params.Type = "A"
+			case "A", "AAAA", "CNAME", "MX", "TXT", "NS", "SOA", "CAA", "SRV":
+			default:
+				return nil, fmt.Errorf("不支持的记录类型: %s", params.Type)
+			}
+			resp, err := QueryDNS(params.Domain, params.Type)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]any{"domain": params.Domain, "type": params.Type, "status": resp.Status, "answer": resp.Answer}, nil
+		},
+	},
+	"get_ip_info": {
+		Description: "查询IP地址的归属地、运营商等信息",
+		Parameters: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"ip": map[string]any{"type": "string", "description": "IPv4 或 IPv6 地址"},
+			},
+			"required": []string{"ip"},
+		},
+		Run: func(args json.RawMessage) (any, error) {
+			var params struct {
+				IP string `json:"ip"`
+			}
+			if err := json.Unmarshal(args, &params); err != nil {
+				return nil, err
+			}
+			ip := net.ParseIP(strings.TrimSpace(params.IP))
+			if ip == nil {
+				return nil, errors.New("IP地址格式错误")
+			}
+			return GetIPInfo(ip.String())
+		},
+	},
+	"parse_xiaohongshu_link": {
+		Description: "解析小红书分享链接, 获取笔记标题、描述、图片和作者信息",
+		Parameters: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"share_text": map[string]any{"type": "string", "description": "包含 xhslink.com 短链接的分享文本"},
+			},
+			"required": []string{"share_text"},
+		},
+		Run: func(args json.RawMessage) (any, error) {
+			var params struct {
+				ShareText string `json:"share_text"`
+			}
+			if err := json.Unmarshal(args, &params); err != nil {
+				return nil, err
+			}
+			return ParseXHSLink(params.ShareText)
+		},
+	},
+}
+
+// 工具在请求中的顺序
+var llmToolOrder = []string{"get_bilibili_follow_anime", "query_dns", "get_ip_info", "parse_xiaohongshu_link"}
+
+/*
+返回全部已注册工具的定义
+*/
+func LLMToolDefinitions() []model.LLMTool {
+	tools := make([]model.LLMTool, 0, len(llmToolOrder))
+	for _, name := range llmToolOrder {
+		tool := llmToolRegistry[name]
+		tools = append(tools, model.LLMTool{
+			Type: "function",
+			Function: model.LLMToolFunction{
+				Name:        name,
+				Description: tool.Description,
+				Parameters:  tool.Parameters,
+			},
+		})
+	}
+	return tools
+}
+
+// runLLMTool 执行一次工具调用, 返回 JSON 格式的结果
+func runLLMTool(ctx context.Context, call model.ToolCall) (string, error) {
+	tool, ok := llmToolRegistry[call.Function.Name]
+	if !ok {
+		return "", fmt.Errorf("工具不存在: %s", call.Function.Name)
+	}
+	args := json.RawMessage(call.Function.Arguments)
+	if strings.TrimSpace(call.Function.Arguments) == "" {
+		args = json.RawMessage("{}")
+	}
+	if !json.Valid(args) {
+		return "", errors.New("工具参数不是有效的JSON")
+	}
+
+	type toolResult struct {
+		value any
+		err   error
+	}
+	done := make(chan toolResult, 1)
+	go func() {
+		value, err := tool.Run(args)
+		done <- toolResult{value, err}
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, config.LLMToolTimeout)
+	defer cancel()
+	select {
+	case result := <-done:
+		if result.err != nil {
+			return "", result.err
+		}
+		data, err := json.Marshal(result.value)
+		if err != nil {
+			return "", fmt.Errorf("编码工具结果失败: %v", err)
+		}
+		if len(data) > llmToolResultMaxLength {
+			data = append(data[:llmToolResultMaxLength], "...(已截断)"...)
+		}
+		return string(data), nil
+	case <-ctx.Done():
+		return "", errors.New("工具调用超时")
+	}
+}
+
+// marshalLLMEvent 编码工具事件
+func marshalLLMEvent(event string, payload any) model.LLMStreamEvent {
+	data, _ := json.Marshal(payload)
+	return model.LLMStreamEvent{Event: event, Data: string(data)}
+}
+
+/*
+带工具调用循环的流式对话
+模型要求调用工具时执行工具, 把调用和结果作为单独的事件推送给客户端, 再把结果交给模型继续生成
+文本和推理片段分别以 delta / reasoning 事件推送, 结束时推送 usage 事件
+超过最大轮数后不再提供工具, 要求模型直接回答
+*/
+func streamChatWithTools(ctx context.Context, caller model.LLMCaller, provider LLMProvider, params model.LLMChatParams) (<-chan model.LLMStreamEvent, <-chan error) {
+	eventChan := make(chan model.LLMStreamEvent, 10)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(eventChan)
+		defer close(errChan)
+
+		send := func(event model.LLMStreamEvent) {
+			select {
+			case eventChan <- event:
+			case <-ctx.Done():
+			}
+		}
+
+		messages := append([]model.Message(nil), params.Messages...)
+		callIDs := map[string]bool{}
+		var usage model.LLMUsage
+		for round := 0; ; round++ {
+			roundParams := params
+			roundParams.Messages = messages
+			if round >= config.LLMMaxToolRounds {
+				roundParams.Tools = nil
+			}
+			if round > 0 {
+				if err := CheckLLMQuota(caller); err != nil {
+					errChan <- err
+					return
+				}
+			}
+
+			chunks, upstreamErr := meteredChatStream(ctx, caller, provider, roundParams)
+			var content strings.Builder
+			var calls []model.ToolCall
+			for chunk := range chunks {
+				if chunk.Reasoning != "" {
+					send(marshalLLMEvent(model.LLMEventReasoning, model.LLMDeltaEvent{Content: chunk.Reasoning}))
+				}
+				if chunk.Content != "" {
+					content.WriteString(chunk.Content)
+					send(marshalLLMEvent(model.LLMEventDelta, model.LLMDeltaEvent{Content: chunk.Content}))
+				}
+				if chunk.Usage != nil {
+					usage.PromptTokens += chunk.Usage.PromptTokens
+					usage.CompletionTokens += chunk.Usage.CompletionTokens
+					usage.TotalTokens += chunk.Usage.TotalTokens
+				}
+				calls = append(calls, chunk.ToolCalls...)
+			}
+			if err := <-upstreamErr; err != nil {
+				errChan <- err
+				return
+			}
+			if len(calls) == 0 || len(roundParams.Tools) == 0 || ctx.Err() != nil {
+				// 多轮工具调用的用量合计后推送
+				send(marshalLLMEvent(model.LLMEventUsage, usage))
+				return
+			}
+
+			// 调用ID在整个请求内唯一, 部分服务商不返回ID或每轮重复编号
+			for i := range calls {
+				if calls[i].ID == "" || callIDs[calls[i].ID] {
+					calls[i].ID = fmt.Sprintf("call_%d", len(callIDs)+1)
+				}
+				callIDs[calls[i].ID] = true
+				calls[i].Type = "function"
+			}
+			messages = append(messages, model.Message{Role: model.ChatRoleAssistant, Content: content.String(), ToolCalls: calls})
+
+			for _, call := range calls {
+				send(marshalLLMEvent(model.LLMEventToolCall, model.LLMToolCallEvent{
+					ID:        call.ID,
+					Name:      call.Function.Name,
+					Arguments: call.Function.Arguments,
+				}))
+				fmt.Println("LLM工具调用 - 用户:", caller.Username, "工具:", call.Function.Name, "参数:", call.Function.Arguments)
+
+				resultEvent := model.LLMToolResultEvent{ID: call.ID, Name: call.Function.Name}
+				result, err := runLLMTool(ctx, call)
+				if err != nil {
+					resultEvent.Error = err.Error()
+					errorJSON, _ := json.Marshal(map[string]string{"error": err.Error()})
+					result = string(errorJSON)
+				} else {
+					resultEvent.Result = result
+				}
+				send(marshalLLMEvent(model.LLMEventToolResult, resultEvent))
+				messages = append(messages, model.Message{Role: model.ChatRoleTool, Content: result, ToolCallID: call.ID})
+			}
+		}
+	}()
+	return eventChan, errChan
+}
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gin/config"
+	"gin/db"
+	"gin/model"
+	"time"
+)
+
+// QuotaExceededError token 配额已用完
+type QuotaExceededError struct {
+	Period  string // daily 或 monthly
+	Limit   int64
+	Used    int64
+	ResetAt time.Time
+}
+
+func (e *QuotaExceededError) Error() string {
+	period := "今日"
+	if e.Period == "monthly" {
+		period = "本月"
+	}
+	return fmt.Sprintf("%s token 配额已用完(%d/%d), 将于 %s 重置", period, e.Used, e.Limit, e.ResetAt.Format("2006-01-02 15:04:05"))
+}
+
+func llmDailyUsageKey(userID string, t time.Time) string {
+	return "llm:usage:day:" + userID + ":" + t.Format("20060102")
+}
+
+func llmMonthlyUsageKey(userID string, t time.Time) string {
+	return "llm:usage:month:" + userID + ":" + t.Format("200601")
+}
+
+// llmAnonymousDailyUsageKey 所有未登录调用者当日的用量合计
+func llmAnonymousDailyUsageKey(t time.Time) string {
+	return "llm:usage:anon:day:" + t.Format("20060102")
+}
+
+// llmQuotaStatus 读取调用者当前的日/月用量, 未登录调用者只有较低的每日配额
+func llmQuotaStatus(caller model.LLMCaller) (model.LLMQuotaStatus, model.LLMQuotaStatus) {
+	now := time.Now()
+	year, month, day := now.Date()
+	daily := model.LLMQuotaStatus{
+		Limit:   int64(config.LLMDailyTokenQuota),
+		ResetAt: time.Date(year, month, day+1, 0, 0, 0, 0, now.Location()),
+	}
+	monthly := model.LLMQuotaStatus{
+		Limit:   int64(config.LLMMonthlyTokenQuota),
+		ResetAt: time.Date(year, month+1, 1, 0, 0, 0, 0, now.Location()),
+	}
+	if caller.Anonymous {
+		daily.Limit = int64(config.LLMAnonymousDailyTokenQuota)
+		monthly.Limit = 0
+	}
+	daily.Used, _ = db.RDB.Get(db.Ctx, llmDailyUsageKey(caller.UserID, now)).Int64()
+	monthly.Used, _ = db.RDB.Get(db.Ctx, llmMonthlyUsageKey(caller.UserID, now)).Int64()
+	return daily, monthly
+}
+
+/*
+检查用户的日/月 token 配额, 用完时返回 *QuotaExceededError
+未登录调用者还要检查所有匿名调用合计的每日配额
+*/
+func CheckLLMQuota(caller model.LLMCaller) error {
+	daily, monthly := llmQuotaStatus(caller)
+	if daily.Limit > 0 && daily.Used >= daily.Limit {
+		return &QuotaExceededError{Period: "daily", Limit: daily.Limit, Used: daily.Used, ResetAt: daily.ResetAt}
+	}
+	if monthly.Limit > 0 && monthly.Used >= monthly.Limit {
+		return &QuotaExceededError{Period: "monthly", Limit: monthly.Limit, Used: monthly.Used, ResetAt: monthly.ResetAt}
+	}
+	if caller.Anonymous && config.LLMAnonymousGlobalDailyQuota > 0 {
+		limit := int64(config.LLMAnonymousGlobalDailyQuota)
+		used, _ := db.RDB.Get(db.Ctx, llmAnonymousDailyUsageKey(time.Now())).Int64()
+		if used >= limit {
+			return &QuotaExceededError{Period: "daily", Limit: limit, Used: used, ResetAt: daily.ResetAt}
+		}
+	}
+	return nil
+}
+
+// estimateTokens 服务商未返回用量时粗略估算 token 数(中文约1字1 token, 英文约4字符1 token)
+func estimateTokens(text string) int {
+	tokens := 0
+	ascii := 0
+	for _, r := range text {
+		if r < 128 {
+			ascii++
+		} else {
+			tokens++
+		}
+	}
+	return tokens + (ascii+3)/4
+}
+
+func estimateUsage(messages []model.Message, reply string) *model.LLMUsage {
+	usage := &model.LLMUsage{CompletionTokens: estimateTokens(reply)}
+	for _, msg := range messages {
+		usage.PromptTokens += estimateTokens(msg.Content) + 4
+	}
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	return usage
+}
+
+/*
+记录一次请求的用量, 同时累加到日/月配额计数
+*/
+func RecordLLMUsage(caller model.LLMCaller, provider, modelName string, usage model.LLMUsage, estimated bool) {
+	if usage.TotalTokens == 0 {
+		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	}
+	now := time.Now()
+	dailyKey := llmDailyUsageKey(caller.UserID, now)
+	monthlyKey := llmMonthlyUsageKey(caller.UserID, now)
+	pipe := db.RDB.TxPipeline()
+	pipe.IncrBy(db.Ctx, dailyKey, int64(usage.TotalTokens))
+	pipe.Expire(db.Ctx, dailyKey, 48*time.Hour)
+	pipe.IncrBy(db.Ctx, monthlyKey, int64(usage.TotalTokens))
+	pipe.Expire(db.Ctx, monthlyKey, 32*24*time.Hour)
+	if caller.Anonymous {
+		anonymousKey := llmAnonymousDailyUsageKey(now)
+		pipe.IncrBy(db.Ctx, anonymousKey, int64(usage.TotalTokens))
+		pipe.Expire(db.Ctx, anonymousKey, 48*time.Hour)
+	}
+	if _, err := pipe.Exec(db.Ctx); err != nil {
+		fmt.Println("累加LLM用量失败:", err)
+	}
+
+	record := model.LLMUsageRecord{
+		UserID:           caller.UserID,
+		APIKeyID:         caller.APIKeyID,
+		Provider:         provider,
+		Model:            modelName,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+		Estimated:        estimated,
+		CreatedAt:        now,
+	}
+	if err := db.DB.Create(&record).Error; err != nil {
+		fmt.Println("保存LLM用量记录失败:", err)
+	}
+}
+
+// meteredChatStream 转发服务商的流式回复(文本、推理片段和工具调用), 结束后记录用量并在最后一个数据块中返回
+// 服务商未返回用量(如客户端中途断开)时按字符数估算; ctx 取消或服务商长时间无响应时中止上游请求
+func meteredChatStream(ctx context.Context, caller model.LLMCaller, provider LLMProvider, params model.LLMChatParams) (<-chan model.LLMChunk, <-chan error) {
+	// 服务商连续 LLMIdleTimeout 没有返回数据时取消请求
+	upstreamCtx, cancel := context.WithCancelCause(ctx)
+	upstreamData, upstreamErr := provider.ChatStream(upstreamCtx, params)
+	idleTimeout := config.LLMIdleTimeout
+	if idleTimeout <= 0 {
+		idleTimeout = config.LLMRequestTimeout
+	}
+	idleTimer := time.AfterFunc(idleTimeout, func() { cancel(ErrLLMStreamIdle) })
+	dataChan := make(chan model.LLMChunk, 10)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(dataChan)
+		defer close(errChan)
+		defer cancel(nil)
+
+		var usage *model.LLMUsage
+		var reply []byte
+		for chunk := range upstreamData {
+			idleTimer.Reset(idleTimeout)
+			if chunk.Usage != nil {
+				usage = chunk.Usage
+			}
+			if chunk.Content == "" && chunk.Reasoning == "" && len(chunk.ToolCalls) == 0 {
+				continue
+			}
+			reply = append(reply, chunk.Reasoning...)
+			reply = append(reply, chunk.Content...)
+			for _, call := range chunk.ToolCalls {
+				reply = append(reply, call.Function.Arguments...)
+			}
+			select {
+			case dataChan <- model.LLMChunk{Content: chunk.Content, Reasoning: chunk.Reasoning, ToolCalls: chunk.ToolCalls}:
+			case <-ctx.Done():
+			}
+		}
+		idleTimer.Stop()
+		err := <-upstreamErr
+		if err != nil && errors.Is(context.Cause(upstreamCtx), ErrLLMStreamIdle) {
+			err = ErrLLMStreamIdle
+		}
+
+		estimated := usage == nil
+		if estimated {
+			usage = estimateUsage(params.Messages, string(reply))
+		}
+		// 请求失败且没有产生任何输出时不计量
+		if err == nil || len(reply) > 0 {
+			RecordLLMUsage(caller, provider.Name(), params.Model, *usage, estimated)
+		}
+		if err != nil {
+			errChan <- err
+			return
+		}
+		// 最后一个数据块只携带本次请求的用量(服务商未返回时为估算值)
+		select {
+		case dataChan <- model.LLMChunk{Usage: usage}:
+		case <-ctx.Done():
+		}
+	}()
+	return dataChan, errChan
+}
+
+/*
+查询用量报表, 按服务商和模型汇总, 同时返回当前的日/月配额使用情况
+*/
+func GetLLMUsageReport(caller model.LLMCaller, from, to time.Time) (model.LLMUsageReport, error) {
+	report := model.LLMUsageReport{From: from, To: to, Models: []model.LLMModelUsage{}}
+	err := db.DB.Model(&model.LLMUsageRecord{}).
+		Select("provider, model, COUNT(*) AS requests, SUM(promptTokens) AS prompt_tokens, SUM(completionTokens) AS completion_tokens, SUM(totalTokens) AS total_tokens").
+		Where("userId = ? AND createdAt >= ? AND createdAt < ?", caller.UserID, from, to).
+		Group("provider, model").
+		Order("total_tokens DESC").
+		Scan(&report.Models).Error
+	if err != nil {
+		return report, fmt.Errorf("查询LLM用量失败: %v", err)
+	}
+	report.Daily, report.Monthly = llmQuotaStatus(caller)
+	return report, nil
+}
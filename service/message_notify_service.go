@@ -0,0 +1,222 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gin/config"
+	"gin/model"
+	"html"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var errWebhookAddressBlocked = errors.New("webhook 地址指向内网或保留地址")
+
+// validateNotifyTargets 校验消息的回执通知目标
+// 回执邮箱只能是已登录发送者账号的邮箱, 避免匿名用户借回执向任意地址发信
+func validateNotifyTargets(msg *model.EncryptionMessage, senderUsername string) error {
+	msg.NotifyEmail = strings.TrimSpace(msg.NotifyEmail)
+	msg.NotifyWebhook = strings.TrimSpace(msg.NotifyWebhook)
+	if msg.NotifyEmail != "" {
+		if senderUsername == "" {
+			return fmt.Errorf("%w: 回执邮箱需要登录后使用", ErrInvalidEncryptionOptions)
+		}
+		sender, err := findUserByUsername(senderUsername)
+		if err != nil {
+			return fmt.Errorf("%w: 查询发送者失败: %v", ErrInvalidEncryptionOptions, err)
+		}
+		if normalizeEmail(sender.Email) != normalizeEmail(msg.NotifyEmail) {
+			return fmt.Errorf("%w: 回执邮箱只能是当前账号的邮箱", ErrInvalidEncryptionOptions)
+		}
+		if err := CheckEmailPolicy(msg.NotifyEmail); err != nil {
+			return fmt.Errorf("%w: 回执邮箱不可用: %v", ErrInvalidEncryptionOptions, err)
+		}
+	}
+	if msg.NotifyWebhook != "" {
+		if err := validateWebhookURL(msg.NotifyWebhook); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidEncryptionOptions, err)
+		}
+	}
+	return nil
+}
+
+// validateWebhookURL 只允许 http/https, 并拒绝直接使用内网地址
+// 域名解析结果在真正发起连接时由 webhookDialControl 再次检查, 防止DNS重绑定
+func validateWebhookURL(raw string) error {
+	if len(raw) > 1024 {
+		return errors.New("webhook 地址过长")
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return errors.New("webhook 地址必须是 http/https 链接")
+	}
+	if u.User != nil {
+		return errors.New("webhook 地址不能包含账号信息")
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil && isBlockedWebhookIP(ip) {
+		return errWebhookAddressBlocked
+	}
+	if strings.EqualFold(u.Hostname(), "localhost") {
+		return errWebhookAddressBlocked
+	}
+	return nil
+}
+
+func isBlockedWebhookIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() ||
+		// 100.64.0.0/10 运营商级NAT
+		(ip.To4() != nil && ip.To4()[0] == 100 && ip.To4()[1]&0xc0 == 64)
+}
+
+// webhookDialControl 在建立连接前检查实际连接的IP
+func webhookDialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || isBlockedWebhookIP(ip) {
+		return errWebhookAddressBlocked
+	}
+	return nil
+}
+
+var webhookClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy: nil,
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: webhookDialControl,
+		}).DialContext,
+		TLSHandshakeTimeout: 5 * time.Second,
+	},
+	// 不跟随重定向, 避免被重定向到内网地址
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// approximateLocation 通过IP获取大致位置, 失败时返回空字符串
+func approximateLocation(clientIP string) string {
+	if clientIP == "" {
+		return ""
+	}
+	info, err := GetIPInfo(clientIP)
+	if err != nil || info == nil {
+		return ""
+	}
+	var parts []string
+	for _, part := range []string{info.Country, info.RegionName, info.City} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+/*
+异步发送消息回执
+只发送事件、时间、大致位置等元数据, 不包含消息内容和查看者IP
+*/
+func NotifyMessageEvent(msg model.EncryptionMessage, event, clientIP string, remainingViews int) {
+	if msg.NotifyEmail == "" && msg.NotifyWebhook == "" {
+		return
+	}
+	go func() {
+		notification := model.MessageNotification{
+			Event:          event,
+			MessageUUID:    msg.UUID,
+			OccurredAt:     time.Now(),
+			Location:       approximateLocation(clientIP),
+			RemainingViews: remainingViews,
+		}
+		if msg.NotifyEmail != "" {
+			if err := sendNotificationEmail(msg.NotifyEmail, notification); err != nil {
+				fmt.Println("发送消息回执邮件失败:", err)
+			}
+		}
+		if msg.NotifyWebhook != "" {
+			if err := sendNotificationWebhook(msg.NotifyWebhook, notification); err != nil {
+				fmt.Println("发送消息回执Webhook失败:", err)
+			}
+		}
+	}()
+}
+
+func notificationEventText(event string) string {
+	switch event {
+	case model.MessageEventRetrieved:
+		return "已被查看"
+	case model.MessageEventExpiredUnread:
+		return "已过期，且未被查看"
+	case model.MessageEventDestroyed:
+		return "因访问口令错误次数过多已被销毁"
+	}
+	return event
+}
+
+func sendNotificationEmail(to string, notification model.MessageNotification) error {
+	location := notification.Location
+	if location == "" {
+		location = "未知"
+	}
+	body := `<h1>您好：</h1>
+<p style="font-size: 16px;color:#000;">您发送的加密消息 <b>` + html.EscapeString(notification.MessageUUID) + `</b> ` +
+		notificationEventText(notification.Event) + `。</p>
+<p>时间：` + notification.OccurredAt.Format("2006-01-02 15:04:05") + `</p>`
+	if notification.Event == model.MessageEventRetrieved {
+		body += fmt.Sprintf(`<p>查看者大致位置：%s</p><p>剩余查看次数：%d</p>`, html.EscapeString(location), notification.RemainingViews)
+	}
+	// 回执由查看者触发, 计入全站每日发送配额
+	if err := consumeEmailQuotas(""); err != nil {
+		return err
+	}
+	return DeliverMail(OutgoingMail{
+		To:       to,
+		Subject:  "加密消息回执",
+		HTMLBody: body,
+	})
+}
+
+func sendNotificationWebhook(target string, notification model.MessageNotification) error {
+	payload, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), config.NotifyWebhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "SouthAki-Notify/1.0")
+
+	// 只使用专用的签名密钥, 不能退回 SECRET_KEY: 接收方需要持有密钥才能验签, 且 SECRET_KEY 还用于签发退订链接
+	if secret := config.NotifyWebhookSecret; secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(payload)
+		req.Header.Set("X-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook 返回状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
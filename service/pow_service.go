@@ -0,0 +1,126 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"gin/config"
+	"gin/db"
+	"gin/model"
+	"gin/utils"
+	"math/bits"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// PowKeyPrefix 工作量证明挑战ID前缀, 用于和图形验证码的key区分
+const PowKeyPrefix = "pow_"
+
+type powChallengeEntry struct {
+	Nonce      string `json:"nonce"`
+	Difficulty int    `json:"difficulty"`
+}
+
+// powDifficulty 根据IP在统计窗口内的请求次数计算难度
+// clientIP 需要是经过可信代理解析的地址, IPv6 按 /64 前缀计数
+func powDifficulty(clientIP string) (int, error) {
+	key := "pow:ip:" + utils.ClientIPKey(clientIP)
+	count, err := db.RDB.Incr(db.Ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if count == 1 {
+		if err := db.RDB.Expire(db.Ctx, key, config.PowWindow).Err(); err != nil {
+			return 0, err
+		}
+	}
+
+	difficulty := config.PowBaseDifficulty
+	if config.PowDifficultyStep > 0 {
+		difficulty += int(count-1) / config.PowDifficultyStep
+	}
+	if difficulty > config.PowMaxDifficulty {
+		difficulty = config.PowMaxDifficulty
+	}
+	return difficulty, nil
+}
+
+/*
+签发工作量证明挑战
+*/
+func IssuePowChallenge(clientIP string) (model.PowChallenge, error) {
+	difficulty, err := powDifficulty(clientIP)
+	if err != nil {
+		return model.PowChallenge{}, fmt.Errorf("计算挑战难度失败: %v", err)
+	}
+
+	nonceBytes := make([]byte, 16)
+	if _, err := rand.Read(nonceBytes); err != nil {
+		return model.PowChallenge{}, fmt.Errorf("生成随机数失败: %v", err)
+	}
+	entry := powChallengeEntry{Nonce: hex.EncodeToString(nonceBytes), Difficulty: difficulty}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return model.PowChallenge{}, err
+	}
+
+	challengeID := PowKeyPrefix + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if err := db.RDB.Set(db.Ctx, "pow:challenge:"+challengeID, data, config.PowChallengeTTL).Err(); err != nil {
+		return model.PowChallenge{}, fmt.Errorf("保存挑战失败: %v", err)
+	}
+	fmt.Printf("签发PoW挑战: %s, IP: %s, 难度: %d\n", challengeID, clientIP, difficulty)
+
+	return model.PowChallenge{
+		ChallengeID: challengeID,
+		Nonce:       entry.Nonce,
+		Difficulty:  difficulty,
+		Algorithm:   "sha256",
+		ExpiresIn:   int(config.PowChallengeTTL.Seconds()),
+	}, nil
+}
+
+/*
+校验工作量证明, 挑战只能使用一次
+*/
+func VerifyPowSolution(challengeID, suffix string) bool {
+	if suffix == "" || len(suffix) > 128 {
+		return false
+	}
+	data, err := db.RDB.GetDel(db.Ctx, "pow:challenge:"+challengeID).Result()
+	if err != nil {
+		return false
+	}
+	var entry powChallengeEntry
+	if err := json.Unmarshal([]byte(data), &entry); err != nil {
+		return false
+	}
+
+	hash := sha256.Sum256([]byte(entry.Nonce + suffix))
+	return leadingZeroBits(hash[:]) >= entry.Difficulty
+}
+
+func leadingZeroBits(data []byte) int {
+	count := 0
+	for _, b := range data {
+		if b == 0 {
+			count += 8
+			continue
+		}
+		return count + bits.LeadingZeros8(b)
+	}
+	return count
+}
+
+/*
+统一的人机验证入口
+humanCheckKey 以 pow_ 开头时按工作量证明校验, 否则按图形验证码校验
+*/
+func VerifyHumanCheck(key, code string) bool {
+	if strings.HasPrefix(key, PowKeyPrefix) {
+		return VerifyPowSolution(key, code)
+	}
+	return VerifyCaptcha(key, code)
+}
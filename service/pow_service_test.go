@@ -0,0 +1,27 @@
+package service
+
+import "testing"
+
+func TestLeadingZeroBits(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"空输入", nil, 0},
+		{"首位为1", []byte{0x80, 0x00}, 0},
+		{"首字节部分为0", []byte{0x01, 0xff}, 7},
+		{"首字节中间", []byte{0x10}, 3},
+		{"整字节为0后跨字节", []byte{0x00, 0x40}, 9},
+		{"多个整字节为0", []byte{0x00, 0x00, 0x0f}, 20},
+		{"全部为0", []byte{0x00, 0x00}, 16},
+		{"后续字节不影响结果", []byte{0x00, 0x01, 0x00, 0x00}, 15},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := leadingZeroBits(tt.data); got != tt.want {
+				t.Errorf("leadingZeroBits(%x) = %d, want %d", tt.data, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package service
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// ragDocument 从静态资源中提取出的一篇文档
+type ragDocument struct {
+	Source string
+	Title  string
+	Text   string
+}
+
+// 单个HTML文件最大读取字节数
+const ragMaxDocumentSize = 20 * 1024 * 1024
+
+// 这些元素之后换行, 保留段落结构
+var ragBlockElements = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "tr": true, "section": true, "article": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "blockquote": true, "pre": true,
+}
+
+// extractHTMLText 提取HTML正文和标题, 跳过脚本和样式
+func extractHTMLText(r io.Reader) (string, string, error) {
+	root, err := html.Parse(io.LimitReader(r, ragMaxDocumentSize))
+	if err != nil {
+		return "", "", fmt.Errorf("解析HTML失败: %v", err)
+	}
+	var title string
+	var text strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "script", "style", "noscript", "template", "svg":
+				return
+			case "title":
+				if n.FirstChild != nil && title == "" {
+					title = strings.TrimSpace(n.FirstChild.Data)
+				}
+				return
+			}
+		}
+		if n.Type == html.TextNode {
+			if value := strings.TrimSpace(n.Data); value != "" {
+				text.WriteString(value)
+				text.WriteString(" ")
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+		if n.Type == html.ElementNode && ragBlockElements[n.Data] {
+			text.WriteString("\n")
+		}
+	}
+	walk(root)
+	return title, normalizeRAGText(text.String()), nil
+}
+
+// normalizeRAGText 合并多余空白, 保留段落换行
+func normalizeRAGText(text string) string {
+	lines := strings.Split(text, "\n")
+	kept := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+type epubContainer struct {
+	Rootfiles []struct {
+		FullPath string `xml:"full-path,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+type epubPackage struct {
+	Title    string `xml:"metadata>title"`
+	Manifest []struct {
+		ID        string `xml:"id,attr"`
+		Href      string `xml:"href,attr"`
+		MediaType string `xml:"media-type,attr"`
+	} `xml:"manifest>item"`
+	Spine []struct {
+		IDRef string `xml:"idref,attr"`
+	} `xml:"spine>itemref"`
+}
+
+func readZipXML(files map[string]*zip.File, name string, v any) error {
+	file, ok := files[name]
+	if !ok {
+		return fmt.Errorf("缺少 %s", name)
+	}
+	rc, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(io.LimitReader(rc, ragMaxDocumentSize)).Decode(v)
+}
+
+// extractEPUB 按阅读顺序(spine)提取EPUB中每个章节文件的文本
+func extractEPUB(filePath, source string) ([]ragDocument, error) {
+	reader, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("打开EPUB失败: %v", err)
+	}
+	defer reader.Close()
+
+	files := make(map[string]*zip.File, len(reader.File))
+	for _, file := range reader.File {
+		files[file.Name] = file
+	}
+	var container epubContainer
+	if err := readZipXML(files, "META-INF/container.xml", &container); err != nil {
+		return nil, fmt.Errorf("读取EPUB目录失败: %v", err)
+	}
+	if len(container.Rootfiles) == 0 {
+		return nil, fmt.Errorf("EPUB缺少 rootfile")
+	}
+	opfPath := container.Rootfiles[0].FullPath
+	var pkg epubPackage
+	if err := readZipXML(files, opfPath, &pkg); err != nil {
+		return nil, fmt.Errorf("读取EPUB内容清单失败: %v", err)
+	}
+
+	hrefs := make(map[string]string, len(pkg.Manifest))
+	for _, item := range pkg.Manifest {
+		if strings.Contains(item.MediaType, "html") {
+			hrefs[item.ID] = item.Href
+		}
+	}
+	title := strings.TrimSpace(pkg.Title)
+	if title == "" {
+		title = strings.TrimSuffix(path.Base(source), path.Ext(source))
+	}
+
+	var documents []ragDocument
+	for _, ref := range pkg.Spine {
+		href, ok := hrefs[ref.IDRef]
+		if !ok {
+			continue
+		}
+		name := path.Join(path.Dir(opfPath), href)
+		file, ok := files[name]
+		if !ok {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		_, text, err := extractHTMLText(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		if text == "" {
+			continue
+		}
+		documents = append(documents, ragDocument{Source: source + "#" + href, Title: title, Text: text})
+	}
+	return documents, nil
+}
+
+// chunkRAGText 按段落把文本切成不超过 size 个字符的片段, 相邻片段重叠 overlap 个字符
+func chunkRAGText(text string, size, overlap int) []string {
+	if size <= 0 {
+		size = 600
+	}
+	if overlap < 0 || overlap >= size {
+		overlap = 0
+	}
+	var chunks []string
+	var current []rune
+	flush := func() {
+		if chunk := strings.TrimSpace(string(current)); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		if overlap > 0 && len(current) > overlap {
+			current = append([]rune(nil), current[len(current)-overlap:]...)
+		} else {
+			current = current[:0]
+		}
+	}
+	for _, paragraph := range strings.Split(text, "\n") {
+		runes := []rune(strings.TrimSpace(paragraph))
+		if len(runes) == 0 {
+			continue
+		}
+		// 超长段落按句子边界或固定长度切开
+		for len(runes) > 0 {
+			room := size - len(current)
+			if len(runes)+1 <= room {
+				current = append(current, runes...)
+				current = append(current, '\n')
+				break
+			}
+			if len(current) > overlap {
+				flush()
+				continue
+			}
+			cut := ragSentenceCut(runes, size-len(current))
+			current = append(current, runes[:cut]...)
+			runes = runes[cut:]
+			flush()
+		}
+	}
+	if len(current) > overlap || (len(chunks) == 0 && len(current) > 0) {
+		overlap = 0
+		flush()
+	}
+	return chunks
+}
+
+// ragSentenceCut 在 limit 之内找最后一个句末标点, 找不到时直接在 limit 处切开
+func ragSentenceCut(runes []rune, limit int) int {
+	if limit >= len(runes) {
+		return len(runes)
+	}
+	if limit <= 0 {
+		limit = 1
+	}
+	for i := limit - 1; i > limit/2; i-- {
+		switch runes[i] {
+		case '。', '！', '？', '…', '.', '!', '?', ';', '；':
+			return i + 1
+		}
+		if unicode.IsSpace(runes[i]) {
+			return i + 1
+		}
+	}
+	return limit
+}
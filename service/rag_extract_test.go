@@ -0,0 +1,43 @@
+package service
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChunkRAGText(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		size    int
+		overlap int
+		want    []string
+	}{
+		{"空文本", "", 10, 0, nil},
+		{"只有空行", "\n  \n\t\n", 10, 0, nil},
+		{"短文本为一个片段", "hello", 10, 0, []string{"hello"}},
+		{"多个段落合并", "ab\ncd", 10, 0, []string{"ab\ncd"}},
+		{"段落加换行正好填满", "abcd\nefgh", 10, 0, []string{"abcd\nefgh"}},
+		{"放不下时另起片段", "abcde\nfghij", 10, 0, []string{"abcde", "fghij"}},
+		{"超长段落按固定长度切开", "abcdefghijklmnopqrstuvwxyz", 10, 0, []string{"abcdefghij", "klmnopqrst", "uvwxyz"}},
+		{"超长段落优先在句末切开", "一二三四五六七。八九十", 10, 0, []string{"一二三四五六七。", "八九十"}},
+		{"超长段落在空白处切开", "aaaaaaa bbbbbbb", 10, 0, []string{"aaaaaaa", "bbbbbbb"}},
+		{"相邻片段重叠", "abcdefghijklmnop", 10, 3, []string{"abcdefghij", "hijklmnop"}},
+		{"重叠不小于片段大小时忽略", "abcdefghijklmnop", 10, 10, []string{"abcdefghij", "klmnop"}},
+		{"默认片段大小", strings.Repeat("字", 700), 0, 0, []string{strings.Repeat("字", 600), strings.Repeat("字", 100)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := chunkRAGText(tt.text, tt.size, tt.overlap)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chunkRAGText(%q, %d, %d) = %q, want %q", tt.text, tt.size, tt.overlap, got, tt.want)
+			}
+			for _, chunk := range got {
+				if n := len([]rune(chunk)); tt.size > 0 && n > tt.size {
+					t.Errorf("片段长度 %d 超过 %d: %q", n, tt.size, chunk)
+				}
+			}
+		})
+	}
+}
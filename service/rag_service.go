@@ -0,0 +1,429 @@
+package service
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"gin/config"
+	"gin/model"
+	"hash/fnv"
+	"io/fs"
+	"math"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+var (
+	ErrRAGIndexEmpty    = errors.New("检索索引为空, 请先重建索引")
+	ErrRAGIndexBuilding = errors.New("检索索引正在重建")
+	ErrRAGIndexOutdated = errors.New("检索索引与当前向量化配置不一致, 请重建索引")
+)
+
+const (
+	ragLocalDimensions = 512 // 本地哈希向量维度
+	ragEmbedBatchSize  = 32  // 每次向量化请求的片段数
+	ragSnippetLength   = 120 // 引用摘要长度
+)
+
+// ragIndex 保存到磁盘的索引
+type ragIndex struct {
+	Embedder  string
+	Documents int
+	BuiltAt   time.Time
+	Chunks    []model.RAGChunk
+}
+
+var (
+	ragIndexMu    sync.RWMutex
+	ragCurrent    *ragIndex
+	ragBuildMu    sync.Mutex
+	ragIsBuilding bool
+)
+
+// ragEmbedder 把文本转换为向量
+type ragEmbedder struct {
+	name  string
+	embed func(ctx context.Context, inputs []string) ([][]float32, error)
+}
+
+// resolveRAGEmbedder 未配置向量化服务商时使用本地哈希向量
+func resolveRAGEmbedder() (ragEmbedder, error) {
+	if config.RAGEmbeddingProvider == "" {
+		return ragEmbedder{name: "local", embed: localRAGEmbed}, nil
+	}
+	provider, err := findLLMProvider(config.RAGEmbeddingProvider)
+	if err != nil {
+		return ragEmbedder{}, err
+	}
+	embeddingProvider, ok := provider.(LLMEmbeddingProvider)
+	if !ok {
+		return ragEmbedder{}, fmt.Errorf("LLM服务商 %s 不支持向量化", provider.Name())
+	}
+	if config.RAGEmbeddingModel == "" {
+		return ragEmbedder{}, errors.New("未配置向量化模型 RAG_EMBEDDING_MODEL")
+	}
+	return ragEmbedder{
+		name: provider.Name() + "/" + config.RAGEmbeddingModel,
+		embed: func(ctx context.Context, inputs []string) ([][]float32, error) {
+			return embeddingProvider.Embed(ctx, config.RAGEmbeddingModel, inputs)
+		},
+	}, nil
+}
+
+// localRAGEmbed 本地哈希向量: 中文取单字和相邻两字, 其他文字取小写单词, 哈希到固定维度
+func localRAGEmbed(_ context.Context, inputs []string) ([][]float32, error) {
+	vectors := make([][]float32, len(inputs))
+	for i, input := range inputs {
+		vector := make([]float32, ragLocalDimensions)
+		add := func(token string) {
+			h := fnv.New32a()
+			h.Write([]byte(token))
+			vector[h.Sum32()%ragLocalDimensions]++
+		}
+		var word []rune
+		var prev rune
+		flushWord := func() {
+			if len(word) > 0 {
+				add(string(word))
+				word = word[:0]
+			}
+		}
+		for _, r := range strings.ToLower(input) {
+			switch {
+			case unicode.Is(unicode.Han, r):
+				flushWord()
+				add(string(r))
+				if prev != 0 {
+					add(string([]rune{prev, r}))
+				}
+				prev = r
+				continue
+			case unicode.IsLetter(r) || unicode.IsDigit(r):
+				word = append(word, r)
+			default:
+				flushWord()
+			}
+			prev = 0
+		}
+		flushWord()
+		vectors[i] = vector
+	}
+	return vectors, nil
+}
+
+// normalizeRAGVector 归一化后余弦相似度即为点积
+func normalizeRAGVector(vector []float32) {
+	var sum float64
+	for _, v := range vector {
+		sum += float64(v) * float64(v)
+	}
+	if sum == 0 {
+		return
+	}
+	norm := float32(math.Sqrt(sum))
+	for i := range vector {
+		vector[i] /= norm
+	}
+}
+
+/*
+启动时加载磁盘上的检索索引, 索引不存在时不报错
+*/
+func InitRAGIndex() error {
+	file, err := os.Open(config.RAGIndexPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		fmt.Println("检索索引不存在, 可调用 /api/admin/rag/reindex 建立索引")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("打开检索索引失败: %v", err)
+	}
+	defer file.Close()
+
+	var index ragIndex
+	if err := gob.NewDecoder(file).Decode(&index); err != nil {
+		return fmt.Errorf("读取检索索引失败: %v", err)
+	}
+	ragIndexMu.Lock()
+	ragCurrent = &index
+	ragIndexMu.Unlock()
+	fmt.Println("检索索引已加载 - 向量化:", index.Embedder, "文档:", index.Documents, "片段:", len(index.Chunks))
+	return nil
+}
+
+// collectRAGDocuments 遍历静态资源目录, 提取 EPUB、HTML 和纯文本文件
+func collectRAGDocuments(root string) ([]ragDocument, int, error) {
+	var documents []ragDocument
+	files := 0
+	err := filepath.WalkDir(root, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, filePath)
+		if err != nil {
+			return err
+		}
+		source := filepath.ToSlash(rel)
+
+		var extracted []ragDocument
+		switch strings.ToLower(filepath.Ext(filePath)) {
+		case ".epub":
+			extracted, err = extractEPUB(filePath, source)
+		case ".html", ".htm", ".xhtml":
+			extracted, err = extractHTMLFile(filePath, source)
+		case ".txt", ".md":
+			extracted, err = extractTextFile(filePath, source)
+		default:
+			return nil
+		}
+		if err != nil {
+			// 单个文件解析失败不影响其他文件
+			fmt.Println("提取文档失败:", source, err)
+			return nil
+		}
+		if len(extracted) > 0 {
+			files++
+			documents = append(documents, extracted...)
+		}
+		return nil
+	})
+	return documents, files, err
+}
+
+func extractHTMLFile(filePath, source string) ([]ragDocument, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	title, text, err := extractHTMLText(file)
+	if err != nil || text == "" {
+		return nil, err
+	}
+	if title == "" {
+		title = path.Base(source)
+	}
+	return []ragDocument{{Source: source, Title: title, Text: text}}, nil
+}
+
+func extractTextFile(filePath, source string) ([]ragDocument, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	text := normalizeRAGText(string(data))
+	if text == "" {
+		return nil, nil
+	}
+	return []ragDocument{{Source: source, Title: path.Base(source), Text: text}}, nil
+}
+
+/*
+重建检索索引
+提取静态资源中的文本, 切分后向量化并保存到索引文件, 完成后替换内存中的索引
+*/
+func RebuildRAGIndex(ctx context.Context) (model.RAGIndexStatus, error) {
+	ragBuildMu.Lock()
+	if ragIsBuilding {
+		ragBuildMu.Unlock()
+		return model.RAGIndexStatus{}, ErrRAGIndexBuilding
+	}
+	ragIsBuilding = true
+	ragBuildMu.Unlock()
+	defer func() {
+		ragBuildMu.Lock()
+		ragIsBuilding = false
+		ragBuildMu.Unlock()
+	}()
+
+	embedder, err := resolveRAGEmbedder()
+	if err != nil {
+		return model.RAGIndexStatus{}, err
+	}
+	documents, files, err := collectRAGDocuments(config.RAGSourceDir)
+	if err != nil {
+		return model.RAGIndexStatus{}, fmt.Errorf("读取静态资源失败: %v", err)
+	}
+
+	var chunks []model.RAGChunk
+	for _, document := range documents {
+		for _, text := range chunkRAGText(document.Text, config.RAGChunkSize, config.RAGChunkOverlap) {
+			chunks = append(chunks, model.RAGChunk{Source: document.Source, Title: document.Title, Text: text})
+		}
+	}
+	for start := 0; start < len(chunks); start += ragEmbedBatchSize {
+		end := min(start+ragEmbedBatchSize, len(chunks))
+		inputs := make([]string, 0, end-start)
+		for _, chunk := range chunks[start:end] {
+			inputs = append(inputs, chunk.Title+"\n"+chunk.Text)
+		}
+		vectors, err := embedder.embed(ctx, inputs)
+		if err != nil {
+			return model.RAGIndexStatus{}, fmt.Errorf("向量化失败: %v", err)
+		}
+		if len(vectors) != len(inputs) {
+			return model.RAGIndexStatus{}, errors.New("向量化结果数量与输入不一致")
+		}
+		for i, vector := range vectors {
+			normalizeRAGVector(vector)
+			chunks[start+i].Vector = vector
+		}
+	}
+
+	index := &ragIndex{Embedder: embedder.name, Documents: files, BuiltAt: time.Now(), Chunks: chunks}
+	if err := saveRAGIndex(index); err != nil {
+		return model.RAGIndexStatus{}, err
+	}
+	ragIndexMu.Lock()
+	ragCurrent = index
+	ragIndexMu.Unlock()
+	fmt.Println("检索索引重建完成 - 向量化:", index.Embedder, "文档:", files, "片段:", len(chunks))
+	return GetRAGIndexStatus(), nil
+}
+
+// saveRAGIndex 先写临时文件再重命名, 避免写入中断损坏索引
+func saveRAGIndex(index *ragIndex) error {
+	if err := os.MkdirAll(filepath.Dir(config.RAGIndexPath), 0o755); err != nil {
+		return fmt.Errorf("创建索引目录失败: %v", err)
+	}
+	tmpPath := config.RAGIndexPath + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("创建索引文件失败: %v", err)
+	}
+	if err := gob.NewEncoder(file).Encode(index); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("写入索引文件失败: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("写入索引文件失败: %v", err)
+	}
+	if err := os.Rename(tmpPath, config.RAGIndexPath); err != nil {
+		return fmt.Errorf("保存索引文件失败: %v", err)
+	}
+	return nil
+}
+
+/*
+查询检索索引状态
+*/
+func GetRAGIndexStatus() model.RAGIndexStatus {
+	ragBuildMu.Lock()
+	status := model.RAGIndexStatus{Building: ragIsBuilding}
+	ragBuildMu.Unlock()
+
+	ragIndexMu.RLock()
+	defer ragIndexMu.RUnlock()
+	if ragCurrent != nil {
+		status.Embedder = ragCurrent.Embedder
+		status.Documents = ragCurrent.Documents
+		status.Chunks = len(ragCurrent.Chunks)
+		status.BuiltAt = ragCurrent.BuiltAt
+	}
+	return status
+}
+
+/*
+检索与问题最相关的片段, 返回引用信息和片段文本(与引用一一对应)
+*/
+func SearchRAG(ctx context.Context, query string, topK int) ([]model.RAGCitation, []string, error) {
+	ragIndexMu.RLock()
+	index := ragCurrent
+	ragIndexMu.RUnlock()
+	if index == nil || len(index.Chunks) == 0 {
+		return nil, nil, ErrRAGIndexEmpty
+	}
+	embedder, err := resolveRAGEmbedder()
+	if err != nil {
+		return nil, nil, err
+	}
+	if embedder.name != index.Embedder {
+		return nil, nil, ErrRAGIndexOutdated
+	}
+	vectors, err := embedder.embed(ctx, []string{query})
+	if err != nil {
+		return nil, nil, fmt.Errorf("向量化失败: %v", err)
+	}
+	if len(vectors) != 1 {
+		return nil, nil, errors.New("向量化结果数量与输入不一致")
+	}
+	queryVector := vectors[0]
+	normalizeRAGVector(queryVector)
+
+	type scored struct {
+		chunk *model.RAGChunk
+		score float64
+	}
+	results := make([]scored, 0, len(index.Chunks))
+	for i := range index.Chunks {
+		chunk := &index.Chunks[i]
+		if len(chunk.Vector) != len(queryVector) {
+			continue
+		}
+		var score float64
+		for j, v := range chunk.Vector {
+			score += float64(v) * float64(queryVector[j])
+		}
+		if score > 0 {
+			results = append(results, scored{chunk: chunk, score: score})
+		}
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].score > results[j].score })
+	if topK <= 0 {
+		topK = config.RAGTopK
+	}
+	results = results[:min(topK, len(results))]
+
+	citations := make([]model.RAGCitation, 0, len(results))
+	texts := make([]string, 0, len(results))
+	for i, result := range results {
+		file, _, _ := strings.Cut(result.chunk.Source, "#")
+		snippet := []rune(result.chunk.Text)
+		if len(snippet) > ragSnippetLength {
+			snippet = append(snippet[:ragSnippetLength], '…')
+		}
+		citations = append(citations, model.RAGCitation{
+			Index:   i + 1,
+			Source:  result.chunk.Source,
+			Title:   result.chunk.Title,
+			URL:     ragStaticURL(file),
+			Score:   math.Round(result.score*1000) / 1000,
+			Snippet: string(snippet),
+		})
+		texts = append(texts, result.chunk.Text)
+	}
+	return citations, texts, nil
+}
+
+// ragStaticURL 来源文件对应的静态资源地址
+func ragStaticURL(source string) string {
+	segments := strings.Split(source, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return "/static/" + strings.Join(segments, "/")
+}
+
+// buildRAGMessage 把检索结果拼成系统消息, 要求模型以 [n] 标注引用
+func buildRAGMessage(citations []model.RAGCitation, texts []string) model.Message {
+	var content strings.Builder
+	content.WriteString("以下是从站内资料中检索到的参考内容。回答时优先依据这些内容, 使用某段内容时在句末用 [编号] 标注来源; ")
+	content.WriteString("参考内容与问题无关或没有相关信息时如实说明, 不要编造引用。\n")
+	for i, citation := range citations {
+		fmt.Fprintf(&content, "\n[%d] %s (%s)\n%s\n", citation.Index, citation.Title, citation.Source, texts[i])
+	}
+	return model.Message{Role: model.ChatRoleSystem, Content: content.String()}
+}
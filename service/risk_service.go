@@ -0,0 +1,191 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"gin/config"
+	"gin/db"
+	"gin/model"
+	"gin/utils"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrHumanCheckRequired = errors.New("当前请求需要完成人机验证")
+	ErrEmailCodeRequired  = errors.New("当前请求需要邮箱验证码")
+)
+
+// 达到该失败次数后IP被标记为不良信誉
+const riskBadReputationFailures = 10
+
+// 常见脚本/爬虫客户端的User-Agent特征
+var riskBotUserAgents = []string{
+	"curl", "wget", "python", "go-http-client", "java/", "okhttp", "httpclient",
+	"scrapy", "headless", "phantomjs", "selenium", "puppeteer", "postman", "bot", "spider",
+}
+
+func riskIdentity(identity string) string {
+	return strings.ToLower(strings.TrimSpace(identity))
+}
+
+// riskIP 计数使用的IP标识, client.IP 来自可信代理解析后的地址, IPv6 按 /64 前缀计数
+func riskIP(client model.ClientMeta) string {
+	return utils.ClientIPKey(client.IP)
+}
+
+// TrackRiskRequest 记录一次请求, 用于计算请求速率
+func TrackRiskRequest(action string, client model.ClientMeta) {
+	key := "risk:velocity:" + action + ":" + riskIP(client)
+	count, err := db.RDB.Incr(db.Ctx, key).Result()
+	if err != nil {
+		fmt.Println("记录请求速率失败:", err)
+		return
+	}
+	if count == 1 {
+		db.RDB.Expire(db.Ctx, key, config.RiskVelocityWindow)
+	}
+}
+
+// RecordRiskFailure 记录一次验证失败(验证码错误、密码错误等)
+func RecordRiskFailure(identity string, client model.ClientMeta) {
+	ipKey := "risk:fail:ip:" + riskIP(client)
+	count, err := db.RDB.Incr(db.Ctx, ipKey).Result()
+	if err != nil {
+		fmt.Println("记录失败次数失败:", err)
+		return
+	}
+	if count == 1 {
+		db.RDB.Expire(db.Ctx, ipKey, config.RiskFailureWindow)
+	}
+	if count >= riskBadReputationFailures {
+		db.RDB.Set(db.Ctx, "risk:bad:ip:"+riskIP(client), count, config.RiskReputationTTL)
+	}
+
+	if identity = riskIdentity(identity); identity != "" {
+		idKey := "risk:fail:id:" + identity
+		if n, err := db.RDB.Incr(db.Ctx, idKey).Result(); err == nil && n == 1 {
+			db.RDB.Expire(db.Ctx, idKey, config.RiskFailureWindow)
+		}
+	}
+}
+
+// RecordRiskSuccess 验证成功后清除该账号的失败记录
+func RecordRiskSuccess(identity string) {
+	if identity = riskIdentity(identity); identity != "" {
+		db.RDB.Del(db.Ctx, "risk:fail:id:"+identity)
+	}
+}
+
+// userAgentRisk 检查User-Agent异常, 返回风险分和原因
+func userAgentRisk(userAgent string) (int, string) {
+	ua := strings.ToLower(strings.TrimSpace(userAgent))
+	if ua == "" {
+		return 35, "缺少User-Agent"
+	}
+	for _, pattern := range riskBotUserAgents {
+		if strings.Contains(ua, pattern) {
+			return 35, "User-Agent疑似自动化工具"
+		}
+	}
+	if len(ua) < 20 || !strings.HasPrefix(ua, "mozilla/") {
+		return 20, "User-Agent格式异常"
+	}
+	return 0, ""
+}
+
+func riskCounter(key string) (int, error) {
+	value, err := db.RDB.Get(db.Ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(value)
+}
+
+/*
+评估请求风险
+根据IP信誉、失败历史、User-Agent异常和请求速率计算风险分, 并给出需要的验证步骤
+*/
+func AssessRisk(action, identity string, client model.ClientMeta) model.RiskAssessment {
+	assessment := model.RiskAssessment{Reasons: []string{}}
+	add := func(score int, reason string) {
+		assessment.Score += score
+		assessment.Reasons = append(assessment.Reasons, reason)
+	}
+
+	ipFailures, err1 := riskCounter("risk:fail:ip:" + riskIP(client))
+	badReputation, err2 := db.RDB.Exists(db.Ctx, "risk:bad:ip:"+riskIP(client)).Result()
+	velocity, err3 := riskCounter("risk:velocity:" + action + ":" + riskIP(client))
+	idFailures := 0
+	var err4 error
+	if id := riskIdentity(identity); id != "" {
+		idFailures, err4 = riskCounter("risk:fail:id:" + id)
+	}
+	if err := errors.Join(err1, err2, err3, err4); err != nil {
+		// 无法获取风险数据时按高风险处理
+		fmt.Println("读取风险数据失败:", err)
+		add(100, "风险数据不可用")
+	}
+
+	if badReputation > 0 {
+		add(40, "IP信誉不良")
+	}
+	if ipFailures > 0 {
+		add(min(ipFailures*8, 40), fmt.Sprintf("IP近期失败%d次", ipFailures))
+	}
+	if idFailures > 0 {
+		add(min(idFailures*10, 40), fmt.Sprintf("账号近期失败%d次", idFailures))
+	}
+	if score, reason := userAgentRisk(client.UserAgent); score > 0 {
+		add(score, reason)
+	}
+	if config.RiskVelocityLimit > 0 && velocity > config.RiskVelocityLimit {
+		add(min((velocity-config.RiskVelocityLimit)*5+20, 50), "请求过于频繁")
+	}
+
+	assessment.Score = min(assessment.Score, 100)
+	switch {
+	case assessment.Score >= 70:
+		assessment.Level = model.RiskLevelHigh
+	case assessment.Score >= 30:
+		assessment.Level = model.RiskLevelMedium
+	default:
+		assessment.Level = model.RiskLevelLow
+	}
+	assessment.RequireHumanCheck = assessment.Score >= config.RiskHumanCheckThreshold
+	// 重置密码始终需要邮箱验证码证明邮箱归属, 只有注册场景按风险决定
+	switch action {
+	case model.RiskActionResetPassword:
+		assessment.RequireEmailCode = true
+	case model.RiskActionRegister:
+		assessment.RequireEmailCode = assessment.Score >= config.RiskEmailCodeThreshold
+	}
+	return assessment
+}
+
+// EvaluateRisk 记录本次请求并评估风险
+func EvaluateRisk(action, identity string, client model.ClientMeta) model.RiskAssessment {
+	TrackRiskRequest(action, client)
+	assessment := AssessRisk(action, identity, client)
+	fmt.Printf("风险评估 - 场景: %s, IP: %s, 分数: %d, 原因: %v\n", action, client.IP, assessment.Score, assessment.Reasons)
+	return assessment
+}
+
+// requireHumanCheck 风险要求时校验人机验证
+func requireHumanCheck(assessment model.RiskAssessment, key, code string) error {
+	if !assessment.RequireHumanCheck {
+		return nil
+	}
+	if key == "" || code == "" {
+		return ErrHumanCheckRequired
+	}
+	if !VerifyHumanCheck(key, code) {
+		return errors.New("图形验证码无效或已过期")
+	}
+	return nil
+}
@@ -2,7 +2,7 @@ package service
 
 import (
 	"fmt"
-	"gin/db"
+	"gin/config"
 	"log"
 	"time"
 
@@ -16,14 +16,14 @@ func InitScheduledTasks() error {
 	// 创建调度器（使用本地时区）
 	scheduler = gocron.NewScheduler(time.Local)
 
-	// 每隔 24 小时清空 encryptionmessage 表
-	_, err := scheduler.Every(24).Hours().Do(ClearEncryptionMessages)
+	// 定期清理已过期的加密消息
+	_, err := scheduler.Every(config.EncryptionCleanupInterval).Do(CleanupExpiredEncryptionMessagesTask)
 	if err != nil {
-		return fmt.Errorf("添加清空加密消息任务失败: %v", err)
+		return fmt.Errorf("添加清理过期加密消息任务失败: %v", err)
 	}
 
 	log.Println("✓ 定时任务已初始化")
-	log.Println("✓ 任务: 每隔 24 小时清空 encryptionmessage 表")
+	log.Printf("✓ 任务: 每隔 %s 清理过期的加密消息和附件\n", config.EncryptionCleanupInterval)
 
 	// 启动调度器
 	scheduler.StartAsync()
@@ -31,17 +31,24 @@ func InitScheduledTasks() error {
 	return nil
 }
 
-// ClearEncryptionMessages 清空加密消息表
-func ClearEncryptionMessages() {
-	log.Printf("[定时任务] 开始清空 encryptionmessage 表 (执行时间: %s)\n", time.Now().Format("2006-01-02 15:04:05"))
+// CleanupExpiredEncryptionMessagesTask 清理过期加密消息和附件
+func CleanupExpiredEncryptionMessagesTask() {
+	log.Printf("[定时任务] 开始清理过期加密消息 (执行时间: %s)\n", time.Now().Format("2006-01-02 15:04:05"))
 
-	// 删除所有数据
-	if err := db.DB.Exec("DELETE FROM encryptionmessage").Error; err != nil {
-		log.Printf("✗ 清空 encryptionmessage 表失败: %v\n", err)
+	deleted, err := CleanupExpiredEncryptionMessages()
+	if err != nil {
+		log.Printf("✗ 清理过期加密消息失败(已删除 %d 条): %v\n", deleted, err)
 		return
 	}
 
-	log.Printf("✓ 成功清空 encryptionmessage 表\n")
+	log.Printf("✓ 成功清理 %d 条过期加密消息\n", deleted)
+
+	removed, err := CleanupEncryptionAttachments()
+	if err != nil {
+		log.Printf("✗ 清理过期附件失败(已删除 %d 个): %v\n", removed, err)
+		return
+	}
+	log.Printf("✓ 成功清理 %d 个过期附件\n", removed)
 }
 
 // StopScheduler 停止调度器（优雅关闭）
@@ -0,0 +1,262 @@
+package service
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gin/config"
+	"gin/model"
+	"gin/utils"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"math/big"
+	mathrand "math/rand/v2"
+	"strconv"
+
+	"github.com/mojocn/base64Captcha"
+)
+
+const (
+	sliderWidth     = 300 // 背景图宽度
+	sliderHeight    = 150 // 背景图高度
+	sliderBlockSize = 42  // 拼图块主体边长
+	sliderTabRadius = 8   // 拼图块凸起半径
+
+	sliderMinTrackPoints = 5     // 轨迹最少采样点
+	sliderMinDurationMs  = 300   // 最短拖动时长
+	sliderMaxDurationMs  = 20000 // 最长拖动时长
+)
+
+// sliderPieceSize 拼图块外接矩形边长(主体 + 右侧/上方凸起)
+const sliderPieceSize = sliderBlockSize + sliderTabRadius
+
+/*
+生成滑块拼图验证码
+*/
+func GenerateSliderCaptcha(uuid string) (model.SliderCaptcha, error) {
+	minX := sliderPieceSize + 10
+	maxX := sliderWidth - sliderPieceSize - 10
+	x, err := secureRandInt(minX, maxX)
+	if err != nil {
+		return model.SliderCaptcha{}, err
+	}
+	y, err := secureRandInt(5, sliderHeight-sliderPieceSize-5)
+	if err != nil {
+		return model.SliderCaptcha{}, err
+	}
+
+	background := drawSliderBackground()
+	piece := image.NewRGBA(image.Rect(0, 0, sliderPieceSize, sliderPieceSize))
+	for py := 0; py < sliderPieceSize; py++ {
+		for px := 0; px < sliderPieceSize; px++ {
+			if !insideSliderPiece(px, py) {
+				continue
+			}
+			original := background.RGBAAt(x+px, y+py)
+			if onSliderPieceEdge(px, py) {
+				piece.SetRGBA(px, py, color.RGBA{255, 255, 255, 255})
+				background.SetRGBA(x+px, y+py, color.RGBA{255, 255, 255, 200})
+				continue
+			}
+			piece.SetRGBA(px, py, original)
+			// 缺口处压暗
+			background.SetRGBA(x+px, y+py, color.RGBA{original.R / 3, original.G / 3, original.B / 3, 255})
+		}
+	}
+
+	backgroundB64, err := encodePNGBase64(background)
+	if err != nil {
+		return model.SliderCaptcha{}, err
+	}
+	pieceB64, err := encodePNGBase64(piece)
+	if err != nil {
+		return model.SliderCaptcha{}, err
+	}
+
+	id := base64Captcha.RandomId()
+	if err := (utils.RedisStore{Kind: model.CaptchaTypeSlider}).Set(id, strconv.Itoa(x)); err != nil {
+		return model.SliderCaptcha{}, err
+	}
+
+	return model.SliderCaptcha{
+		ID:         id,
+		Background: backgroundB64,
+		Piece:      pieceB64,
+		PieceY:     y,
+		Width:      sliderWidth,
+		Height:     sliderHeight,
+		PieceSize:  sliderPieceSize,
+	}, nil
+}
+
+// insideSliderPiece 判断拼图块外接矩形内的点是否属于拼图块
+// 主体为正方形, 上方和右侧各有一个半圆形凸起
+func insideSliderPiece(px, py int) bool {
+	top := sliderTabRadius
+	if px < sliderBlockSize && py >= top {
+		return true
+	}
+	dx, dy := float64(px-sliderBlockSize/2), float64(py-top)
+	if dx*dx+dy*dy <= sliderTabRadius*sliderTabRadius {
+		return true
+	}
+	dx, dy = float64(px-sliderBlockSize), float64(py-top-sliderBlockSize/2)
+	return dx*dx+dy*dy <= sliderTabRadius*sliderTabRadius
+}
+
+func onSliderPieceEdge(px, py int) bool {
+	for _, d := range [][2]int{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+		nx, ny := px+d[0], py+d[1]
+		if nx < 0 || ny < 0 || nx >= sliderPieceSize || ny >= sliderPieceSize || !insideSliderPiece(nx, ny) {
+			return true
+		}
+	}
+	return false
+}
+
+// drawSliderBackground 生成随机渐变背景并叠加干扰图形
+func drawSliderBackground() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, sliderWidth, sliderHeight))
+	from := randomSliderColor()
+	to := randomSliderColor()
+	for y := 0; y < sliderHeight; y++ {
+		for x := 0; x < sliderWidth; x++ {
+			ratio := float64(x+y) / float64(sliderWidth+sliderHeight)
+			img.SetRGBA(x, y, color.RGBA{
+				R: uint8(float64(from.R)*(1-ratio) + float64(to.R)*ratio),
+				G: uint8(float64(from.G)*(1-ratio) + float64(to.G)*ratio),
+				B: uint8(float64(from.B)*(1-ratio) + float64(to.B)*ratio),
+				A: 255,
+			})
+		}
+	}
+
+	// 随机圆形, 让缺口边缘无法通过纯色背景直接识别
+	for i := 0; i < 18; i++ {
+		cx, cy := mathrand.IntN(sliderWidth), mathrand.IntN(sliderHeight)
+		radius := 6 + mathrand.IntN(28)
+		fill := randomSliderColor()
+		for y := max(cy-radius, 0); y < min(cy+radius, sliderHeight); y++ {
+			for x := max(cx-radius, 0); x < min(cx+radius, sliderWidth); x++ {
+				if (x-cx)*(x-cx)+(y-cy)*(y-cy) > radius*radius {
+					continue
+				}
+				old := img.RGBAAt(x, y)
+				img.SetRGBA(x, y, color.RGBA{
+					R: uint8((int(old.R) + int(fill.R)) / 2),
+					G: uint8((int(old.G) + int(fill.G)) / 2),
+					B: uint8((int(old.B) + int(fill.B)) / 2),
+					A: 255,
+				})
+			}
+		}
+	}
+	return img
+}
+
+func randomSliderColor() color.RGBA {
+	return color.RGBA{
+		R: uint8(60 + mathrand.IntN(180)),
+		G: uint8(60 + mathrand.IntN(180)),
+		B: uint8(60 + mathrand.IntN(180)),
+		A: 255,
+	}
+}
+
+func encodePNGBase64(img image.Image) (string, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", fmt.Errorf("编码图片失败: %v", err)
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// secureRandInt 返回 [min, max] 范围内的随机整数
+func secureRandInt(min, max int) (int, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(max-min+1)))
+	if err != nil {
+		return 0, err
+	}
+	return min + int(n.Int64()), nil
+}
+
+// verifySliderAnswer 校验滑块位置和拖动轨迹
+func verifySliderAnswer(expected, code string) error {
+	answerX, err := strconv.Atoi(expected)
+	if err != nil {
+		return errors.New("验证码数据异常")
+	}
+	var answer model.SliderAnswer
+	if err := json.Unmarshal([]byte(code), &answer); err != nil {
+		return errors.New("滑块验证码答案格式错误")
+	}
+
+	tolerance := float64(config.CaptchaSliderTolerance)
+	if math.Abs(answer.X-float64(answerX)) > tolerance {
+		return errors.New("滑块位置不正确")
+	}
+	return checkSliderTrack(answer, tolerance)
+}
+
+// checkSliderTrack 对拖动轨迹做基本的合理性检查
+func checkSliderTrack(answer model.SliderAnswer, tolerance float64) error {
+	track := answer.Track
+	if len(track) < sliderMinTrackPoints {
+		return errors.New("拖动轨迹过短")
+	}
+	first, last := track[0], track[len(track)-1]
+	duration := last.T - first.T
+	if duration < sliderMinDurationMs || duration > sliderMaxDurationMs {
+		return errors.New("拖动时长异常")
+	}
+	if first.X > 10 {
+		return errors.New("拖动轨迹起点异常")
+	}
+	if math.Abs(last.X-answer.X) > tolerance+2 {
+		return errors.New("拖动轨迹终点与提交位置不一致")
+	}
+
+	var forward, backward float64
+	var speeds []float64
+	for i := 1; i < len(track); i++ {
+		dt := track[i].T - track[i-1].T
+		if dt < 0 {
+			return errors.New("拖动轨迹时间顺序异常")
+		}
+		dx := track[i].X - track[i-1].X
+		if dx >= 0 {
+			forward += dx
+		} else {
+			backward -= dx
+		}
+		if dt > 0 {
+			speeds = append(speeds, dx/float64(dt))
+		}
+	}
+	if forward == 0 || backward > forward*0.3 {
+		return errors.New("拖动轨迹异常")
+	}
+
+	// 速度几乎恒定的匀速拖动一般来自脚本
+	if len(speeds) >= 3 {
+		var mean float64
+		for _, speed := range speeds {
+			mean += speed
+		}
+		mean /= float64(len(speeds))
+		var variance float64
+		for _, speed := range speeds {
+			variance += (speed - mean) * (speed - mean)
+		}
+		variance /= float64(len(speeds))
+		if mean > 0 && math.Sqrt(variance)/mean < 0.05 {
+			return errors.New("拖动轨迹异常")
+		}
+	}
+	return nil
+}
@@ -34,7 +34,15 @@ func init() {
 // RegisterService 用户注册服务
 // 验证用户信息并创建新用户账户
 // 使用SRP协议，Salt和Verifier由客户端生成
-func RegisterService(req model.Register) error {
+// 人机验证和邮箱验证码是否必需由风险评估决定
+func RegisterService(req model.Register, client model.ClientMeta) error {
+	assessment := EvaluateRisk(model.RiskActionRegister, req.Email, client)
+
+	// 此前缀保留给未登录的LLM调用者
+	if strings.HasPrefix(req.Username, AnonymousLLMCallerPrefix) {
+		return errors.New("用户名不能以 " + AnonymousLLMCallerPrefix + " 开头")
+	}
+
 	// 检查用户名与邮箱是否已存在
 	var existingUser model.User
 	if err := db.DB.Where("username = ? OR email = ?", req.Username, req.Email).First(&existingUser).Error; err == nil {
@@ -43,16 +51,25 @@ func RegisterService(req model.Register) error {
 		return errors.New("用户名或邮箱已存在")
 	}
 
-	// 验证邮箱验证码
-	if !VerifyCode(req.Email, req.EmailVerificationCode) {
-		fmt.Println("邮箱验证码验证失败 - 邮箱:", req.Email)
-		return errors.New("邮箱验证码无效或已过期")
+	// 邮箱验证码风险要求时必填
+	if assessment.RequireEmailCode && req.EmailVerificationCode == "" {
+		return ErrEmailCodeRequired
 	}
 
-	// 验证图片验证码
-	if !VerifyCaptcha(req.HumanCheckKey, req.HumanCheckCode) {
-		fmt.Println("图片验证码验证失败 - Key:", req.HumanCheckKey)
-		return errors.New("图形验证码无效或已过期")
+	// 先验证图片验证码, 邮箱验证码校验成功后会被删除, 人机验证未通过时不能消耗
+	if err := requireHumanCheck(assessment, req.HumanCheckKey, req.HumanCheckCode); err != nil {
+		fmt.Println("人机验证失败 - Key:", req.HumanCheckKey, err)
+		if !errors.Is(err, ErrHumanCheckRequired) {
+			RecordRiskFailure(req.Email, client)
+		}
+		return err
+	}
+
+	// 验证邮箱验证码(提交了也要校验)
+	if req.EmailVerificationCode != "" && !VerifyCode(req.Email, req.EmailVerificationCode) {
+		fmt.Println("邮箱验证码验证失败 - 邮箱:", req.Email)
+		RecordRiskFailure(req.Email, client)
+		return errors.New("邮箱验证码无效或已过期")
 	}
 
 	// 检查 Verifier 长度
@@ -76,17 +93,29 @@ func RegisterService(req model.Register) error {
 		return errors.New("创建用户失败: " + err.Error())
 	}
 
+	RecordRiskSuccess(req.Email)
 	fmt.Println("用户注册成功 - 用户名:", newUser.Username, "邮箱:", newUser.Email)
 	return nil
 }
 
 // LoginService 用户登录第一步服务
 // 使用SRP协议，返回服务器公钥B和盐值Salt
-func LoginService(req model.Login) (model.LoginResponse, error) {
+// 风险较高时要求先完成人机验证
+func LoginService(req model.Login, client model.ClientMeta) (model.LoginResponse, error) {
+	assessment := EvaluateRisk(model.RiskActionLogin, req.Username, client)
+	if err := requireHumanCheck(assessment, req.HumanCheckKey, req.HumanCheckCode); err != nil {
+		fmt.Println("登录人机验证失败 - 用户名:", req.Username, err)
+		if !errors.Is(err, ErrHumanCheckRequired) {
+			RecordRiskFailure(req.Username, client)
+		}
+		return model.LoginResponse{}, err
+	}
+
 	var user model.User
 	if err := db.DB.Where("username = ?", req.Username).First(&user).Error; err != nil {
 		// 用户不存在
 		fmt.Println("用户不存在 - 用户名:", req.Username)
+		RecordRiskFailure(req.Username, client)
 		return model.LoginResponse{}, errors.New("用户不存在")
 	}
 
@@ -184,7 +213,7 @@ func generateRandomBigInt(max *big.Int) (*big.Int, error) {
 
 // LoginStep2Service 用户登录第二步服务
 // 使用SRP协议，验证客户端证据消息M1，返回服务器证据消息M2
-func LoginStep2Service(req model.LoginStep2) (model.LoginStep2Response, error) {
+func LoginStep2Service(req model.LoginStep2, client model.ClientMeta) (model.LoginStep2Response, error) {
 	var user model.User
 	if err := db.DB.Where("username = ?", req.Username).First(&user).Error; err != nil {
 		fmt.Println("用户不存在 - 用户名:", req.Username)
@@ -329,10 +358,12 @@ func LoginStep2Service(req model.LoginStep2) (model.LoginStep2Response, error) {
 	if !strings.EqualFold(req.M1, expectedM1Hex) {
 		fmt.Println(" M1验证失败 - 期望:", expectedM1Hex, "实际:", req.M1)
 		db.RDB.Del(db.Ctx, sessionKey)
+		RecordRiskFailure(req.Username, client)
 		return model.LoginStep2Response{}, errors.New("登录验证失败")
 	}
 
 	fmt.Println(" M1验证成功")
+	RecordRiskSuccess(req.Username)
 
 	// 6. 计算 M2 = H(A | M1 | K)
 	// ⚠️ 关键：A 也必须使用 PAD 后的值
@@ -413,7 +444,10 @@ func hashBytes(data []byte) []byte {
 }
 
 // 重置密码服务
-func ResetPasswordService(req model.ChangePassword) error {
+// 邮箱验证码始终必需, 人机验证由风险评估决定
+func ResetPasswordService(req model.ChangePassword, client model.ClientMeta) error {
+	assessment := EvaluateRisk(model.RiskActionResetPassword, req.Email, client)
+
 	// 检查用户名和邮箱是否存在
 	var existingUser model.User
 	if err := db.DB.Where("email = ?", req.Email).First(&existingUser).Error; err != nil {
@@ -422,9 +456,24 @@ func ResetPasswordService(req model.ChangePassword) error {
 		return errors.New("用户不存在,请去注册")
 	}
 
+	// 邮箱验证码必填
+	if req.EmailVerificationCode == "" {
+		return ErrEmailCodeRequired
+	}
+
+	// 先验证图片验证码, 邮箱验证码校验成功后会被删除, 人机验证未通过时不能消耗
+	if err := requireHumanCheck(assessment, req.HumanCheckKey, req.HumanCheckCode); err != nil {
+		fmt.Println("人机验证失败 - Key:", req.HumanCheckKey, err)
+		if !errors.Is(err, ErrHumanCheckRequired) {
+			RecordRiskFailure(req.Email, client)
+		}
+		return err
+	}
+
 	// 如果在的话,验证邮箱验证码
 	if !VerifyCode(req.Email, req.EmailVerificationCode) {
 		fmt.Println("邮箱验证码验证失败 - 邮箱:", req.Email)
+		RecordRiskFailure(req.Email, client)
 		return errors.New("邮箱验证码无效或已过期")
 	}
 
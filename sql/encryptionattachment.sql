@@ -0,0 +1,44 @@
+/*
+ Navicat Premium Dump SQL
+
+ Source Server         : Windows
+ Source Server Type    : MySQL
+ Source Server Version : 80044 (8.0.44)
+ Source Host           : localhost:3306
+ Source Schema         : homepages
+
+ Target Server Type    : MySQL
+ Target Server Version : 80044 (8.0.44)
+ File Encoding         : 65001
+
+ Date: 19/10/2026 10:12:45
+*/
+
+SET NAMES utf8mb4;
+SET FOREIGN_KEY_CHECKS = 0;
+
+-- ----------------------------
+-- Table structure for encryptionattachment
+-- ----------------------------
+DROP TABLE IF EXISTS `encryptionattachment`;
+CREATE TABLE `encryptionattachment`  (
+  `id` bigint UNSIGNED NOT NULL AUTO_INCREMENT,
+  `attachmentId` varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NULL DEFAULT NULL COMMENT '附件唯一id',
+  `messageUuid` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NULL DEFAULT NULL COMMENT '所属消息uuid',
+  `encryptedName` text CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NULL COMMENT '加密后的文件名',
+  `iv` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NULL DEFAULT NULL COMMENT 'IV值',
+  `size` bigint NULL DEFAULT NULL COMMENT '加密后文件大小',
+  `chunkSize` bigint NULL DEFAULT NULL COMMENT '分片大小',
+  `totalChunks` bigint NULL DEFAULT NULL COMMENT '分片总数',
+  `status` varchar(16) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NULL DEFAULT NULL COMMENT '上传状态',
+  `expiresAt` datetime(3) NULL DEFAULT NULL COMMENT '过期时间',
+  `deleteAfter` datetime(3) NULL DEFAULT NULL COMMENT '随消息销毁后的删除时间',
+  `createdAt` datetime(3) NULL DEFAULT NULL COMMENT '创建时间',
+  PRIMARY KEY (`id`) USING BTREE,
+  UNIQUE INDEX `idx_encryptionattachment_attachmentId`(`attachmentId` ASC) USING BTREE,
+  INDEX `idx_encryptionattachment_messageUuid`(`messageUuid` ASC) USING BTREE,
+  INDEX `idx_encryptionattachment_expiresAt`(`expiresAt` ASC) USING BTREE,
+  INDEX `idx_encryptionattachment_deleteAfter`(`deleteAfter` ASC) USING BTREE
+) ENGINE = InnoDB AUTO_INCREMENT = 1 CHARACTER SET = utf8mb4 COLLATE = utf8mb4_0900_ai_ci ROW_FORMAT = Dynamic;
+
+SET FOREIGN_KEY_CHECKS = 1;
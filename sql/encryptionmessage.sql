@@ -27,7 +27,23 @@ CREATE TABLE `encryptionmessage`  (
   `encryptedAESKey` text CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL COMMENT '公钥',
   `iv` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL COMMENT '随机None',
   `cipherText` text CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL COMMENT '密文',
-  PRIMARY KEY (`id`) USING BTREE
+  `maxViews` int NOT NULL DEFAULT 1 COMMENT '最大查看次数',
+  `views` int NOT NULL DEFAULT 0 COMMENT '已查看次数',
+  `accessSalt` varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NULL DEFAULT NULL COMMENT '访问口令盐值',
+  `accessHash` varchar(128) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NULL DEFAULT NULL COMMENT '访问口令Argon2id哈希',
+  `recipientUserId` varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NULL DEFAULT NULL COMMENT '收件人用户id',
+  `recipientKeyId` varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NULL DEFAULT NULL COMMENT '收件人公钥id',
+  `senderUsername` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NULL DEFAULT NULL COMMENT '发送人用户名',
+  `notifyEmail` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NULL DEFAULT NULL COMMENT '回执通知邮箱',
+  `notifyWebhook` varchar(1024) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NULL DEFAULT NULL COMMENT '回执通知Webhook',
+  `uploadTokenHash` varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NULL DEFAULT NULL COMMENT '附件上传凭证哈希',
+  `failedAttempts` int NOT NULL DEFAULT 0 COMMENT '访问口令错误次数',
+  `createdAt` datetime(3) NULL DEFAULT NULL COMMENT '创建时间',
+  `expiresAt` datetime(3) NULL DEFAULT NULL COMMENT '过期时间',
+  PRIMARY KEY (`id`) USING BTREE,
+  UNIQUE INDEX `idx_encryptionmessage_uuid`(`uuid` ASC) USING BTREE,
+  INDEX `idx_encryptionmessage_expiresAt`(`expiresAt` ASC) USING BTREE,
+  INDEX `idx_encryptionmessage_recipientUserId`(`recipientUserId` ASC) USING BTREE
 ) ENGINE = InnoDB AUTO_INCREMENT = 6 CHARACTER SET = utf8mb4 COLLATE = utf8mb4_0900_ai_ci ROW_FORMAT = Dynamic;
 
 SET FOREIGN_KEY_CHECKS = 1;
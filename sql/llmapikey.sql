@@ -0,0 +1,39 @@
+/*
+ Navicat Premium Dump SQL
+
+ Source Server         : Windows
+ Source Server Type    : MySQL
+ Source Server Version : 80044 (8.0.44)
+ Source Host           : localhost:3306
+ Source Schema         : homepages
+
+ Target Server Type    : MySQL
+ Target Server Version : 80044 (8.0.44)
+ File Encoding         : 65001
+
+ Date: 19/10/2026 17:05:42
+*/
+
+SET NAMES utf8mb4;
+SET FOREIGN_KEY_CHECKS = 0;
+
+-- ----------------------------
+-- Table structure for llmapikey
+-- ----------------------------
+DROP TABLE IF EXISTS `llmapikey`;
+CREATE TABLE `llmapikey`  (
+  `id` bigint UNSIGNED NOT NULL AUTO_INCREMENT,
+  `keyId` varchar(32) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NULL DEFAULT NULL COMMENT '密钥id',
+  `userId` varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NULL DEFAULT NULL COMMENT '所属用户id',
+  `name` varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NULL DEFAULT NULL COMMENT '备注',
+  `prefix` varchar(16) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NULL DEFAULT NULL COMMENT '密钥前缀',
+  `keyHash` varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NULL DEFAULT NULL COMMENT '密钥SHA-256哈希',
+  `createdAt` datetime(3) NULL DEFAULT NULL COMMENT '创建时间',
+  `lastUsedAt` datetime(3) NULL DEFAULT NULL COMMENT '最后使用时间',
+  PRIMARY KEY (`id`) USING BTREE,
+  UNIQUE INDEX `idx_llmapikey_keyId`(`keyId` ASC) USING BTREE,
+  UNIQUE INDEX `idx_llmapikey_keyHash`(`keyHash` ASC) USING BTREE,
+  INDEX `idx_llmapikey_userId`(`userId` ASC) USING BTREE
+) ENGINE = InnoDB AUTO_INCREMENT = 1 CHARACTER SET = utf8mb4 COLLATE = utf8mb4_0900_ai_ci ROW_FORMAT = Dynamic;
+
+SET FOREIGN_KEY_CHECKS = 1;
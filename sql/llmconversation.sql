@@ -0,0 +1,37 @@
+/*
+ Navicat Premium Dump SQL
+
+ Source Server         : Windows
+ Source Server Type    : MySQL
+ Source Server Version : 80044 (8.0.44)
+ Source Host           : localhost:3306
+ Source Schema         : homepages
+
+ Target Server Type    : MySQL
+ Target Server Version : 80044 (8.0.44)
+ File Encoding         : 65001
+
+ Date: 19/10/2026 16:20:11
+*/
+
+SET NAMES utf8mb4;
+SET FOREIGN_KEY_CHECKS = 0;
+
+-- ----------------------------
+-- Table structure for llmconversation
+-- ----------------------------
+DROP TABLE IF EXISTS `llmconversation`;
+CREATE TABLE `llmconversation`  (
+  `id` bigint UNSIGNED NOT NULL AUTO_INCREMENT,
+  `conversationId` varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NULL DEFAULT NULL COMMENT '对话id',
+  `userId` varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NULL DEFAULT NULL COMMENT '所属用户id',
+  `title` varchar(100) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NULL DEFAULT NULL COMMENT '标题',
+  `createdAt` datetime(3) NULL DEFAULT NULL COMMENT '创建时间',
+  `updatedAt` datetime(3) NULL DEFAULT NULL COMMENT '最后一次对话时间',
+  PRIMARY KEY (`id`) USING BTREE,
+  UNIQUE INDEX `idx_llmconversation_conversationId`(`conversationId` ASC) USING BTREE,
+  INDEX `idx_llmconversation_userId`(`userId` ASC) USING BTREE,
+  INDEX `idx_llmconversation_updatedAt`(`updatedAt` ASC) USING BTREE
+) ENGINE = InnoDB AUTO_INCREMENT = 1 CHARACTER SET = utf8mb4 COLLATE = utf8mb4_0900_ai_ci ROW_FORMAT = Dynamic;
+
+SET FOREIGN_KEY_CHECKS = 1;
@@ -0,0 +1,42 @@
+/*
+ Navicat Premium Dump SQL
+
+ Source Server         : Windows
+ Source Server Type    : MySQL
+ Source Server Version : 80044 (8.0.44)
+ Source Host           : localhost:3306
+ Source Schema         : homepages
+
+ Target Server Type    : MySQL
+ Target Server Version : 80044 (8.0.44)
+ File Encoding         : 65001
+
+ Date: 19/10/2026 17:48:03
+*/
+
+SET NAMES utf8mb4;
+SET FOREIGN_KEY_CHECKS = 0;
+
+-- ----------------------------
+-- Table structure for llmpersona
+-- ----------------------------
+DROP TABLE IF EXISTS `llmpersona`;
+CREATE TABLE `llmpersona`  (
+  `id` bigint UNSIGNED NOT NULL AUTO_INCREMENT,
+  `name` varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NULL DEFAULT NULL COMMENT '名称',
+  `description` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NULL DEFAULT NULL COMMENT '简介',
+  `systemPrompt` text CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NULL COMMENT '系统提示词',
+  `provider` varchar(32) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NULL DEFAULT NULL COMMENT '服务商',
+  `model` varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NULL DEFAULT NULL COMMENT '模型',
+  `temperature` double NULL DEFAULT NULL COMMENT '温度',
+  `maxTokens` bigint NULL DEFAULT NULL COMMENT '最大输出token',
+  `lockModel` tinyint(1) NULL DEFAULT NULL COMMENT '锁定服务商和模型',
+  `lockTemperature` tinyint(1) NULL DEFAULT NULL COMMENT '锁定温度',
+  `lockMaxTokens` tinyint(1) NULL DEFAULT NULL COMMENT '锁定最大输出token',
+  `enabled` tinyint(1) NULL DEFAULT NULL COMMENT '是否启用',
+  `createdAt` datetime(3) NULL DEFAULT NULL COMMENT '创建时间',
+  `updatedAt` datetime(3) NULL DEFAULT NULL COMMENT '更新时间',
+  PRIMARY KEY (`id`) USING BTREE
+) ENGINE = InnoDB AUTO_INCREMENT = 1 CHARACTER SET = utf8mb4 COLLATE = utf8mb4_0900_ai_ci ROW_FORMAT = Dynamic;
+
+SET FOREIGN_KEY_CHECKS = 1;
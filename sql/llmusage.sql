@@ -0,0 +1,39 @@
+/*
+ Navicat Premium Dump SQL
+
+ Source Server         : Windows
+ Source Server Type    : MySQL
+ Source Server Version : 80044 (8.0.44)
+ Source Host           : localhost:3306
+ Source Schema         : homepages
+
+ Target Server Type    : MySQL
+ Target Server Version : 80044 (8.0.44)
+ File Encoding         : 65001
+
+ Date: 19/10/2026 17:05:42
+*/
+
+SET NAMES utf8mb4;
+SET FOREIGN_KEY_CHECKS = 0;
+
+-- ----------------------------
+-- Table structure for llmusage
+-- ----------------------------
+DROP TABLE IF EXISTS `llmusage`;
+CREATE TABLE `llmusage`  (
+  `id` bigint UNSIGNED NOT NULL AUTO_INCREMENT,
+  `userId` varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NULL DEFAULT NULL COMMENT '用户id',
+  `apiKeyId` varchar(32) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NULL DEFAULT NULL COMMENT 'API密钥id',
+  `provider` varchar(32) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NULL DEFAULT NULL COMMENT '服务商',
+  `model` varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NULL DEFAULT NULL COMMENT '模型',
+  `promptTokens` bigint NULL DEFAULT NULL COMMENT '输入token',
+  `completionTokens` bigint NULL DEFAULT NULL COMMENT '输出token',
+  `totalTokens` bigint NULL DEFAULT NULL COMMENT '合计token',
+  `estimated` tinyint(1) NULL DEFAULT NULL COMMENT '是否为估算值',
+  `createdAt` datetime(3) NULL DEFAULT NULL COMMENT '请求时间',
+  PRIMARY KEY (`id`) USING BTREE,
+  INDEX `idx_llmusage_user_time`(`userId` ASC, `createdAt` ASC) USING BTREE
+) ENGINE = InnoDB AUTO_INCREMENT = 1 CHARACTER SET = utf8mb4 COLLATE = utf8mb4_0900_ai_ci ROW_FORMAT = Dynamic;
+
+SET FOREIGN_KEY_CHECKS = 1;
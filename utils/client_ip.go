@@ -0,0 +1,16 @@
+package utils
+
+import "net"
+
+// ClientIPKey 返回按IP限流和计数时使用的标识
+// IPv6 用户通常可以使用整个 /64 网段, 按 /64 前缀计数, 避免轮换地址绕过限制
+func ClientIPKey(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ip
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.String()
+	}
+	return parsed.Mask(net.CIDRMask(64, 128)).String() + "/64"
+}
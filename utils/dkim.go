@@ -0,0 +1,245 @@
+package utils
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DKIM 签名算法
+const (
+	DKIMAlgorithmRSA     = "rsa-sha256"
+	DKIMAlgorithmEd25519 = "ed25519-sha256"
+)
+
+// dkimSignedHeaders 参与签名的邮件头(存在时才会签名)
+var dkimSignedHeaders = []string{
+	"from", "reply-to", "to", "cc", "subject", "date", "message-id",
+	"mime-version", "content-type", "content-transfer-encoding",
+	"list-unsubscribe", "list-unsubscribe-post",
+}
+
+// DKIMSigner DKIM 签名器, 使用 relaxed/relaxed 规范化
+type DKIMSigner struct {
+	Domain    string
+	Selector  string
+	Algorithm string
+	key       crypto.Signer
+}
+
+// LoadDKIMSigner 从PEM文件加载DKIM私钥
+// 支持 PKCS#1(RSA) 与 PKCS#8(RSA/Ed25519) 格式, algorithm 为空时根据密钥类型自动选择
+func LoadDKIMSigner(domain, selector, keyPath, algorithm string) (*DKIMSigner, error) {
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("读取DKIM私钥失败: %v", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("DKIM私钥不是有效的PEM格式")
+	}
+
+	var key crypto.Signer
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		rsaKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("解析DKIM私钥失败: %v", err)
+		}
+		key = rsaKey
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("解析DKIM私钥失败: %v", err)
+		}
+		signer, ok := parsed.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("不支持的DKIM私钥类型")
+		}
+		key = signer
+	default:
+		return nil, fmt.Errorf("不支持的DKIM私钥PEM类型: %s", block.Type)
+	}
+
+	keyAlgorithm := ""
+	switch key.(type) {
+	case *rsa.PrivateKey:
+		keyAlgorithm = DKIMAlgorithmRSA
+	case ed25519.PrivateKey:
+		keyAlgorithm = DKIMAlgorithmEd25519
+	default:
+		return nil, fmt.Errorf("不支持的DKIM私钥类型")
+	}
+	if algorithm == "" {
+		algorithm = keyAlgorithm
+	}
+	if algorithm != keyAlgorithm {
+		return nil, fmt.Errorf("DKIM算法 %s 与私钥类型不匹配(应为 %s)", algorithm, keyAlgorithm)
+	}
+
+	return &DKIMSigner{
+		Domain:    domain,
+		Selector:  selector,
+		Algorithm: algorithm,
+		key:       key,
+	}, nil
+}
+
+// KeyType 返回DNS记录中 k= 对应的密钥类型
+func (s *DKIMSigner) KeyType() string {
+	if s.Algorithm == DKIMAlgorithmEd25519 {
+		return "ed25519"
+	}
+	return "rsa"
+}
+
+// PublicKey 返回签名私钥对应的公钥
+func (s *DKIMSigner) PublicKey() crypto.PublicKey {
+	return s.key.Public()
+}
+
+// PublicKeyRecord 返回应发布在DNS中的 p= 值
+// RSA 为 SubjectPublicKeyInfo 的DER编码, Ed25519 为32字节原始公钥(RFC 8463)
+func (s *DKIMSigner) PublicKeyRecord() (string, error) {
+	if pub, ok := s.key.Public().(ed25519.PublicKey); ok {
+		return base64.StdEncoding.EncodeToString(pub), nil
+	}
+	der, err := x509.MarshalPKIXPublicKey(s.key.Public())
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(der), nil
+}
+
+// Sign 对完整的邮件(CRLF换行)签名, 返回在最前面插入 DKIM-Signature 头后的邮件
+func (s *DKIMSigner) Sign(message []byte) ([]byte, error) {
+	headerPart, body, found := bytes.Cut(message, []byte("\r\n\r\n"))
+	if !found {
+		return nil, fmt.Errorf("邮件格式错误: 缺少头部与正文的分隔")
+	}
+
+	bodyHash := sha256.Sum256(canonicalBodyRelaxed(body))
+	headers := parseHeaders(string(headerPart) + "\r\n")
+
+	// 同名头部取最后一次出现的值(RFC 6376 5.4.2)
+	var signedNames []string
+	var canonical strings.Builder
+	for _, name := range dkimSignedHeaders {
+		for i := len(headers) - 1; i >= 0; i-- {
+			if strings.EqualFold(headers[i].name, name) {
+				signedNames = append(signedNames, name)
+				canonical.WriteString(canonicalHeaderRelaxed(headers[i].raw))
+				break
+			}
+		}
+	}
+	if len(signedNames) == 0 {
+		return nil, fmt.Errorf("邮件缺少可签名的头部")
+	}
+
+	tags := []string{
+		"v=1",
+		"a=" + s.Algorithm,
+		"c=relaxed/relaxed",
+		"d=" + s.Domain,
+		"s=" + s.Selector,
+		"t=" + strconv.FormatInt(time.Now().Unix(), 10),
+		"h=" + strings.Join(signedNames, ":"),
+		"bh=" + base64.StdEncoding.EncodeToString(bodyHash[:]),
+		"b=",
+	}
+	dkimHeader := "DKIM-Signature: " + strings.Join(tags, ";\r\n\t")
+
+	// 签名数据: 规范化后的签名头 + 不带结尾CRLF的 DKIM-Signature(b=为空)
+	canonical.WriteString(strings.TrimSuffix(canonicalHeaderRelaxed(dkimHeader+"\r\n"), "\r\n"))
+	digest := sha256.Sum256([]byte(canonical.String()))
+
+	var signature []byte
+	var err error
+	switch key := s.key.(type) {
+	case *rsa.PrivateKey:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	case ed25519.PrivateKey:
+		// RFC 8463: 使用 PureEdDSA 对 SHA-256 摘要签名
+		signature = ed25519.Sign(key, digest[:])
+	default:
+		err = fmt.Errorf("不支持的DKIM私钥类型")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("DKIM签名失败: %v", err)
+	}
+
+	var signed bytes.Buffer
+	signed.WriteString(dkimHeader)
+	signed.WriteString(base64.StdEncoding.EncodeToString(signature))
+	signed.WriteString("\r\n")
+	signed.Write(message)
+	return signed.Bytes(), nil
+}
+
+type mailHeader struct {
+	name string
+	raw  string // 包含折行和结尾CRLF的原始头部
+}
+
+// parseHeaders 按顺序解析邮件头, 保留折行
+func parseHeaders(headerPart string) []mailHeader {
+	var headers []mailHeader
+	for _, line := range strings.SplitAfter(headerPart, "\r\n") {
+		if line == "" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(headers) > 0 {
+			headers[len(headers)-1].raw += line
+			continue
+		}
+		name, _, _ := strings.Cut(line, ":")
+		headers = append(headers, mailHeader{name: strings.TrimSpace(name), raw: line})
+	}
+	return headers
+}
+
+// canonicalHeaderRelaxed relaxed 头部规范化(RFC 6376 3.4.2)
+func canonicalHeaderRelaxed(raw string) string {
+	name, value, _ := strings.Cut(raw, ":")
+	value = strings.ReplaceAll(value, "\r\n", "")
+	value = strings.Join(strings.FieldsFunc(value, isWSP), " ")
+	return strings.ToLower(strings.TrimSpace(name)) + ":" + value + "\r\n"
+}
+
+// canonicalBodyRelaxed relaxed 正文规范化(RFC 6376 3.4.4)
+func canonicalBodyRelaxed(body []byte) []byte {
+	lines := strings.Split(string(body), "\r\n")
+	for i, line := range lines {
+		fields := strings.FieldsFunc(line, isWSP)
+		// 行内连续空白压缩为一个空格, 去除行尾空白
+		if len(fields) > 0 && isWSP(rune(line[0])) {
+			lines[i] = " " + strings.Join(fields, " ")
+		} else {
+			lines[i] = strings.Join(fields, " ")
+		}
+	}
+	// 去除末尾所有空行
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(lines, "\r\n") + "\r\n")
+}
+
+func isWSP(r rune) bool {
+	return r == ' ' || r == '\t'
+}
@@ -0,0 +1,172 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalHeaderRelaxed(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{"小写头部名", "Subject: Hello\r\n", "subject:Hello\r\n"},
+		{"去除冒号两侧空白", "From  :   a@example.com  \r\n", "from:a@example.com\r\n"},
+		{"压缩连续空白", "Subject: a \t  b\tc\r\n", "subject:a b c\r\n"},
+		{"展开折行", "Subject: first\r\n\tsecond\r\n  third\r\n", "subject:first second third\r\n"},
+		{"空值", "X-Empty:\r\n", "x-empty:\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canonicalHeaderRelaxed(tt.raw); got != tt.want {
+				t.Errorf("canonicalHeaderRelaxed(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanonicalBodyRelaxed(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"空正文", "", ""},
+		{"只有空行", "\r\n\r\n", ""},
+		{"补齐结尾CRLF", "hello", "hello\r\n"},
+		{"去除行尾空白", "hello \t\r\n", "hello\r\n"},
+		{"压缩行内空白", "a  \t b\r\n", "a b\r\n"},
+		{"保留行首空白为一个空格", "  \tindented\r\n", " indented\r\n"},
+		{"去除末尾空行", "a\r\n\r\n\r\n", "a\r\n"},
+		{"保留中间空行", "a\r\n\r\nb\r\n", "a\r\n\r\nb\r\n"},
+		{"只有空白的行变为空行", "a\r\n   \r\nb\r\n", "a\r\n\r\nb\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(canonicalBodyRelaxed([]byte(tt.body))); got != tt.want {
+				t.Errorf("canonicalBodyRelaxed(%q) = %q, want %q", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDKIMSignerSign(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message := "From: Sender <sender@example.com>\r\n" +
+		"To: rcpt@example.org\r\n" +
+		"Subject:  Hello\r\n\tWorld\r\n" +
+		"X-Not-Signed: ignored\r\n" +
+		"Subject: Latest\r\n" +
+		"\r\n" +
+		"Body  line \r\n\r\n\r\n"
+
+	tests := []struct {
+		name      string
+		algorithm string
+		key       crypto.Signer
+	}{
+		{"RSA", DKIMAlgorithmRSA, rsaKey},
+		{"Ed25519", DKIMAlgorithmEd25519, edKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer := &DKIMSigner{Domain: "example.com", Selector: "mail", Algorithm: tt.algorithm, key: tt.key}
+			signed, err := signer.Sign([]byte(message))
+			if err != nil {
+				t.Fatalf("Sign() error = %v", err)
+			}
+			header, rest, found := strings.Cut(string(signed), "\r\nFrom:")
+			if !found || "From:"+rest != message {
+				t.Fatalf("签名头之后应为原始邮件, got %q", signed)
+			}
+
+			tags := map[string]string{}
+			var order []string
+			for _, tag := range strings.Split(strings.TrimPrefix(header, "DKIM-Signature: "), ";\r\n\t") {
+				name, value, _ := strings.Cut(tag, "=")
+				tags[name] = value
+				order = append(order, name)
+			}
+			if got := strings.Join(order, ","); got != "v,a,c,d,s,t,h,bh,b" {
+				t.Errorf("标签顺序 = %s", got)
+			}
+			wantTags := map[string]string{
+				"v": "1",
+				"a": tt.algorithm,
+				"c": "relaxed/relaxed",
+				"d": "example.com",
+				"s": "mail",
+				"h": "from:to:subject",
+			}
+			for name, want := range wantTags {
+				if tags[name] != want {
+					t.Errorf("%s= %q, want %q", name, tags[name], want)
+				}
+			}
+			bodyHash := sha256.Sum256([]byte("Body line\r\n"))
+			if want := base64.StdEncoding.EncodeToString(bodyHash[:]); tags["bh"] != want {
+				t.Errorf("bh= %q, want %q", tags["bh"], want)
+			}
+
+			// 按验证方的方式重建签名数据: 同名头部取最后一个, 签名头的 b= 置空且不带结尾CRLF
+			unsigned := strings.TrimSuffix(header, tags["b"])
+			data := "from:Sender <sender@example.com>\r\n" +
+				"to:rcpt@example.org\r\n" +
+				"subject:Latest\r\n" +
+				strings.TrimSuffix(canonicalHeaderRelaxed(unsigned+"\r\n"), "\r\n")
+			digest := sha256.Sum256([]byte(data))
+			signature, err := base64.StdEncoding.DecodeString(tags["b"])
+			if err != nil {
+				t.Fatalf("b= 不是有效的base64: %v", err)
+			}
+			switch key := tt.key.(type) {
+			case *rsa.PrivateKey:
+				err = rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature)
+			case ed25519.PrivateKey:
+				if !ed25519.Verify(key.Public().(ed25519.PublicKey), digest[:], signature) {
+					err = rsa.ErrVerification
+				}
+			}
+			if err != nil {
+				t.Errorf("签名验证失败: %v", err)
+			}
+		})
+	}
+}
+
+func TestDKIMSignerSignRejectsInvalidMessage(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := &DKIMSigner{Domain: "example.com", Selector: "mail", Algorithm: DKIMAlgorithmEd25519, key: key}
+	tests := []struct {
+		name    string
+		message string
+	}{
+		{"缺少头部与正文分隔", "Subject: hi\r\nbody"},
+		{"没有可签名的头部", "X-Custom: hi\r\n\r\nbody"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := signer.Sign([]byte(tt.message)); err == nil {
+				t.Errorf("Sign(%q) 应返回错误", tt.message)
+			}
+		})
+	}
+}
@@ -1,30 +1,62 @@
 package utils
 
 import (
+	"encoding/json"
 	"gin/db"
 	"time"
 )
 
-type RedisStore struct{}
+// CaptchaEntry 存储在Redis中的验证码记录
+type CaptchaEntry struct {
+	Kind   string `json:"kind"`   // 验证码类型
+	Answer string `json:"answer"` // 验证码答案
+}
+
+// RedisStore 实现 base64Captcha.Store, Kind 会随答案一起存储
+type RedisStore struct {
+	Kind string
+}
 
 func (r RedisStore) Set(id, value string) error {
 	key := "captcha:" + id
-	return db.RDB.Set(db.Ctx, key, value, 3*time.Minute).Err()
+	entry, err := json.Marshal(CaptchaEntry{Kind: r.Kind, Answer: value})
+	if err != nil {
+		return err
+	}
+	return db.RDB.Set(db.Ctx, key, entry, 3*time.Minute).Err()
 }
 
 func (r RedisStore) Get(id string, clear bool) string {
-	key := "captcha:" + id
-	val, err := db.RDB.Get(db.Ctx, key).Result()
-	if err != nil {
+	entry, ok := r.GetEntry(id, clear)
+	if !ok {
 		return ""
 	}
+	return entry.Answer
+}
+
+// GetEntry 获取验证码类型和答案, 兼容旧版本只存储答案的记录
+// clear 为 true 时用 GETDEL 原子地读取并删除, 同一个验证码不能被并发请求重复使用
+func (r RedisStore) GetEntry(id string, clear bool) (CaptchaEntry, bool) {
+	key := "captcha:" + id
+	var val string
+	var err error
 	if clear {
-		db.RDB.Del(db.Ctx, key)
+		val, err = db.RDB.GetDel(db.Ctx, key).Result()
+	} else {
+		val, err = db.RDB.Get(db.Ctx, key).Result()
+	}
+	if err != nil {
+		return CaptchaEntry{}, false
+	}
+
+	var entry CaptchaEntry
+	if err := json.Unmarshal([]byte(val), &entry); err != nil || entry.Answer == "" {
+		return CaptchaEntry{Kind: "digit", Answer: val}, true
 	}
-	return val
+	return entry, true
 }
 
 func (r RedisStore) Verify(id, answer string, clear bool) bool {
 	val := r.Get(id, clear)
-	return val == answer
+	return val != "" && val == answer
 }